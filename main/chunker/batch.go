@@ -0,0 +1,132 @@
+package chunker
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of BatchOptions
+const (
+	DefaultConcurrency   = 4
+	DefaultBatchSize     = 16
+	DefaultMaxBatchBytes = 4 << 20
+	DefaultCallTimeout   = time.Minute
+)
+
+// Page is the markdown of a page to chunk. IDs must be unique within a call.
+type Page struct {
+	ID       int
+	Markdown string
+}
+
+// PageResult holds the chunks of a page, or the error chunking it
+type PageResult struct {
+	PageID int
+	Chunks []Chunk
+	Err    error
+}
+
+// BatchChunker is implemented by chunkers that chunk many pages in one call. Results are in the
+// order of pages; the error is only set when the whole call failed.
+type BatchChunker interface {
+	ChunkBatch(ctx context.Context, pages []Page, opts Options) ([]PageResult, error)
+}
+
+// BatchOptions controls how ChunkPages spreads pages over calls to a chunker
+type BatchOptions struct {
+	// Concurrency is the number of calls made at the same time
+	Concurrency int
+	// BatchSize is the number of pages sent in each call to a BatchChunker
+	BatchSize int
+	// MaxBatchBytes caps the markdown sent in each call to a BatchChunker. A page that is larger on
+	// its own is sent alone.
+	MaxBatchBytes int
+	// CallTimeout is the deadline of each call
+	CallTimeout time.Duration
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	if o.MaxBatchBytes <= 0 {
+		o.MaxBatchBytes = DefaultMaxBatchBytes
+	}
+	if o.CallTimeout <= 0 {
+		o.CallTimeout = DefaultCallTimeout
+	}
+	return o
+}
+
+// ChunkPages chunks pages with a bounded number of calls at a time, each with its own deadline.
+// Chunkers that implement BatchChunker are sent up to BatchSize pages and MaxBatchBytes of markdown
+// per call and others one page per call. Results are in the order of pages, and a page that could
+// not be chunked has its error set.
+func ChunkPages(ctx context.Context, c Chunker, pages []Page, opts Options, batch BatchOptions) []PageResult {
+	batch = batch.withDefaults()
+	batcher, isBatcher := c.(BatchChunker)
+
+	results := make([]PageResult, len(pages))
+	group := errgroup.Group{}
+	group.SetLimit(batch.Concurrency)
+	for start, end := 0, 0; start < len(pages); start = end {
+		end = start + 1
+		if isBatcher {
+			end = batchEnd(pages, start, batch)
+		}
+		// The call keeps the bounds of its batch while the loop moves on
+		start, end := start, end
+		group.Go(func() error {
+			if !isBatcher {
+				callCtx, cancel := context.WithTimeout(ctx, batch.CallTimeout)
+				defer cancel()
+				chunks, err := c.Chunk(callCtx, pages[start].Markdown, opts)
+				results[start] = PageResult{PageID: pages[start].ID, Chunks: chunks, Err: err}
+				return nil
+			}
+			copy(results[start:end], chunkBatch(ctx, batcher, pages[start:end], opts, batch.CallTimeout))
+			return nil
+		})
+	}
+	group.Wait()
+	return results
+}
+
+// batchEnd returns the end of the batch of pages that starts at start, which holds up to BatchSize
+// pages and MaxBatchBytes of markdown, and at least one page
+func batchEnd(pages []Page, start int, batch BatchOptions) int {
+	end := start + 1
+	size := len(pages[start].Markdown)
+	for end < len(pages) && end-start < batch.BatchSize && size+len(pages[end].Markdown) <= batch.MaxBatchBytes {
+		size += len(pages[end].Markdown)
+		end++
+	}
+	return end
+}
+
+// chunkBatch chunks pages with one call. A batch the service refuses as too large is split in two
+// and each half is sent again, so only pages that are too large on their own fail.
+func chunkBatch(ctx context.Context, batcher BatchChunker, pages []Page, opts Options, callTimeout time.Duration) []PageResult {
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	batchResults, err := batcher.ChunkBatch(callCtx, pages, opts)
+	cancel()
+	if status.Code(err) == codes.ResourceExhausted && len(pages) > 1 {
+		half := len(pages) / 2
+		return append(chunkBatch(ctx, batcher, pages[:half], opts, callTimeout), chunkBatch(ctx, batcher, pages[half:], opts, callTimeout)...)
+	}
+	if err == nil {
+		return batchResults
+	}
+	results := make([]PageResult, len(pages))
+	for i, page := range pages {
+		results[i] = PageResult{PageID: page.ID, Err: err}
+	}
+	return results
+}
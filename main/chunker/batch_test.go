@@ -0,0 +1,157 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchChunker chunks each page into one chunk and records the size of every call. Calls with more
+// than maxBytes of markdown fail like a gRPC message over the size limit.
+type batchChunker struct {
+	mu       sync.Mutex
+	calls    []int
+	fail     map[int]error
+	maxBytes int
+}
+
+func (b *batchChunker) Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error) {
+	return []Chunk{newChunk(markdown)}, nil
+}
+
+func (b *batchChunker) ChunkBatch(ctx context.Context, pages []Page, opts Options) ([]PageResult, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, len(pages))
+	b.mu.Unlock()
+
+	size := 0
+	for _, page := range pages {
+		size += len(page.Markdown)
+	}
+	if b.maxBytes > 0 && size > b.maxBytes {
+		return nil, status.Error(codes.ResourceExhausted, "message larger than max")
+	}
+
+	results := make([]PageResult, len(pages))
+	for i, page := range pages {
+		if err, ok := b.fail[page.ID]; ok {
+			return nil, err
+		}
+		results[i] = PageResult{PageID: page.ID, Chunks: []Chunk{newChunk(page.Markdown)}}
+	}
+	return results, nil
+}
+
+func testPages(n int) []Page {
+	pages := make([]Page, n)
+	for i := range pages {
+		pages[i] = Page{ID: i + 1, Markdown: fmt.Sprintf("Page %d", i+1)}
+	}
+	return pages
+}
+
+func TestChunkPagesBatchesInOrder(t *testing.T) {
+	c := &batchChunker{}
+	results := ChunkPages(context.Background(), c, testPages(10), Options{}, BatchOptions{BatchSize: 4})
+
+	if len(c.calls) != 3 {
+		t.Errorf("got %d calls, want 3", len(c.calls))
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if result.PageID != i+1 || result.Chunks[0].Text != fmt.Sprintf("Page %d", i+1) {
+			t.Errorf("result %d is for page %d: %+v", i, result.PageID, result.Chunks)
+		}
+	}
+}
+
+func TestChunkPagesFailsOnlyTheFailedBatch(t *testing.T) {
+	c := &batchChunker{fail: map[int]error{5: errors.New("unavailable")}}
+	results := ChunkPages(context.Background(), c, testPages(8), Options{}, BatchOptions{BatchSize: 4})
+
+	for i, result := range results {
+		failed := i >= 4
+		if (result.Err != nil) != failed {
+			t.Errorf("page %d has error %v, want failed %v", result.PageID, result.Err, failed)
+		}
+		if result.PageID != i+1 {
+			t.Errorf("result %d is for page %d", i, result.PageID)
+		}
+	}
+}
+
+func TestChunkPagesCapsBatchBytes(t *testing.T) {
+	pages := []Page{
+		{ID: 1, Markdown: strings.Repeat("a", 40)},
+		{ID: 2, Markdown: strings.Repeat("b", 40)},
+		{ID: 3, Markdown: strings.Repeat("c", 150)},
+		{ID: 4, Markdown: strings.Repeat("d", 40)},
+	}
+	c := &batchChunker{}
+	results := ChunkPages(context.Background(), c, pages, Options{}, BatchOptions{Concurrency: 1, BatchSize: 10, MaxBatchBytes: 100})
+
+	if want := []int{2, 1, 1}; fmt.Sprint(c.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", c.calls, want)
+	}
+	for i, result := range results {
+		if result.Err != nil || result.PageID != pages[i].ID {
+			t.Errorf("result %d: %+v", i, result)
+		}
+	}
+}
+
+func TestChunkPagesSplitsBatchesTooLargeToSend(t *testing.T) {
+	pages := []Page{
+		{ID: 1, Markdown: strings.Repeat("a", 40)},
+		{ID: 2, Markdown: strings.Repeat("b", 40)},
+		{ID: 3, Markdown: strings.Repeat("c", 150)},
+		{ID: 4, Markdown: strings.Repeat("d", 40)},
+	}
+	c := &batchChunker{maxBytes: 100}
+	results := ChunkPages(context.Background(), c, pages, Options{}, BatchOptions{BatchSize: 4})
+
+	for i, result := range results {
+		if result.PageID != pages[i].ID {
+			t.Errorf("result %d is for page %d", i, result.PageID)
+		}
+		tooLarge := result.PageID == 3
+		if (status.Code(result.Err) == codes.ResourceExhausted) != tooLarge || !tooLarge && result.Err != nil {
+			t.Errorf("page %d has error %v, want too large %v", result.PageID, result.Err, tooLarge)
+		}
+	}
+}
+
+// slowChunker chunks pages one at a time and blocks on pages that contain "slow" until the call is
+// cancelled
+type slowChunker struct{}
+
+func (slowChunker) Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error) {
+	if strings.Contains(markdown, "slow") {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return []Chunk{newChunk(markdown)}, nil
+}
+
+func TestChunkPagesTimesOutEachCall(t *testing.T) {
+	pages := []Page{{ID: 1, Markdown: "fast"}, {ID: 2, Markdown: "slow"}, {ID: 3, Markdown: "fast"}}
+	results := ChunkPages(context.Background(), slowChunker{}, pages, Options{}, BatchOptions{CallTimeout: 10 * time.Millisecond})
+
+	if !errors.Is(results[1].Err, context.DeadlineExceeded) {
+		t.Errorf("slow page error = %v, want deadline exceeded", results[1].Err)
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil || len(results[i].Chunks) != 1 {
+			t.Errorf("page %d: %+v", results[i].PageID, results[i])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package chunker splits page markdown into the chunks that are embedded and retrieved.
+// Chunking can run in-process or in the rag-tools gRPC service.
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+const (
+	// KindLocal chunks markdown in-process
+	KindLocal = "local"
+	// KindGRPC chunks markdown with the rag-tools service
+	KindGRPC = "grpc"
+	// KindStructured chunks markdown in-process along its heading, code, table and list structure
+	KindStructured = "structured"
+)
+
+// Default sizes, in tokens
+const (
+	DefaultChunkSize = 256
+	DefaultOverlap   = 50
+)
+
+// Options controls the size of chunks, counted in tokens
+type Options struct {
+	ChunkSize int
+	// Overlap is how much of the end of a chunk the next one repeats. Chunks do not overlap when it is 0.
+	Overlap int
+	// Tokenizer counts the tokens of ChunkSize and Overlap. It defaults to tokenizer.Approximate;
+	// tokenizer.Characters sizes chunks in characters like the rag-tools service used to.
+	Tokenizer tokenizer.Tokenizer
+}
+
+// withDefaults fills in the chunk size and tokenizer used when none are given
+func (o Options) withDefaults() Options {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+	if o.Tokenizer == nil {
+		o.Tokenizer = tokenizer.Approximate{}
+	}
+	return o
+}
+
+// length measures text with the tokenizer
+func (o Options) length(text string) int {
+	return o.Tokenizer.Count(text)
+}
+
+// tokenCounter returns the tokenizer that counts the tokens of a chunk. Chunks sized in characters
+// are still counted in tokens.
+func (o Options) tokenCounter() tokenizer.Tokenizer {
+	if _, ok := o.Tokenizer.(tokenizer.Characters); ok {
+		return tokenizer.Approximate{}
+	}
+	return o.Tokenizer
+}
+
+func (o Options) validate() error {
+	if o.Overlap < 0 {
+		return fmt.Errorf("chunk overlap %d is negative", o.Overlap)
+	}
+	if o.Overlap > o.ChunkSize {
+		return fmt.Errorf("chunk overlap %d is larger than chunk size %d", o.Overlap, o.ChunkSize)
+	}
+	return nil
+}
+
+var fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// Chunk is a piece of markdown with where it is in the page and what it contains
+type Chunk struct {
+	Text string
+	// StartOffset and EndOffset are the byte offsets of the chunk in the markdown. Chunks that
+	// were changed while splitting, such as code blocks with a reopened fence, span the source
+	// they were made from.
+	StartOffset int
+	EndOffset   int
+	// HeadingPath lists the headings the chunk is under, outermost first, such as "## Install"
+	HeadingPath []string
+	// Anchor is the id of the innermost heading the chunk is under, taken from the heading's {#id}
+	// attribute or made from its text as GitHub does. It is empty when the chunk is under no heading.
+	Anchor     string
+	TokenCount int
+	// ContentType is ContentText, ContentCode, ContentTable or ContentMixed
+	ContentType string
+	// HasCode is set when the chunk contains all or part of a fenced code block
+	HasCode bool
+	// CodeLanguages lists the languages of the chunk's code blocks in order of appearance
+	CodeLanguages []string
+}
+
+// Chunker splits markdown into chunks
+type Chunker interface {
+	Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error)
+}
+
+// newChunk describes the code in a chunk of text. A closing fence at the start of a chunk that
+// begins inside a code block is counted as code without a language.
+func newChunk(text string) Chunk {
+	chunk := Chunk{Text: text}
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		match := fenceRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) && match[2] == "" {
+				fence = ""
+			}
+			continue
+		}
+		fence = match[1]
+		chunk.HasCode = true
+		chunk.CodeLanguages = appendLanguage(chunk.CodeLanguages, match[2])
+	}
+	return chunk
+}
+
+// appendLanguage adds a code language to languages once, ignoring empty ones
+func appendLanguage(languages []string, language string) []string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return languages
+	}
+	for _, l := range languages {
+		if l == language {
+			return languages
+		}
+	}
+	return append(languages, language)
+}
@@ -0,0 +1,109 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
+)
+
+// GRPC chunks markdown with the rag-tools MarkdownChunkerService. The service only knows the
+// tokenizers of the tokenizer package.
+type GRPC struct {
+	client pb.MarkdownChunkerServiceClient
+}
+
+// NewGRPC returns a chunker that calls the rag-tools service through client
+func NewGRPC(client pb.MarkdownChunkerServiceClient) *GRPC {
+	return &GRPC{client: client}
+}
+
+func (g *GRPC) Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	response, err := g.client.ChunkMarkdown(ctx, &pb.ChunkMarkdownRequest{
+		Content:   markdown,
+		ChunkSize: int32(opts.ChunkSize),
+		Overlap:   int32(opts.Overlap),
+		Tokenizer: opts.Tokenizer.Name(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The service does not know the anchors, so they are found from the chunk offsets
+	return anchorChunks(markdown, fromPB(response.Chunks)), nil
+}
+
+// ChunkBatch chunks pages with one ChunkMarkdownBatch call, reading the chunks of each page as the
+// service streams them back
+func (g *GRPC) ChunkBatch(ctx context.Context, pages []Page, opts Options) ([]PageResult, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	request := &pb.ChunkMarkdownBatchRequest{
+		ChunkSize: int32(opts.ChunkSize),
+		Overlap:   int32(opts.Overlap),
+		Tokenizer: opts.Tokenizer.Name(),
+	}
+	index := make(map[int64]int, len(pages))
+	results := make([]PageResult, len(pages))
+	for i, page := range pages {
+		request.Pages = append(request.Pages, &pb.Page{Id: int64(page.ID), Content: page.Markdown})
+		index[int64(page.ID)] = i
+		results[i] = PageResult{PageID: page.ID, Err: fmt.Errorf("no chunks returned for page %d", page.ID)}
+	}
+
+	stream, err := g.client.ChunkMarkdownBatch(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[response.PageId]
+		if !ok {
+			continue
+		}
+		if response.Error != "" {
+			results[i].Err = errors.New(response.Error)
+			continue
+		}
+		results[i].Chunks = anchorChunks(pages[i].Markdown, fromPB(response.Chunks))
+		results[i].Err = nil
+	}
+	return results, nil
+}
+
+// fromPB converts the chunks returned by the rag-tools service
+func fromPB(pbChunks []*pb.Chunk) []Chunk {
+	chunks := make([]Chunk, 0, len(pbChunks))
+	for _, c := range pbChunks {
+		chunk := newChunk(c.Text)
+		chunk.StartOffset = int(c.StartOffset)
+		chunk.EndOffset = int(c.EndOffset)
+		chunk.HeadingPath = c.HeadingPath
+		chunk.TokenCount = int(c.TokenCount)
+		chunk.ContentType = contentTypes[c.ContentType]
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// contentTypes maps the content types of the rag-tools service to the ones of this package
+var contentTypes = map[pb.ContentType]string{
+	pb.ContentType_CONTENT_TYPE_TEXT:  ContentText,
+	pb.ContentType_CONTENT_TYPE_CODE:  ContentCode,
+	pb.ContentType_CONTENT_TYPE_TABLE: ContentTable,
+	pb.ContentType_CONTENT_TYPE_MIXED: ContentMixed,
+}
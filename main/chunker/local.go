@@ -0,0 +1,141 @@
+package chunker
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// markdownSeparators are the separators of LangChain's MarkdownTextSplitter, tried in order. LangChain
+// escapes them before matching, so the heading and horizontal rule patterns only match their literal
+// text. They are kept as they are so chunks stay identical to the rag-tools service.
+var markdownSeparators = []string{
+	"\n#{1,6} ",
+	"```\n",
+	"\n\\*\\*\\*+\n",
+	"\n---+\n",
+	"\n___+\n",
+	"\n\n",
+	"\n",
+	" ",
+	"",
+}
+
+// Local is an in-process port of the MarkdownTextSplitter used by the rag-tools service. Lengths are
+// counted with the tokenizer of the options and separators are kept at the start of the piece that
+// follows them.
+type Local struct{}
+
+// NewLocal returns a chunker that runs in-process
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (l *Local) Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if pythonStrip(markdown) == "" {
+		return []Chunk{}, nil
+	}
+	texts := splitText(markdown, markdownSeparators, opts)
+	return describeChunks(markdown, locateChunks(markdown, texts), opts.tokenCounter()), nil
+}
+
+// splitText splits on the first separator found in text, then splits pieces that are still too
+// long with the remaining separators
+func splitText(text string, separators []string, opts Options) []string {
+	var chunks []string
+
+	separator := separators[len(separators)-1]
+	var remaining []string
+	for i, s := range separators {
+		if s == "" {
+			separator = s
+			break
+		}
+		if strings.Contains(text, s) {
+			separator = s
+			remaining = separators[i+1:]
+			break
+		}
+	}
+
+	var goodSplits []string
+	for _, s := range splitKeepingSeparator(text, separator) {
+		if opts.length(s) < opts.ChunkSize {
+			goodSplits = append(goodSplits, s)
+			continue
+		}
+		if len(goodSplits) > 0 {
+			chunks = append(chunks, mergeSplits(goodSplits, opts)...)
+			goodSplits = nil
+		}
+		if len(remaining) == 0 {
+			chunks = append(chunks, s)
+		} else {
+			chunks = append(chunks, splitText(s, remaining, opts)...)
+		}
+	}
+	if len(goodSplits) > 0 {
+		chunks = append(chunks, mergeSplits(goodSplits, opts)...)
+	}
+	return chunks
+}
+
+// splitKeepingSeparator splits text before each separator, or into characters when the separator is empty
+func splitKeepingSeparator(text string, separator string) []string {
+	var splits []string
+	if separator == "" {
+		for _, r := range text {
+			splits = append(splits, string(r))
+		}
+		return splits
+	}
+	parts := strings.Split(text, separator)
+	if parts[0] != "" {
+		splits = append(splits, parts[0])
+	}
+	for _, part := range parts[1:] {
+		splits = append(splits, separator+part)
+	}
+	return splits
+}
+
+// mergeSplits joins splits into chunks of up to ChunkSize tokens. Each chunk starts with the
+// trailing splits of the previous one, up to Overlap tokens. Like LangChain, the length of a chunk
+// is the sum of the lengths of its splits.
+func mergeSplits(splits []string, opts Options) []string {
+	var chunks []string
+	var current []string
+	total := 0
+	for _, split := range splits {
+		splitLength := opts.length(split)
+		if total+splitLength > opts.ChunkSize {
+			if len(current) > 0 {
+				if chunk := pythonStrip(strings.Join(current, "")); chunk != "" {
+					chunks = append(chunks, chunk)
+				}
+				for total > opts.Overlap || (total+splitLength > opts.ChunkSize && total > 0) {
+					total -= opts.length(current[0])
+					current = current[1:]
+				}
+			}
+		}
+		current = append(current, split)
+		total += splitLength
+	}
+	if chunk := pythonStrip(strings.Join(current, "")); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// pythonStrip trims whitespace like Python's str.strip, which also treats the ASCII separator
+// characters as whitespace
+func pythonStrip(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || (r >= '\x1c' && r <= '\x1f')
+	})
+}
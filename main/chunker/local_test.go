@@ -0,0 +1,130 @@
+package chunker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+// parityCase is one chunk size, overlap and tokenizer of a fixture written by
+// rag-tools/scripts/generate_parity_fixtures.py
+type parityCase struct {
+	ChunkSize int      `json:"chunk_size"`
+	Overlap   int      `json:"overlap"`
+	Tokenizer string   `json:"tokenizer"`
+	Chunks    []string `json:"chunks"`
+}
+
+// TestLocalParity checks that the local chunker returns the same chunks as the rag-tools service
+// for every testdata/parity/*.md page
+func TestLocalParity(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "parity", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no test pages found")
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".md")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixture, err := os.ReadFile(strings.TrimSuffix(page, ".md") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cases []parityCase
+			if err := json.Unmarshal(fixture, &cases); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tc := range cases {
+				tok, ok := tokenizer.ByName(tc.Tokenizer)
+				if !ok {
+					t.Fatalf("unknown tokenizer %q", tc.Tokenizer)
+				}
+				result, err := NewLocal().Chunk(context.Background(), string(input), Options{ChunkSize: tc.ChunkSize, Overlap: tc.Overlap, Tokenizer: tok})
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks := []string{}
+				for _, chunk := range result {
+					chunks = append(chunks, chunk.Text)
+				}
+				if !reflect.DeepEqual(chunks, tc.Chunks) {
+					t.Errorf("%s chunk size %d, overlap %d: got %d chunks, want %d", tc.Tokenizer, tc.ChunkSize, tc.Overlap, len(chunks), len(tc.Chunks))
+					for i := 0; i < len(chunks) && i < len(tc.Chunks); i++ {
+						if chunks[i] != tc.Chunks[i] {
+							t.Errorf("first difference at chunk %d:\n--- got ---\n%s\n--- want ---\n%s", i, chunks[i], tc.Chunks[i])
+							break
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLocalEdgeCases(t *testing.T) {
+	local := NewLocal()
+
+	chunks, err := local.Chunk(context.Background(), " \n\t\n", Options{})
+	if err != nil || len(chunks) != 0 {
+		t.Errorf("blank markdown = %v, %v, want no chunks", chunks, err)
+	}
+
+	if _, err := local.Chunk(context.Background(), "text", Options{ChunkSize: 100, Overlap: 200}); err == nil {
+		t.Error("expected an error when the overlap is larger than the chunk size")
+	}
+	if _, err := local.Chunk(context.Background(), "text", Options{ChunkSize: 100, Overlap: -1}); err == nil {
+		t.Error("expected an error when the overlap is negative")
+	}
+
+	// Sizes default to tokens counted by the approximate tokenizer
+	chunks, err = local.Chunk(context.Background(), strings.Repeat("This is a test. ", 100), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Errorf("got %d chunks, want the text split", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.TokenCount > DefaultChunkSize {
+			t.Errorf("chunk %d has %d tokens, want at most %d", i, chunk.TokenCount, DefaultChunkSize)
+		}
+	}
+}
+
+func TestLocalHonorsZeroOverlap(t *testing.T) {
+	var sentences []string
+	for i := range 40 {
+		sentences = append(sentences, fmt.Sprintf("Sentence %d of the guide.", i))
+	}
+	markdown := strings.Join(sentences, " ")
+	for _, overlap := range []int{0, 30} {
+		chunks, err := NewLocal().Chunk(context.Background(), markdown, Options{ChunkSize: 100, Overlap: overlap, Tokenizer: tokenizer.Characters{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		overlapping := false
+		for i := 1; i < len(chunks); i++ {
+			if chunks[i].StartOffset < chunks[i-1].EndOffset {
+				overlapping = true
+			}
+		}
+		if overlapping != (overlap > 0) {
+			t.Errorf("overlap %d: chunks overlap = %v", overlap, overlapping)
+		}
+	}
+}
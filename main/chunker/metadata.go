@@ -0,0 +1,239 @@
+package chunker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+// Content types of a chunk
+const (
+	ContentText  = "text"
+	ContentCode  = "code"
+	ContentTable = "table"
+	ContentMixed = "mixed"
+)
+
+var (
+	headingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	// headingIDRegex matches the {#id} attribute the markdown converter adds to headings with an id
+	headingIDRegex = regexp.MustCompile(`[ \t]*\{#([A-Za-z][\w\-.:]*)\}$`)
+	// inlineLinkRegex matches inline links and images, whose text is kept in slugs
+	inlineLinkRegex = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// heading is an ATX heading, the byte offset of the line it is on and the anchor it can be linked to
+type heading struct {
+	offset int
+	level  int
+	text   string
+	anchor string
+}
+
+// fence is a fenced code block from the start of its opening line to the end of its closing line
+type fence struct {
+	start  int
+	end    int
+	marker string
+}
+
+// scanMarkdown returns the ATX headings of markdown outside fenced code blocks, and the fenced code
+// blocks
+func scanMarkdown(markdown string) ([]heading, []fence) {
+	var headings []heading
+	var fences []fence
+	var open *fence
+	anchors := make(map[string]bool)
+	offset := 0
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		lineOffset := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			if open == nil {
+				open = &fence{start: lineOffset, marker: match[1]}
+			} else if strings.HasPrefix(strings.TrimSpace(line), open.marker) && match[2] == "" {
+				open.end = offset
+				fences = append(fences, *open)
+				open = nil
+			}
+			continue
+		}
+		if open != nil {
+			continue
+		}
+		if match := headingRegex.FindStringSubmatch(line); match != nil && match[2] != "" {
+			headings = append(headings, heading{offset: lineOffset, level: len(match[1]), text: match[2], anchor: headingAnchor(match[2], anchors)})
+		}
+	}
+	if open != nil {
+		open.end = len(markdown)
+		fences = append(fences, *open)
+	}
+	return headings, fences
+}
+
+// openFence returns the marker of the code block an offset is inside, or "" when it is not in one
+func openFence(fences []fence, offset int) string {
+	for _, f := range fences {
+		if f.start < offset && offset < f.end {
+			return f.marker
+		}
+	}
+	return ""
+}
+
+// headingAnchor returns the id of a heading's {#id} attribute, or else a slug of its text made the
+// way GitHub does: lowercased, with spaces turned into hyphens and punctuation dropped. A slug that
+// is already in use gets the first free "-1", "-2" suffix.
+func headingAnchor(text string, anchors map[string]bool) string {
+	if match := headingIDRegex.FindStringSubmatch(text); match != nil {
+		anchors[match[1]] = true
+		return match[1]
+	}
+
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(inlineLinkRegex.ReplaceAllString(text, "$1"))) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_', r == '-':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+	anchor := slug.String()
+	for i := 1; anchors[anchor]; i++ {
+		anchor = fmt.Sprintf("%s-%d", slug.String(), i)
+	}
+	anchors[anchor] = true
+	return anchor
+}
+
+// headingPath returns the headings a byte offset is under, outermost first. A heading on the line
+// the offset is on is included, and {#id} attributes are left out.
+func headingPath(headings []heading, offset int) []string {
+	stack := headingStack(headings, offset)
+	path := make([]string, 0, len(stack))
+	for _, h := range stack {
+		path = append(path, strings.Repeat("#", h.level)+" "+headingIDRegex.ReplaceAllString(h.text, ""))
+	}
+	return path
+}
+
+// sectionAnchor returns the anchor of the innermost heading a byte offset is under
+func sectionAnchor(headings []heading, offset int) string {
+	stack := headingStack(headings, offset)
+	if len(stack) == 0 {
+		return ""
+	}
+	return stack[len(stack)-1].anchor
+}
+
+// headingStack returns the headings a byte offset is under, outermost first
+func headingStack(headings []heading, offset int) []heading {
+	var stack []heading
+	for _, h := range headings {
+		if h.offset > offset {
+			break
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+	}
+	return stack
+}
+
+// contentType describes a chunk as code or a table when everything but its headings is code or
+// table rows, as text when it has neither, and as mixed otherwise. fence is the marker of the code
+// block the chunk starts in, if any.
+func contentType(text string, fence string) string {
+	var hasCode, hasTable, hasText bool
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			hasCode = true
+			if fence == "" {
+				fence = match[1]
+			} else if strings.HasPrefix(trimmed, fence) && match[2] == "" {
+				fence = ""
+			}
+			continue
+		}
+		switch {
+		case fence != "":
+			hasCode = true
+		case trimmed == "" || headingRegex.MatchString(line):
+		case strings.HasPrefix(trimmed, "|"):
+			hasTable = true
+		default:
+			hasText = true
+		}
+	}
+
+	switch {
+	case hasCode && !hasTable && !hasText:
+		return ContentCode
+	case hasTable && !hasCode && !hasText:
+		return ContentTable
+	case !hasCode && !hasTable:
+		return ContentText
+	default:
+		return ContentMixed
+	}
+}
+
+// locateChunks finds each chunk in markdown. Every chunk starts after the one before it, so the
+// search starts after the previous chunk's start and repeated text is matched in order. Chunks
+// that cannot be found get the offsets of the previous one.
+func locateChunks(markdown string, texts []string) []Chunk {
+	chunks := make([]Chunk, 0, len(texts))
+	start, end := 0, 0
+	for i, text := range texts {
+		from := start
+		if i > 0 && from < len(markdown) {
+			_, size := utf8.DecodeRuneInString(markdown[from:])
+			from += size
+		}
+		if j := strings.Index(markdown[from:], text); j >= 0 {
+			start = from + j
+			end = start + len(text)
+		} else if j := strings.Index(markdown, text); j >= 0 {
+			start = j
+			end = start + len(text)
+		}
+
+		chunk := newChunk(text)
+		chunk.StartOffset = start
+		chunk.EndOffset = end
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// describeChunks fills in the heading path, anchor, token count and content type of chunks whose
+// offsets are set
+func describeChunks(markdown string, chunks []Chunk, counter tokenizer.Tokenizer) []Chunk {
+	headings, fences := scanMarkdown(markdown)
+	for i := range chunks {
+		chunks[i].HeadingPath = headingPath(headings, chunks[i].StartOffset)
+		chunks[i].Anchor = sectionAnchor(headings, chunks[i].StartOffset)
+		chunks[i].TokenCount = counter.Count(chunks[i].Text)
+		chunks[i].ContentType = contentType(chunks[i].Text, openFence(fences, chunks[i].StartOffset))
+	}
+	return chunks
+}
+
+// anchorChunks fills in the anchor of chunks whose offsets are set
+func anchorChunks(markdown string, chunks []Chunk) []Chunk {
+	headings, _ := scanMarkdown(markdown)
+	for i := range chunks {
+		chunks[i].Anchor = sectionAnchor(headings, chunks[i].StartOffset)
+	}
+	return chunks
+}
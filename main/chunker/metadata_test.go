@@ -0,0 +1,163 @@
+package chunker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+func TestLocateChunksWithRepeatedText(t *testing.T) {
+	markdown := "Note: see below.\n\nStep one.\n\nNote: see below.\n\nStep two."
+	chunks := locateChunks(markdown, []string{"Note: see below.\n\nStep one.", "Note: see below.\n\nStep two."})
+
+	want := [][2]int{{0, 27}, {29, len(markdown)}}
+	for i, chunk := range chunks {
+		if got := [2]int{chunk.StartOffset, chunk.EndOffset}; got != want[i] {
+			t.Errorf("chunk %d offsets = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+// TestLocalOffsets checks that every chunk of the parity pages is found where it was cut from,
+// including when chunks overlap
+func TestLocalOffsets(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "parity", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		input, err := os.ReadFile(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := string(input)
+		chunks, err := NewLocal().Chunk(context.Background(), markdown, Options{ChunkSize: 250, Overlap: 50, Tokenizer: tokenizer.Characters{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := 0
+		for i, chunk := range chunks {
+			if markdown[chunk.StartOffset:chunk.EndOffset] != chunk.Text {
+				t.Errorf("%s chunk %d: offsets %d-%d do not match its text", page, i, chunk.StartOffset, chunk.EndOffset)
+			}
+			if chunk.StartOffset < previous {
+				t.Errorf("%s chunk %d starts at %d, before the previous chunk at %d", page, i, chunk.StartOffset, previous)
+			}
+			previous = chunk.StartOffset
+		}
+	}
+}
+
+func TestHeadingPath(t *testing.T) {
+	markdown := "# Guide\n\nIntro.\n\n## Install\n\n```sh\n# not a heading\n```\n\n### Linux\n\nText.\n\n## Configure\n\nMore."
+	headings, _ := scanMarkdown(markdown)
+
+	tests := []struct {
+		at   string
+		want []string
+	}{
+		{"Intro.", []string{"# Guide"}},
+		{"## Install", []string{"# Guide", "## Install"}},
+		{"# not a heading", []string{"# Guide", "## Install"}},
+		{"Text.", []string{"# Guide", "## Install", "### Linux"}},
+		{"More.", []string{"# Guide", "## Configure"}},
+	}
+	for _, tt := range tests {
+		if got := headingPath(headings, strings.Index(markdown, tt.at)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("headingPath at %q = %q, want %q", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingPathDropsHeadingIDs(t *testing.T) {
+	markdown := "# Intro {#intro}\n\n## Install {#setup}\n\nRun it."
+	headings, _ := scanMarkdown(markdown)
+
+	want := []string{"# Intro", "## Install"}
+	if got := headingPath(headings, strings.Index(markdown, "Run it.")); !reflect.DeepEqual(got, want) {
+		t.Errorf("headingPath = %q, want %q", got, want)
+	}
+	if got := sectionAnchor(headings, strings.Index(markdown, "Run it.")); got != "setup" {
+		t.Errorf("sectionAnchor = %q, want setup", got)
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	markdown := "# Guide {#guide}\n\n## Install the `cli` tool\n\n## Use [the API](https://example.com/api)\n\n" +
+		"## Install the `cli` tool\n\n## Überblick & Setup\n\n## Install the cli tool {#install-the-cli-tool-1}\n\n## Install the cli tool"
+	headings, _ := scanMarkdown(markdown)
+
+	want := []string{"guide", "install-the-cli-tool", "use-the-api", "install-the-cli-tool-1", "überblick--setup", "install-the-cli-tool-1", "install-the-cli-tool-2"}
+	if len(headings) != len(want) {
+		t.Fatalf("got %d headings, want %d", len(headings), len(want))
+	}
+	for i, h := range headings {
+		if h.anchor != want[i] {
+			t.Errorf("heading %q anchor = %q, want %q", h.text, h.anchor, want[i])
+		}
+	}
+}
+
+func TestChunkAnchors(t *testing.T) {
+	markdown := "Intro.\n\n# Guide {#guide}\n\n## Install\n\n" + strings.Repeat("Install it. ", 30)
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 100, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks[0].Anchor != "" {
+		t.Errorf("first chunk anchor = %q, want none", chunks[0].Anchor)
+	}
+	if last := chunks[len(chunks)-1]; last.Anchor != "install" {
+		t.Errorf("last chunk anchor = %q, want install", last.Anchor)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		text  string
+		fence string
+		want  string
+	}{
+		{"Just some prose.", "", ContentText},
+		{"## Example\n\n```go\nfmt.Println()\n```", "", ContentCode},
+		{"| a | b |\n| --- | --- |\n| 1 | 2 |", "", ContentTable},
+		{"Run this:\n\n```sh\nmake\n```", "", ContentMixed},
+		{"fmt.Println()\n```", "```", ContentCode},
+		{"```\n\n## Run\n\nRun the server.", "```", ContentMixed},
+	}
+	for _, tt := range tests {
+		if got := contentType(tt.text, tt.fence); got != tt.want {
+			t.Errorf("contentType(%q, %q) = %q, want %q", tt.text, tt.fence, got, tt.want)
+		}
+	}
+}
+
+func TestStructuredOffsets(t *testing.T) {
+	install := strings.TrimSpace(strings.Repeat("Install it. ", 30))
+	run := strings.TrimSpace(strings.Repeat("Run it. ", 20))
+	markdown := "# Guide\n\nIntro.\n\n## Install\n\n" + install + "\n\n## Run\n\n" + run
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 200, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the page split", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if markdown[chunk.StartOffset:chunk.EndOffset] != chunk.Text {
+			t.Errorf("chunk %d: offsets %d-%d do not match its text", i, chunk.StartOffset, chunk.EndOffset)
+		}
+	}
+	last := chunks[len(chunks)-1]
+	if !reflect.DeepEqual(last.HeadingPath, []string{"# Guide", "## Run"}) {
+		t.Errorf("last chunk heading path = %q, want [# Guide ## Run]", last.HeadingPath)
+	}
+	if last.TokenCount == 0 || last.ContentType != ContentText {
+		t.Errorf("last chunk has %d tokens and content type %q", last.TokenCount, last.ContentType)
+	}
+}
@@ -0,0 +1,56 @@
+package chunker
+
+import (
+	"slices"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+// DefaultSectionSize is the number of tokens a section grows to before it is split
+const DefaultSectionSize = 1024
+
+// Section is the markdown around consecutive chunks under the same headings. Chunks are small so
+// they embed precisely, and the section they are in is what is given to the model as context.
+type Section struct {
+	Chunk
+	// Chunks are the indexes of the section's chunks
+	Chunks []int
+}
+
+// Sections groups chunks into sections keyed by their heading path. A section spans the markdown
+// from its first chunk to its last and is split before it grows past size tokens, so a long part of
+// a page under one heading becomes several sections. A chunk bigger than size is a section of its own.
+func Sections(markdown string, chunks []Chunk, size int, counter tokenizer.Tokenizer) []Section {
+	if size <= 0 {
+		size = DefaultSectionSize
+	}
+
+	var sections []Section
+	for i, chunk := range chunks {
+		if n := len(sections); n > 0 {
+			last := &sections[n-1]
+			end := max(last.EndOffset, chunk.EndOffset)
+			if slices.Equal(last.HeadingPath, chunk.HeadingPath) && counter.Count(markdown[last.StartOffset:end]) <= size {
+				last.EndOffset = end
+				last.Chunks = append(last.Chunks, i)
+				continue
+			}
+		}
+		sections = append(sections, Section{
+			Chunk:  Chunk{StartOffset: chunk.StartOffset, EndOffset: chunk.EndOffset, HeadingPath: chunk.HeadingPath},
+			Chunks: []int{i},
+		})
+	}
+
+	described := make([]Chunk, len(sections))
+	for i, section := range sections {
+		described[i] = newChunk(markdown[section.StartOffset:section.EndOffset])
+		described[i].StartOffset = section.StartOffset
+		described[i].EndOffset = section.EndOffset
+	}
+	described = describeChunks(markdown, described, counter)
+	for i := range sections {
+		sections[i].Chunk = described[i]
+	}
+	return sections
+}
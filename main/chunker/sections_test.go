@@ -0,0 +1,74 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+func TestSectionsGroupChunksByHeadingPath(t *testing.T) {
+	install := strings.TrimSpace(strings.Repeat("Install it. ", 30))
+	run := strings.TrimSpace(strings.Repeat("Run it. ", 20))
+	markdown := "# Guide\n\nIntro.\n\n## Install\n\n" + install + "\n\n## Run\n\n" + run
+	opts := Options{ChunkSize: 100, Overlap: 10, Tokenizer: tokenizer.Characters{}}
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := Sections(markdown, chunks, 1000, tokenizer.Characters{})
+	var paths [][]string
+	covered := 0
+	for _, section := range sections {
+		paths = append(paths, section.HeadingPath)
+		if markdown[section.StartOffset:section.EndOffset] != section.Text {
+			t.Errorf("section %q: offsets do not match its text", section.HeadingPath)
+		}
+		for _, i := range section.Chunks {
+			if !reflect.DeepEqual(chunks[i].HeadingPath, section.HeadingPath) {
+				t.Errorf("chunk %d under %q is in section %q", i, chunks[i].HeadingPath, section.HeadingPath)
+			}
+			if chunks[i].StartOffset < section.StartOffset || chunks[i].EndOffset > section.EndOffset {
+				t.Errorf("chunk %d is outside its section", i)
+			}
+			covered++
+		}
+	}
+	if covered != len(chunks) {
+		t.Errorf("sections hold %d chunks, want %d", covered, len(chunks))
+	}
+
+	want := [][]string{{"# Guide"}, {"# Guide", "## Install"}, {"# Guide", "## Run"}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("section heading paths = %q, want %q", paths, want)
+	}
+	if sections[1].Anchor != "install" || sections[1].TokenCount != len(sections[1].Text) {
+		t.Errorf("install section has anchor %q and %d tokens", sections[1].Anchor, sections[1].TokenCount)
+	}
+}
+
+func TestSectionsSplitLongSections(t *testing.T) {
+	var reference []string
+	for i := range 40 {
+		reference = append(reference, fmt.Sprintf("Sentence %d of the reference.", i))
+	}
+	markdown := "## Reference\n\n" + strings.Join(reference, " ")
+	chunks, err := NewLocal().Chunk(context.Background(), markdown, Options{ChunkSize: 100, Overlap: 0, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := Sections(markdown, chunks, 400, tokenizer.Characters{})
+	if len(sections) < 3 {
+		t.Fatalf("got %d sections, want the reference split", len(sections))
+	}
+	for _, section := range sections {
+		if len(section.Chunks) > 1 && section.TokenCount > 400 {
+			t.Errorf("section of %d chunks has %d tokens, want at most 400", len(section.Chunks), section.TokenCount)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package chunker
+
+import "fmt"
+
+// Strategies a source can be chunked with
+const (
+	// StrategyRecursive splits markdown by separators like LangChain, in-process or in the rag-tools
+	// service
+	StrategyRecursive = "recursive"
+	// StrategyStructured splits markdown at headings without breaking code blocks, tables or lists
+	StrategyStructured = "structured"
+)
+
+// StrategyVersion is bumped whenever a change to the chunkers changes the chunks they make, so that
+// chunks made before the change can be found by their strategy version and made again
+const StrategyVersion = 1
+
+// Strategy is how the pages of a source are chunked
+type Strategy struct {
+	// Name is StrategyRecursive or StrategyStructured
+	Name        string
+	Options     Options
+	SectionSize int
+}
+
+// Version identifies the strategy and its settings, such as
+// "structured/v1 size=256 overlap=50 sections=1024 tokenizer=approximate"
+func (s Strategy) Version() string {
+	opts := s.Options.withDefaults()
+	sectionSize := s.SectionSize
+	if sectionSize <= 0 {
+		sectionSize = DefaultSectionSize
+	}
+	return fmt.Sprintf("%s/v%d size=%d overlap=%d sections=%d tokenizer=%s", s.Name, StrategyVersion, opts.ChunkSize, opts.Overlap, sectionSize, opts.Tokenizer.Name())
+}
+
+// StrategyOf returns the strategy a chunker implements
+func StrategyOf(c Chunker) string {
+	if _, ok := c.(*Structured); ok {
+		return StrategyStructured
+	}
+	return StrategyRecursive
+}
+
+// ForStrategy returns a chunker for a strategy. The configured chunker is used when it implements
+// the strategy or no strategy is given, so recursive chunking stays in the rag-tools service when
+// it is configured.
+func ForStrategy(strategy string, configured Chunker) (Chunker, error) {
+	switch {
+	case strategy == "" || strategy == StrategyOf(configured):
+		return configured, nil
+	case strategy == StrategyStructured:
+		return NewStructured(), nil
+	case strategy == StrategyRecursive:
+		return NewLocal(), nil
+	}
+	return nil, fmt.Errorf("unknown chunking strategy %q, want %s or %s", strategy, StrategyRecursive, StrategyStructured)
+}
@@ -0,0 +1,44 @@
+package chunker
+
+import (
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+func TestForStrategy(t *testing.T) {
+	local := NewLocal()
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{"", StrategyRecursive},
+		{StrategyRecursive, StrategyRecursive},
+		{StrategyStructured, StrategyStructured},
+	}
+	for _, tt := range tests {
+		c, err := ForStrategy(tt.strategy, local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := StrategyOf(c); got != tt.want {
+			t.Errorf("ForStrategy(%q) = %s chunker, want %s", tt.strategy, got, tt.want)
+		}
+	}
+
+	// The configured chunker is kept when it implements the strategy
+	if c, _ := ForStrategy(StrategyRecursive, local); c != Chunker(local) {
+		t.Errorf("ForStrategy(recursive) did not return the configured chunker")
+	}
+	if _, err := ForStrategy("semantic", local); err == nil {
+		t.Errorf("ForStrategy(semantic) returned no error")
+	}
+}
+
+func TestStrategyVersion(t *testing.T) {
+	strategy := Strategy{Name: StrategyStructured, Options: Options{ChunkSize: 512, Tokenizer: tokenizer.Characters{}}}
+	want := "structured/v1 size=512 overlap=0 sections=1024 tokenizer=characters"
+	if got := strategy.Version(); got != want {
+		t.Errorf("Version() = %q, want %q", got, want)
+	}
+}
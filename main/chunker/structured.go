@@ -0,0 +1,539 @@
+package chunker
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// hardLimitFactor is how many times the chunk size a code block, table or list may grow to before
+// it is split
+const hardLimitFactor = 2
+
+// blockSeparator joins the blocks of a chunk
+const blockSeparator = "\n\n"
+
+type blockKind int
+
+const (
+	blockText blockKind = iota
+	blockHeading
+	blockCode
+	blockTable
+	blockList
+)
+
+// block is a top-level markdown block with its source text and the byte offsets of that text
+type block struct {
+	kind     blockKind
+	text     string
+	start    int
+	end      int
+	language string
+	// items holds the source text of each list item
+	items []string
+}
+
+// piece is a block, or part of one, that is placed in a chunk whole
+type piece struct {
+	text      string
+	start     int
+	end       int
+	heading   bool
+	code      bool
+	languages []string
+}
+
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.Table)).Parser()
+
+// Structured chunks markdown along its structure. Sections are split at headings first and small
+// sections are packed together. Fenced code blocks, tables and lists are never split unless they
+// grow past twice the chunk size; code blocks, also those in list items, are then split with the
+// fence reopened in every chunk, and tables repeat their header. Chunks do not overlap.
+type Structured struct{}
+
+// NewStructured returns a structure-aware chunker that runs in-process
+func NewStructured() *Structured {
+	return &Structured{}
+}
+
+func (s *Structured) Chunk(ctx context.Context, markdown string, opts Options) ([]Chunk, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var sections [][]block
+	for _, b := range parseBlocks([]byte(markdown)) {
+		if b.kind == blockHeading || len(sections) == 0 {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], b)
+	}
+
+	packer := chunkPacker{opts: opts, chunks: []Chunk{}}
+	for _, section := range sections {
+		var pieces []piece
+		for _, b := range section {
+			pieces = append(pieces, splitBlock(b, opts)...)
+		}
+		packer.addSection(pieces)
+	}
+	packer.finish()
+	return describeChunks(markdown, packer.chunks, opts.tokenCounter()), nil
+}
+
+// chunkPacker fills chunks with pieces up to the chunk size
+type chunkPacker struct {
+	opts    Options
+	chunks  []Chunk
+	current []piece
+	length  int
+}
+
+// addSection adds the pieces of a section to the current chunk when the whole section fits, and
+// otherwise starts a new chunk and fills it piece by piece
+func (p *chunkPacker) addSection(pieces []piece) {
+	sectionLength := 0
+	for i, pc := range pieces {
+		if i > 0 {
+			sectionLength += p.opts.length(blockSeparator)
+		}
+		sectionLength += p.opts.length(pc.text)
+	}
+	if p.fits(sectionLength) {
+		for _, pc := range pieces {
+			p.add(pc)
+		}
+		return
+	}
+
+	p.flush()
+	for _, pc := range pieces {
+		if !p.fits(p.opts.length(pc.text)) {
+			p.flush()
+		}
+		p.add(pc)
+	}
+}
+
+func (p *chunkPacker) fits(n int) bool {
+	if len(p.current) == 0 {
+		return n <= p.opts.ChunkSize
+	}
+	return p.length+p.opts.length(blockSeparator)+n <= p.opts.ChunkSize
+}
+
+func (p *chunkPacker) add(pc piece) {
+	if len(p.current) > 0 {
+		p.length += p.opts.length(blockSeparator)
+	}
+	p.current = append(p.current, pc)
+	p.length += p.opts.length(pc.text)
+}
+
+// flush closes the current chunk. Headings stay with the content that follows them, so a chunk
+// holding only headings is kept open.
+func (p *chunkPacker) flush() {
+	hasContent := false
+	for _, pc := range p.current {
+		hasContent = hasContent || !pc.heading
+	}
+	if hasContent {
+		p.finish()
+	}
+}
+
+// finish closes the current chunk, even when it only holds headings
+func (p *chunkPacker) finish() {
+	if len(p.current) == 0 {
+		return
+	}
+
+	texts := make([]string, 0, len(p.current))
+	chunk := Chunk{StartOffset: p.current[0].start, EndOffset: p.current[len(p.current)-1].end}
+	for _, pc := range p.current {
+		texts = append(texts, pc.text)
+		if pc.code {
+			chunk.HasCode = true
+			for _, language := range pc.languages {
+				chunk.CodeLanguages = appendLanguage(chunk.CodeLanguages, language)
+			}
+		}
+	}
+	chunk.Text = strings.Join(texts, blockSeparator)
+	p.chunks = append(p.chunks, chunk)
+	p.current = nil
+	p.length = 0
+}
+
+// splitBlock returns a block as one piece, or as several when it is too long. Code blocks, tables
+// and lists may grow to the hard limit before they are split.
+func splitBlock(b block, opts Options) []piece {
+	whole := newPiece(b, b.text)
+	whole.heading = b.kind == blockHeading
+	limit := opts.ChunkSize
+	if b.kind == blockCode || b.kind == blockTable || b.kind == blockList {
+		limit = opts.ChunkSize * hardLimitFactor
+	}
+	if opts.length(b.text) <= limit || b.kind == blockHeading {
+		return []piece{whole}
+	}
+
+	var texts []string
+	switch b.kind {
+	case blockCode:
+		texts = splitCode(b.text, opts)
+	case blockTable:
+		texts = splitTable(b.text, opts)
+	case blockList:
+		texts = splitList(b.items, opts)
+	default:
+		texts = splitProse(b.text, opts.ChunkSize, opts)
+	}
+
+	pieces := make([]piece, 0, len(texts))
+	cursor := 0
+	for i, t := range texts {
+		pc := newPiece(b, t)
+		// Find the source of the piece without the fence or table header it repeats. The first and
+		// last pieces are stretched to the ends of the block.
+		body := pieceBody(b.kind, t)
+		if j := strings.Index(b.text[cursor:], body); j >= 0 {
+			if i > 0 {
+				pc.start = b.start + cursor + j
+			}
+			if i < len(texts)-1 {
+				pc.end = b.start + cursor + j + len(body)
+			}
+			cursor += j + len(body)
+		}
+		pieces = append(pieces, pc)
+	}
+	return pieces
+}
+
+// newPiece returns a piece of a block with the text t, spanning the whole block until its offsets
+// are narrowed. Lists hold code when they have fenced code blocks in their items.
+func newPiece(b block, t string) piece {
+	pc := piece{text: t, start: b.start, end: b.end}
+	switch b.kind {
+	case blockCode:
+		pc.code = true
+		pc.languages = []string{b.language}
+	case blockList:
+		described := newChunk(t)
+		pc.code = described.HasCode
+		pc.languages = described.CodeLanguages
+	}
+	return pc
+}
+
+// pieceBody returns the part of a piece that was copied from its block
+func pieceBody(kind blockKind, text string) string {
+	lines := strings.Split(text, "\n")
+	switch {
+	case (kind == blockCode || kind == blockList && fenceRegex.MatchString(lines[0])) && len(lines) > 2:
+		return strings.Join(lines[1:len(lines)-1], "\n")
+	case kind == blockTable && len(lines) > 2:
+		return strings.Join(lines[2:], "\n")
+	}
+	return text
+}
+
+// splitCode splits a fenced code block between lines and reopens the fence in every part
+func splitCode(code string, opts Options) []string {
+	lines := strings.Split(code, "\n")
+	opening := lines[0]
+	closing := fenceRegex.FindStringSubmatch(opening)[1]
+	body := lines[1:]
+	if len(body) > 0 && strings.HasPrefix(strings.TrimSpace(body[len(body)-1]), closing) {
+		body = body[:len(body)-1]
+	}
+
+	newline := opts.length("\n")
+	wrapLength := opts.length(opening) + opts.length(closing) + 2*newline
+	var parts []string
+	var current []string
+	currentLength := 0
+	emit := func() {
+		if len(current) > 0 {
+			parts = append(parts, opening+"\n"+strings.Join(current, "\n")+"\n"+closing)
+		}
+		current = nil
+		currentLength = 0
+	}
+	for _, line := range body {
+		for _, segment := range splitLongLine(line, opts.ChunkSize-wrapLength, opts) {
+			segmentLength := opts.length(segment)
+			if len(current) > 0 && wrapLength+currentLength+newline+segmentLength > opts.ChunkSize {
+				emit()
+			}
+			if len(current) > 0 {
+				currentLength += newline
+			}
+			current = append(current, segment)
+			currentLength += segmentLength
+		}
+	}
+	emit()
+	return parts
+}
+
+// splitTable splits a table between rows and repeats the header row and delimiter row in every part
+func splitTable(table string, opts Options) []string {
+	lines := strings.Split(table, "\n")
+	if len(lines) < 3 {
+		return splitProse(table, opts.ChunkSize, opts)
+	}
+	header := strings.Join(lines[:2], "\n")
+	return packParts(lines[2:], header, opts)
+}
+
+// splitList packs list items into parts of up to the chunk size. Items that are too long on their
+// own and hold fenced code blocks are split at the fences, so the code is split like top-level code.
+func splitList(items []string, opts Options) []string {
+	var parts []string
+	var run []string
+	for _, item := range items {
+		if opts.length(item) <= opts.ChunkSize || !hasFence(item) {
+			run = append(run, item)
+			continue
+		}
+		parts = append(parts, packParts(run, "", opts)...)
+		run = nil
+		parts = append(parts, splitListItem(item, opts)...)
+	}
+	return append(parts, packParts(run, "", opts)...)
+}
+
+// hasFence reports whether a list item has a fenced code block
+func hasFence(item string) bool {
+	for _, line := range strings.Split(item, "\n") {
+		if fenceRegex.MatchString(strings.TrimLeft(line, " ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitListItem splits a list item into its prose and its fenced code blocks, splitting the prose
+// as prose and the code with the fence reopened in every part. Fences are unindented so every part
+// opens its code block, and the code lines keep their indentation.
+func splitListItem(item string, opts Options) []string {
+	var parts []string
+	var prose []string
+	emitProse := func() {
+		if text := strings.TrimSpace(strings.Join(prose, "\n")); text != "" {
+			parts = append(parts, splitProse(text, opts.ChunkSize, opts)...)
+		}
+		prose = nil
+	}
+
+	lines := strings.Split(item, "\n")
+	for i := 0; i < len(lines); i++ {
+		opening := strings.TrimLeft(lines[i], " ")
+		match := fenceRegex.FindStringSubmatch(opening)
+		if match == nil {
+			prose = append(prose, lines[i])
+			continue
+		}
+		emitProse()
+
+		code := []string{opening}
+		for i++; i < len(lines); i++ {
+			if closing := strings.TrimSpace(lines[i]); strings.HasPrefix(closing, match[1]) && strings.Trim(closing, match[1][:1]) == "" {
+				code = append(code, closing)
+				break
+			}
+			code = append(code, lines[i])
+		}
+		parts = append(parts, splitCode(strings.Join(code, "\n"), opts)...)
+	}
+	emitProse()
+	return parts
+}
+
+// packParts joins consecutive lines or items into parts of up to the chunk size, each starting
+// with header when one is given. Parts that are too long on their own are split as prose.
+func packParts(parts []string, header string, opts Options) []string {
+	newline := opts.length("\n")
+	headerLength := 0
+	if header != "" {
+		headerLength = opts.length(header)
+	}
+
+	var packed []string
+	var current []string
+	currentLength := headerLength
+	emit := func() {
+		if len(current) > 0 {
+			text := strings.Join(current, "\n")
+			if header != "" {
+				text = header + "\n" + text
+			}
+			packed = append(packed, strings.TrimRight(text, "\n"))
+		}
+		current = nil
+		currentLength = headerLength
+	}
+	for _, part := range parts {
+		part = strings.TrimRight(part, "\n")
+		partLength := opts.length(part)
+		if currentLength+newline+partLength > opts.ChunkSize {
+			emit()
+		}
+		if currentLength+newline+partLength > opts.ChunkSize {
+			packed = append(packed, splitProse(part, opts.ChunkSize, opts)...)
+			continue
+		}
+		current = append(current, part)
+		currentLength += newline + partLength
+	}
+	emit()
+	return packed
+}
+
+// splitProse splits text into parts of up to size between paragraphs, lines, words or characters
+func splitProse(text string, size int, opts Options) []string {
+	return splitText(text, []string{"\n\n", "\n", " ", ""}, Options{ChunkSize: size, Tokenizer: opts.Tokenizer})
+}
+
+// splitLongLine splits a line that is longer than size between words or characters. Whitespace
+// around the parts is dropped.
+func splitLongLine(line string, size int, opts Options) []string {
+	if size <= 0 || opts.length(line) <= size {
+		return []string{line}
+	}
+	return splitText(line, []string{" ", ""}, Options{ChunkSize: size, Tokenizer: opts.Tokenizer})
+}
+
+// parseBlocks parses markdown and returns its top-level blocks with their source text. Each block
+// runs from the line it starts on to the start of the next block, so nodes without source
+// positions, such as thematic breaks, stay with the block before them.
+func parseBlocks(source []byte) []block {
+	doc := markdownParser.Parse(text.NewReader(source))
+
+	type start struct {
+		offset int
+		node   ast.Node
+	}
+	var starts []start
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		offset := nodeStart(n, source)
+		if offset < 0 {
+			continue
+		}
+		if len(starts) > 0 && offset <= starts[len(starts)-1].offset {
+			continue
+		}
+		starts = append(starts, start{offset: offset, node: n})
+	}
+	if len(starts) > 0 {
+		// Text before the first block, such as a thematic break, belongs to it
+		starts[0].offset = 0
+	}
+
+	var blocks []block
+	for i, s := range starts {
+		end := len(source)
+		if i+1 < len(starts) {
+			end = starts[i+1].offset
+		}
+		raw := string(source[s.offset:end])
+		b := block{text: strings.TrimSpace(raw)}
+		if b.text == "" {
+			continue
+		}
+		b.start = s.offset + len(raw) - len(strings.TrimLeftFunc(raw, unicode.IsSpace))
+		b.end = b.start + len(b.text)
+		switch n := s.node.(type) {
+		case *ast.Heading:
+			b.kind = blockHeading
+		case *ast.FencedCodeBlock:
+			b.kind = blockCode
+			b.language = string(n.Language(source))
+		case *extast.Table:
+			b.kind = blockTable
+		case *ast.List:
+			b.kind = blockList
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				itemStart := nodeStart(item, source)
+				itemEnd := end
+				if next := item.NextSibling(); next != nil && nodeStart(next, source) > itemStart {
+					itemEnd = nodeStart(next, source)
+				}
+				if itemStart >= 0 && itemStart < itemEnd {
+					b.items = append(b.items, strings.TrimSpace(string(source[itemStart:itemEnd])))
+				}
+			}
+		}
+		if b.kind == blockCode && !fenceRegex.MatchString(b.text) {
+			// Text that ended up before the fence, such as a thematic break, is not part of the code
+			b.kind = blockText
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// nodeStart returns the offset of the line a node starts on, or -1 when it has no source position
+func nodeStart(n ast.Node, source []byte) int {
+	first := -1
+	consider := func(offset int) {
+		if first < 0 || offset < first {
+			first = offset
+		}
+	}
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if c.Type() == ast.TypeBlock && c.Lines().Len() > 0 {
+			consider(c.Lines().At(0).Start)
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			consider(c.Segment.Start)
+		case *ast.RawHTML:
+			if c.Segments.Len() > 0 {
+				consider(c.Segments.At(0).Start)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if code, ok := n.(*ast.FencedCodeBlock); ok {
+		// The fence line is not part of the code lines
+		switch {
+		case code.Info != nil:
+			first = code.Info.Segment.Start
+		case first >= 0:
+			first = lineStart(source, first) - 1
+		}
+	}
+	if first < 0 {
+		return -1
+	}
+	return lineStart(source, first)
+}
+
+// lineStart returns the offset of the start of the line containing offset
+func lineStart(source []byte, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	if offset > len(source) {
+		offset = len(source)
+	}
+	for offset > 0 && source[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
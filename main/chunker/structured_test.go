@@ -0,0 +1,207 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+)
+
+func TestStructuredSplitsAtHeadings(t *testing.T) {
+	markdown := "# Install\n\n" + strings.Repeat("Install the package. ", 10) +
+		"\n\n## Configure\n\n" + strings.Repeat("Set the options. ", 10) +
+		"\n\n## Run\n\n" + strings.Repeat("Start the server. ", 10)
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 300, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"# Install", "## Configure", "## Run"}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, chunk := range chunks {
+		if !strings.HasPrefix(chunk.Text, want[i]+"\n\n") {
+			t.Errorf("chunk %d starts with %q, want %q", i, firstLine(chunk.Text), want[i])
+		}
+	}
+}
+
+func TestStructuredPacksSmallSections(t *testing.T) {
+	markdown := "# A\n\nOne.\n\n## B\n\nTwo.\n\n## C\n\nThree."
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Text != markdown {
+		t.Errorf("got %+v, want one chunk with the whole page", chunks)
+	}
+}
+
+func TestStructuredKeepsCodeBlocksWhole(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 20) + "```"
+	markdown := "# Example\n\n" + strings.Repeat("Some text before the code. ", 8) + "\n\n" + code + "\n\nAfter the code."
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 500, Overlap: 50, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, chunk := range chunks {
+		if strings.Count(chunk.Text, "```")%2 != 0 {
+			t.Errorf("chunk has an unclosed fence:\n%s", chunk.Text)
+		}
+		if strings.Contains(chunk.Text, code) {
+			found = true
+			if !chunk.HasCode || !reflect.DeepEqual(chunk.CodeLanguages, []string{"go"}) {
+				t.Errorf("code chunk has HasCode %v and languages %q, want true and [go]", chunk.HasCode, chunk.CodeLanguages)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("code block was split: %+v", chunks)
+	}
+}
+
+func TestStructuredReopensOversizedCodeBlocks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("print(%d)", i))
+	}
+	markdown := "```python\n" + strings.Join(lines, "\n") + "\n```"
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 200, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the code block split", len(chunks))
+	}
+	var body []string
+	for _, chunk := range chunks {
+		if len([]rune(chunk.Text)) > 200 {
+			t.Errorf("chunk has %d characters, want at most 200", len([]rune(chunk.Text)))
+		}
+		if !strings.HasPrefix(chunk.Text, "```python\n") || !strings.HasSuffix(chunk.Text, "\n```") {
+			t.Errorf("chunk does not reopen and close the fence:\n%s", chunk.Text)
+		}
+		if !reflect.DeepEqual(chunk.CodeLanguages, []string{"python"}) {
+			t.Errorf("chunk languages = %q, want [python]", chunk.CodeLanguages)
+		}
+		body = append(body, strings.TrimSuffix(strings.TrimPrefix(chunk.Text, "```python\n"), "\n```"))
+	}
+	if got := strings.Join(body, "\n"); got != strings.Join(lines, "\n") {
+		t.Errorf("code lines were lost or reordered:\n%s", got)
+	}
+}
+
+func TestStructuredRepeatsTableHeader(t *testing.T) {
+	header := "| Name | Value |\n| --- | --- |"
+	var rows []string
+	for i := 0; i < 40; i++ {
+		rows = append(rows, fmt.Sprintf("| option%d | %d |", i, i))
+	}
+	markdown := header + "\n" + strings.Join(rows, "\n")
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 200, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the table split", len(chunks))
+	}
+	var got []string
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk.Text, header+"\n") {
+			t.Errorf("chunk does not start with the table header:\n%s", chunk.Text)
+		}
+		got = append(got, strings.TrimPrefix(chunk.Text, header+"\n"))
+	}
+	if strings.Join(got, "\n") != strings.Join(rows, "\n") {
+		t.Errorf("table rows were lost or reordered")
+	}
+}
+
+func TestStructuredKeepsListsWhole(t *testing.T) {
+	list := "- first item\n- second item\n- third item"
+	markdown := "## Steps\n\n" + strings.Repeat("Follow these steps. ", 4) + "\n\n" + list
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 100, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, chunk := range chunks {
+		found = found || strings.Contains(chunk.Text, list)
+	}
+	if !found {
+		t.Errorf("list was split: %+v", chunks)
+	}
+}
+
+func TestStructuredReopensOversizedCodeInListItems(t *testing.T) {
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("  echo %d", i))
+	}
+	markdown := "## Setup\n\n- Install the CLI:\n\n  ```sh\n" + strings.Join(lines, "\n") + "\n  ```\n- Log in"
+
+	chunks, err := NewStructured().Chunk(context.Background(), markdown, Options{ChunkSize: 300, Overlap: 10, Tokenizer: tokenizer.Characters{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var code []string
+	for _, chunk := range chunks {
+		if strings.Count(chunk.Text, "```")%2 != 0 {
+			t.Errorf("chunk has an unclosed fence:\n%s", chunk.Text)
+		}
+		if !strings.Contains(chunk.Text, "echo") {
+			continue
+		}
+		if !chunk.HasCode || !reflect.DeepEqual(chunk.CodeLanguages, []string{"sh"}) {
+			t.Errorf("code chunk has HasCode %v and languages %q, want true and [sh]:\n%s", chunk.HasCode, chunk.CodeLanguages, chunk.Text)
+		}
+		for _, line := range strings.Split(chunk.Text, "\n") {
+			if strings.Contains(line, "echo") {
+				code = append(code, line)
+			}
+		}
+	}
+	if strings.Join(code, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("code lines were lost or reordered")
+	}
+	if last := chunks[len(chunks)-1].Text; !strings.Contains(last, "- Log in") {
+		t.Errorf("last chunk = %q, want the next list item", last)
+	}
+}
+
+func TestStructuredEdgeCases(t *testing.T) {
+	structured := NewStructured()
+
+	chunks, err := structured.Chunk(context.Background(), " \n\t\n", Options{})
+	if err != nil || len(chunks) != 0 {
+		t.Errorf("blank markdown = %v, %v, want no chunks", chunks, err)
+	}
+
+	if _, err := structured.Chunk(context.Background(), "text", Options{ChunkSize: 100, Overlap: 200, Tokenizer: tokenizer.Characters{}}); err == nil {
+		t.Error("expected an error when the overlap is larger than the chunk size")
+	}
+
+	// A page that is only headings is still returned
+	chunks, err = structured.Chunk(context.Background(), "# Title\n\n## Section", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Text != "# Title\n\n## Section" {
+		t.Errorf("headings only = %+v, want one chunk", chunks)
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
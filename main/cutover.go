@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/itsmaleen/tech-doc-processor/handlers"
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// cutoverCommand is the command that switches the default embedding model instead of serving
+const cutoverCommand = "cutover-embeddings"
+
+// runEmbeddingCutover lists the embedding models and how many chunks each has embedded, and with
+// -model makes that model the default of queries. A model that has not embedded every chunk is
+// only made the default with -force, since queries would not find the chunks it is missing.
+func runEmbeddingCutover(ctx context.Context, out io.Writer, pgxConn *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet(cutoverCommand, flag.ContinueOnError)
+	flags.SetOutput(out)
+	model := flags.String("model", "", "the model to make the default of queries; without it the models are only listed")
+	force := flags.Bool("force", false, "make the model the default even though it has not embedded every chunk")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	models, err := handlers.EmbeddingModels(ctx, pgxConn)
+	if err != nil {
+		return fmt.Errorf("failed to list embedding models: %w", err)
+	}
+	printEmbeddingModels(out, models)
+	if *model == "" {
+		return nil
+	}
+
+	target, err := cutoverTarget(models, *model, *force)
+	if err != nil {
+		return err
+	}
+	if target.IsDefault {
+		fmt.Fprintf(out, "%s is already the default embedding model\n", target.Model)
+		return nil
+	}
+
+	if err := handlers.SetDefaultEmbeddingModel(ctx, pgxConn, target.Model); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "The default embedding model is now %s (was %s)\n", target.Model, defaultModelName(models))
+	return nil
+}
+
+// printEmbeddingModels lists models with how many chunks each has embedded, marking the default
+func printEmbeddingModels(out io.Writer, models []types.EmbeddingModel) {
+	for _, m := range models {
+		marker := " "
+		if m.IsDefault {
+			marker = "*"
+		}
+		fmt.Fprintf(out, "%s %s\t%d dimensions\t%d of %d chunks embedded\n", marker, m.Model, m.Dimensions, m.EmbeddedChunks, m.TotalChunks)
+	}
+}
+
+// cutoverTarget returns the model of models that model names, failing when it has no embeddings
+// or, without force, has not embedded every chunk
+func cutoverTarget(models []types.EmbeddingModel, model string, force bool) (types.EmbeddingModel, error) {
+	for _, m := range models {
+		if m.Model != model {
+			continue
+		}
+		if !m.IsDefault && m.EmbeddedChunks < m.TotalChunks && !force {
+			return m, fmt.Errorf("model %s has embedded %d of %d chunks, so queries would miss the rest; embed them first or pass -force", m.Model, m.EmbeddedChunks, m.TotalChunks)
+		}
+		return m, nil
+	}
+	return types.EmbeddingModel{}, fmt.Errorf("model %s has no embeddings; embed chunks with it first with POST /api/rag/embeddings?model=%s", model, model)
+}
+
+// defaultModelName returns the default model of models, or "none"
+func defaultModelName(models []types.EmbeddingModel) string {
+	for _, m := range models {
+		if m.IsDefault {
+			return m.Model
+		}
+	}
+	return "none"
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+var cutoverModels = []types.EmbeddingModel{
+	{Model: "old", Dimensions: 3072, IsDefault: true, EmbeddedChunks: 100, TotalChunks: 100},
+	{Model: "complete", Dimensions: 1024, EmbeddedChunks: 100, TotalChunks: 100},
+	{Model: "partial", Dimensions: 768, EmbeddedChunks: 40, TotalChunks: 100},
+}
+
+func TestCutoverTarget(t *testing.T) {
+	tests := []struct {
+		model   string
+		force   bool
+		wantErr string
+	}{
+		{"complete", false, ""},
+		{"old", false, ""},
+		{"partial", false, "embedded 40 of 100 chunks"},
+		{"partial", true, ""},
+		{"missing", true, "has no embeddings"},
+	}
+	for _, test := range tests {
+		target, err := cutoverTarget(cutoverModels, test.model, test.force)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("cutoverTarget(%q, %v) error = %v, want %q", test.model, test.force, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("cutoverTarget(%q, %v) unexpected error: %v", test.model, test.force, err)
+			continue
+		}
+		if target.Model != test.model {
+			t.Errorf("cutoverTarget(%q, %v) = %q", test.model, test.force, target.Model)
+		}
+	}
+}
+
+func TestPrintEmbeddingModels(t *testing.T) {
+	var out bytes.Buffer
+	printEmbeddingModels(&out, cutoverModels)
+	want := "* old\t3072 dimensions\t100 of 100 chunks embedded\n" +
+		"  complete\t1024 dimensions\t100 of 100 chunks embedded\n" +
+		"  partial\t768 dimensions\t40 of 100 chunks embedded\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestDefaultModelName(t *testing.T) {
+	if got := defaultModelName(cutoverModels); got != "old" {
+		t.Errorf("defaultModelName = %q, want old", got)
+	}
+	if got := defaultModelName(cutoverModels[1:]); got != "none" {
+		t.Errorf("defaultModelName without a default = %q, want none", got)
+	}
+}
+
+func TestRunEmbeddingCutoverRejectsUnknownFlags(t *testing.T) {
+	var out bytes.Buffer
+	// Flags are parsed before the database is used
+	if err := runEmbeddingCutover(context.Background(), &out, nil, []string{"-unknown"}); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+}
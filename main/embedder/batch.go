@@ -0,0 +1,56 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Batch is texts embedded in one request. Start is the index of its first text among all texts.
+type Batch struct {
+	Start      int
+	Texts      []string
+	Embeddings [][]float32
+}
+
+// EmbedBatches embeds texts in batches of e.BatchSize, with at most concurrency batches at once,
+// and calls save with each batch as soon as it is embedded so finished batches are kept when others
+// fail. A batch that cannot be embedded or saved does not stop the others, and the errors of every
+// failed batch are returned together.
+func EmbedBatches(ctx context.Context, e Embedder, texts []string, task Task, concurrency int, save func(ctx context.Context, batch Batch) error) error {
+	batchSize := max(e.BatchSize(), 1)
+
+	var mu sync.Mutex
+	var errs []error
+	group := errgroup.Group{}
+	group.SetLimit(max(concurrency, 1))
+	for start := 0; start < len(texts); start += batchSize {
+		batch := Batch{Start: start, Texts: texts[start:min(start+batchSize, len(texts))]}
+		group.Go(func() error {
+			// Batches not started yet are dropped once the context is done
+			if ctx.Err() != nil {
+				return nil
+			}
+			embeddings, err := e.EmbedBatch(ctx, batch.Texts, task)
+			if err == nil {
+				batch.Embeddings = embeddings
+				err = save(ctx, batch)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("batch of texts %d to %d: %w", batch.Start, batch.Start+len(batch.Texts)-1, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	group.Wait()
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
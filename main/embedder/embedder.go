@@ -0,0 +1,264 @@
+// Package embedder turns text into the vectors chunks are stored and matched with. Embeddings can
+// come from the Gemini API, an OpenAI-compatible endpoint or a deterministic local embedder.
+package embedder
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// KindGemini embeds text with the Gemini API
+	KindGemini = "gemini"
+	// KindOpenAI embeds text with an OpenAI-compatible /embeddings endpoint, such as OpenAI, Ollama
+	// or a llama.cpp server
+	KindOpenAI = "openai"
+	// KindLocal embeds text in-process by hashing its words, for tests and offline development
+	KindLocal = "local"
+)
+
+// DefaultDimensions is the size of the embeddings of the default Gemini model
+const DefaultDimensions = 3072
+
+// Default models of each kind
+const (
+	DefaultGeminiModel = "gemini-embedding-exp-03-07"
+	DefaultOpenAIModel = "text-embedding-3-large"
+	DefaultLocalModel  = "local-hash"
+)
+
+// Default endpoints of each kind
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+// Task is what an embedding is used for. Some models embed documents and the queries that search
+// them differently.
+type Task string
+
+const (
+	TaskDocument Task = "document"
+	TaskQuery    Task = "query"
+)
+
+// Embedder embeds text
+type Embedder interface {
+	// Embed returns the embedding of text, which has Dimensions values
+	Embed(ctx context.Context, text string, task Task) ([]float32, error)
+	// EmbedBatch returns the embeddings of texts in one request, in the order of texts. Batches
+	// can be at most BatchSize texts.
+	EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error)
+	BatchSize() int
+	// Model names the model the embeddings come from. Embeddings of different models cannot be
+	// compared.
+	Model() string
+	Dimensions() int
+}
+
+// APIError is a response of an embedding API that is not a success
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is how long the API asked to wait before calling it again, or 0
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("embedding request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Config selects and configures an embedder
+type Config struct {
+	Kind       string
+	Model      string
+	Dimensions int
+	APIKey     string
+	// BaseURL is the URL the API's paths are relative to
+	BaseURL string
+	// BatchSize caps the texts embedded in one request below the API's own limit
+	BatchSize int
+	// RateLimit is the most texts embedded a minute, or 0 for no limit
+	RateLimit int
+}
+
+// ConfigFromEnv reads the EMBEDDER and EMBEDDING_* settings. The Gemini embedder falls back to
+// GEMINI_API_KEY when EMBEDDING_API_KEY is not set.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := Config{
+		Kind:    getenv("EMBEDDER"),
+		Model:   getenv("EMBEDDING_MODEL"),
+		APIKey:  getenv("EMBEDDING_API_KEY"),
+		BaseURL: getenv("EMBEDDING_BASE_URL"),
+	}
+	if config.Kind == "" {
+		config.Kind = KindGemini
+	}
+	// OpenAI-compatible models embed at their own size unless EMBEDDING_DIMENSIONS asks for another
+	if config.Kind != KindOpenAI {
+		config.Dimensions = DefaultDimensions
+	}
+	for _, setting := range []struct {
+		name  string
+		value *int
+	}{
+		{"EMBEDDING_DIMENSIONS", &config.Dimensions},
+		{"EMBEDDING_BATCH_SIZE", &config.BatchSize},
+		{"EMBEDDING_RATE_LIMIT", &config.RateLimit},
+	} {
+		if value := getenv(setting.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return config, fmt.Errorf("%s must be a positive number", setting.name)
+			}
+			*setting.value = n
+		}
+	}
+
+	switch config.Kind {
+	case KindGemini:
+		if config.APIKey == "" {
+			config.APIKey = getenv("GEMINI_API_KEY")
+		}
+		if config.APIKey == "" {
+			return config, fmt.Errorf("EMBEDDING_API_KEY or GEMINI_API_KEY must be set")
+		}
+	case KindOpenAI:
+		// Local servers such as Ollama need no API key, but they need a URL
+		if config.APIKey == "" && config.BaseURL == "" {
+			return config, fmt.Errorf("EMBEDDING_API_KEY or EMBEDDING_BASE_URL must be set")
+		}
+	case KindLocal:
+	default:
+		return config, fmt.Errorf("EMBEDDER must be %s, %s or %s", KindGemini, KindOpenAI, KindLocal)
+	}
+	return config, nil
+}
+
+// ConfigsFromEnv reads the embedder of ConfigFromEnv, and the embedders of other models to embed
+// chunks with next to it from the same settings suffixed _2, _3 and so on, such as EMBEDDER_2
+// and EMBEDDING_MODEL_2
+func ConfigsFromEnv(getenv func(string) string) ([]Config, error) {
+	config, err := ConfigFromEnv(getenv)
+	if err != nil {
+		return nil, err
+	}
+	configs := []Config{config}
+	for n := 2; getenv(fmt.Sprintf("EMBEDDER_%d", n)) != ""; n++ {
+		suffix := fmt.Sprintf("_%d", n)
+		config, err := ConfigFromEnv(func(key string) string {
+			// GEMINI_API_KEY is shared by every Gemini embedder
+			if key == "GEMINI_API_KEY" {
+				return getenv(key)
+			}
+			return getenv(key + suffix)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("embedder %d: %w", n, err)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// New returns the embedder config selects, filling in the default model and endpoint of its kind.
+// Its calls share one rate limit and are retried when they are rate limited.
+func New(config Config) (Embedder, error) {
+	if config.Dimensions <= 0 && config.Kind != KindOpenAI {
+		config.Dimensions = DefaultDimensions
+	}
+	var e Embedder
+	switch config.Kind {
+	case KindGemini:
+		e = NewGemini(config.APIKey, cmp.Or(config.Model, DefaultGeminiModel), config.Dimensions, cmp.Or(config.BaseURL, DefaultGeminiBaseURL))
+	case KindOpenAI:
+		e = NewOpenAI(config.APIKey, cmp.Or(config.Model, DefaultOpenAIModel), config.Dimensions, cmp.Or(config.BaseURL, DefaultOpenAIBaseURL))
+	case KindLocal:
+		// Local embeddings cost nothing, so they are not limited
+		return NewLocal(cmp.Or(config.Model, DefaultLocalModel), config.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedder %q", config.Kind)
+	}
+	// A minute of texts may be sent at once
+	limiter := NewLimiter(config.RateLimit, cmp.Or(config.RateLimit, e.BatchSize()))
+	return NewLimited(e, limiter, config.BatchSize, DefaultMaxAttempts), nil
+}
+
+// postJSON posts request as JSON to url with headers and decodes the JSON response into response
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, request any, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: retryAfter(resp.Header, body)}
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
+// checkDimensions returns an error when an API returned an embedding of another size than asked
+// for, which could not be stored or compared
+func checkDimensions(embedding []float32, dimensions int, model string) error {
+	if len(embedding) != dimensions {
+		return fmt.Errorf("model %s returned %d dimensions, want %d", model, len(embedding), dimensions)
+	}
+	return nil
+}
+
+// retryAfter returns how long a response asks to wait before the API is called again, from its
+// Retry-After header, in seconds or as a date, or from the retryDelay of a Google API error
+func retryAfter(header http.Header, body []byte) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(0, time.Until(date))
+		}
+	}
+
+	var googleError struct {
+		Error struct {
+			Details []struct {
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &googleError) == nil {
+		for _, detail := range googleError.Error.Details {
+			if delay, err := time.ParseDuration(detail.RetryDelay); err == nil {
+				return delay
+			}
+		}
+	}
+	return 0
+}
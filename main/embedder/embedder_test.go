@@ -0,0 +1,222 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{"GEMINI_API_KEY": "gemini-key"}
+	getenv := func(key string) string { return env[key] }
+
+	config, err := ConfigFromEnv(getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Kind != KindGemini || config.APIKey != "gemini-key" || config.Dimensions != DefaultDimensions {
+		t.Errorf("default config = %+v, want Gemini with GEMINI_API_KEY and %d dimensions", config, DefaultDimensions)
+	}
+
+	env = map[string]string{"EMBEDDER": "openai", "EMBEDDING_BASE_URL": "http://localhost:11434/v1", "EMBEDDING_MODEL": "nomic-embed-text", "EMBEDDING_DIMENSIONS": "768"}
+	config, err = ConfigFromEnv(getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Model() != "nomic-embed-text" || e.Dimensions() != 768 {
+		t.Errorf("got model %s with %d dimensions, want nomic-embed-text with 768", e.Model(), e.Dimensions())
+	}
+
+	// OpenAI-compatible models embed at their own size unless dimensions are configured
+	env = map[string]string{"EMBEDDER": "openai", "EMBEDDING_API_KEY": "key", "EMBEDDING_MODEL": "text-embedding-ada-002"}
+	config, err = ConfigFromEnv(getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err = New(config); err != nil || e.Dimensions() != 0 {
+		t.Errorf("got %d dimensions and error %v, want the model's own size", e.Dimensions(), err)
+	}
+
+	for _, env = range []map[string]string{
+		{},
+		{"EMBEDDER": "openai"},
+		{"EMBEDDER": "local", "EMBEDDING_DIMENSIONS": "-1"},
+		{"EMBEDDER": "cohere"},
+	} {
+		if _, err := ConfigFromEnv(getenv); err == nil {
+			t.Errorf("config %v was accepted", env)
+		}
+	}
+}
+
+func TestGemini(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request geminiEmbedRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if r.URL.Path != "/models/text-embedding-004:embedContent" || r.Header.Get("x-goog-api-key") != "key" {
+			t.Errorf("request to %s with key %q", r.URL.Path, r.Header.Get("x-goog-api-key"))
+		}
+		if request.TaskType != "RETRIEVAL_QUERY" || request.OutputDimensionality == 0 || request.Content.Parts[0].Text != "hello" {
+			t.Errorf("request = %+v", request)
+		}
+		w.Write([]byte(`{"embedding": {"values": [0.1, 0.2, 0.3]}}`))
+	}))
+	defer server.Close()
+
+	embedding, err := NewGemini("key", "models/text-embedding-004", 3, server.URL).Embed(context.Background(), "hello", TaskQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedding) != 3 || embedding[2] != 0.3 {
+		t.Errorf("embedding = %v", embedding)
+	}
+
+	// Embeddings of another size than configured are rejected
+	if _, err := NewGemini("key", "text-embedding-004", 4, server.URL).Embed(context.Background(), "hello", TaskQuery); err == nil {
+		t.Errorf("an embedding of 3 dimensions was accepted as 4")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("request to %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, `{"error": "rate limited"}`, http.StatusTooManyRequests)
+			return
+		}
+		var request openAIEmbedRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Model != "text-embedding-3-small" || request.Dimensions != 2 || request.Input[0] != "hello" {
+			t.Errorf("request = %+v", request)
+		}
+		w.Write([]byte(`{"data": [{"index": 0, "embedding": [0.5, -0.5]}]}`))
+	}))
+	defer server.Close()
+
+	embedding, err := NewOpenAI("key", "text-embedding-3-small", 2, server.URL+"/v1/").Embed(context.Background(), "hello", TaskDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedding) != 2 || embedding[1] != -0.5 {
+		t.Errorf("embedding = %v", embedding)
+	}
+
+	// Errors of the API keep their status
+	_, err = NewOpenAI("", "text-embedding-3-small", 2, server.URL+"/v1").Embed(context.Background(), "hello", TaskDocument)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("error = %v, want an APIError with status 429", err)
+	}
+}
+
+func TestOpenAIWithoutDimensions(t *testing.T) {
+	embedding := `[0.1, 0.2, 0.3]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		json.NewDecoder(r.Body).Decode(&request)
+		if _, ok := request["dimensions"]; ok {
+			t.Errorf("request has dimensions: %v", request)
+		}
+		w.Write([]byte(`{"data": [{"index": 0, "embedding": ` + embedding + `}]}`))
+	}))
+	defer server.Close()
+
+	e := NewOpenAI("", "nomic-embed-text", 0, server.URL)
+	if e.Dimensions() != 0 {
+		t.Errorf("dimensions before the first embedding = %d, want 0", e.Dimensions())
+	}
+	if _, err := e.Embed(context.Background(), "hello", TaskDocument); err != nil {
+		t.Fatal(err)
+	}
+	if e.Dimensions() != 3 {
+		t.Errorf("dimensions = %d, want 3 from the first embedding", e.Dimensions())
+	}
+
+	// Later embeddings must have the size of the first
+	embedding = `[0.1, 0.2, 0.3, 0.4]`
+	if _, err := e.Embed(context.Background(), "hello", TaskDocument); err == nil {
+		t.Errorf("an embedding of 4 dimensions was accepted after one of 3")
+	}
+}
+
+func TestGeminiBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch struct {
+			Requests []geminiEmbedRequest `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&batch)
+		if r.URL.Path != "/models/text-embedding-004:batchEmbedContents" || len(batch.Requests) != 2 || batch.Requests[1].TaskType != "RETRIEVAL_DOCUMENT" {
+			t.Errorf("request to %s = %+v", r.URL.Path, batch)
+		}
+		w.Write([]byte(`{"embeddings": [{"values": [1, 0]}, {"values": [0, 1]}]}`))
+	}))
+	defer server.Close()
+
+	embeddings, err := NewGemini("key", "text-embedding-004", 2, server.URL).EmbedBatch(context.Background(), []string{"a", "b"}, TaskDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings) != 2 || embeddings[1][1] != 1 {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+}
+
+func TestConfigsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"GEMINI_API_KEY":         "gemini-key",
+		"EMBEDDER_2":             "gemini",
+		"EMBEDDING_MODEL_2":      "text-embedding-004",
+		"EMBEDDING_DIMENSIONS_2": "768",
+		"EMBEDDER_3":             "local",
+		// Numbers after a gap are not read
+		"EMBEDDER_5": "local",
+	}
+	configs, err := ConfigsFromEnv(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 3 {
+		t.Fatalf("got %d configs, want 3", len(configs))
+	}
+	if second := configs[1]; second.Kind != KindGemini || second.Model != "text-embedding-004" || second.Dimensions != 768 || second.APIKey != "gemini-key" {
+		t.Errorf("second config = %+v, want text-embedding-004 with 768 dimensions and GEMINI_API_KEY", second)
+	}
+
+	env["EMBEDDING_DIMENSIONS_3"] = "many"
+	if _, err := ConfigsFromEnv(func(key string) string { return env[key] }); err == nil {
+		t.Errorf("an invalid EMBEDDING_DIMENSIONS_3 was accepted")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	first, second := NewLocal("first", 8), NewLocal("second", 8)
+	registry, err := NewRegistry(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.Primary() != first || len(registry.All()) != 2 {
+		t.Errorf("primary = %s of %d, want first of 2", registry.Primary().Model(), len(registry.All()))
+	}
+	if e, ok := registry.Get("second"); !ok || e != second {
+		t.Errorf("Get(second) = %v, %v", e, ok)
+	}
+	if _, ok := registry.Get("third"); ok {
+		t.Errorf("Get(third) found an embedder")
+	}
+
+	if _, err := NewRegistry(first, NewLocal("first", 16)); err == nil {
+		t.Errorf("a model configured twice was accepted")
+	}
+	if _, err := NewRegistry(); err == nil {
+		t.Errorf("an empty registry was accepted")
+	}
+}
@@ -0,0 +1,147 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// geminiBatchSize is the most requests batchEmbedContents takes
+const geminiBatchSize = 100
+
+// Gemini embeds text with the embedContent and batchEmbedContents methods of the Gemini API
+type Gemini struct {
+	apiKey     string
+	model      string
+	dimensions int
+	baseURL    string
+	client     *http.Client
+}
+
+// NewGemini returns an embedder that calls the Gemini API at baseURL. Models may be given with or
+// without their "models/" prefix.
+func NewGemini(apiKey string, model string, dimensions int, baseURL string) *Gemini {
+	return &Gemini{
+		apiKey:     apiKey,
+		model:      strings.TrimPrefix(model, "models/"),
+		dimensions: dimensions,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		client:     &http.Client{},
+	}
+}
+
+func (g *Gemini) Model() string {
+	return g.model
+}
+
+func (g *Gemini) Dimensions() int {
+	return g.dimensions
+}
+
+func (g *Gemini) BatchSize() int {
+	return geminiBatchSize
+}
+
+// geminiContent is the content of a Gemini request
+type geminiContent struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiEmbedRequest is the request of the embedContent method
+type geminiEmbedRequest struct {
+	Model                string        `json:"model"`
+	Content              geminiContent `json:"content"`
+	TaskType             string        `json:"taskType"`
+	OutputDimensionality int           `json:"outputDimensionality"`
+}
+
+// geminiEmbedding is an embedding returned by the Gemini API
+type geminiEmbedding struct {
+	Values []float32 `json:"values"`
+}
+
+// geminiTaskTypes are the task types the Gemini API embeds documents and queries with
+var geminiTaskTypes = map[Task]string{
+	TaskDocument: "RETRIEVAL_DOCUMENT",
+	TaskQuery:    "RETRIEVAL_QUERY",
+}
+
+// request returns the request that embeds text for task
+func (g *Gemini) request(text string, task Task) (geminiEmbedRequest, error) {
+	if g.apiKey == "" {
+		return geminiEmbedRequest{}, fmt.Errorf("missing gemini api key")
+	}
+	taskType, ok := geminiTaskTypes[task]
+	if !ok {
+		return geminiEmbedRequest{}, fmt.Errorf("invalid task: %s", task)
+	}
+	return geminiEmbedRequest{
+		Model:                "models/" + g.model,
+		Content:              geminiContent{Parts: []geminiPart{{Text: text}}},
+		TaskType:             taskType,
+		OutputDimensionality: g.dimensions,
+	}, nil
+}
+
+// post calls a method of the model. The key is sent in a header so it does not end up in logged
+// URLs.
+func (g *Gemini) post(ctx context.Context, method string, request any, response any) error {
+	url := fmt.Sprintf("%s/models/%s:%s", g.baseURL, g.model, method)
+	return postJSON(ctx, g.client, url, map[string]string{"x-goog-api-key": g.apiKey}, request, response)
+}
+
+func (g *Gemini) Embed(ctx context.Context, text string, task Task) ([]float32, error) {
+	request, err := g.request(text, task)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Embedding geminiEmbedding `json:"embedding"`
+	}
+	if err := g.post(ctx, "embedContent", request, &response); err != nil {
+		return nil, err
+	}
+	if err := checkDimensions(response.Embedding.Values, g.dimensions, g.model); err != nil {
+		return nil, err
+	}
+	return response.Embedding.Values, nil
+}
+
+func (g *Gemini) EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error) {
+	if len(texts) > geminiBatchSize {
+		return nil, fmt.Errorf("batch of %d texts is larger than %d", len(texts), geminiBatchSize)
+	}
+	var batch struct {
+		Requests []geminiEmbedRequest `json:"requests"`
+	}
+	for _, text := range texts {
+		request, err := g.request(text, task)
+		if err != nil {
+			return nil, err
+		}
+		batch.Requests = append(batch.Requests, request)
+	}
+
+	var response struct {
+		Embeddings []geminiEmbedding `json:"embeddings"`
+	}
+	if err := g.post(ctx, "batchEmbedContents", batch, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("model %s returned %d embeddings for %d texts", g.model, len(response.Embeddings), len(texts))
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, embedding := range response.Embeddings {
+		if err := checkDimensions(embedding.Values, g.dimensions, g.model); err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding.Values
+	}
+	return embeddings, nil
+}
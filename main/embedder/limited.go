@@ -0,0 +1,108 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Backoff of calls that failed without saying when to retry
+const (
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+)
+
+// DefaultMaxAttempts is how many times a call is made before its error is returned
+const DefaultMaxAttempts = 5
+
+// Limited embeds with another embedder within a shared rate limit, retrying calls that were rate
+// limited or failed on the server. Retries wait as long as the API asked to, or back off
+// exponentially when it did not say.
+type Limited struct {
+	embedder    Embedder
+	limiter     *Limiter
+	batchSize   int
+	maxAttempts int
+}
+
+// NewLimited returns an embedder that calls e within limiter. Batches are at most batchSize texts,
+// or as many as e embeds at once when batchSize is 0.
+func NewLimited(e Embedder, limiter *Limiter, batchSize int, maxAttempts int) *Limited {
+	if batchSize <= 0 || batchSize > e.BatchSize() {
+		batchSize = e.BatchSize()
+	}
+	return &Limited{embedder: e, limiter: limiter, batchSize: batchSize, maxAttempts: max(maxAttempts, 1)}
+}
+
+func (l *Limited) Model() string {
+	return l.embedder.Model()
+}
+
+func (l *Limited) Dimensions() int {
+	return l.embedder.Dimensions()
+}
+
+func (l *Limited) BatchSize() int {
+	return l.batchSize
+}
+
+func (l *Limited) Embed(ctx context.Context, text string, task Task) ([]float32, error) {
+	var embedding []float32
+	err := l.call(ctx, 1, func() (err error) {
+		embedding, err = l.embedder.Embed(ctx, text, task)
+		return err
+	})
+	return embedding, err
+}
+
+func (l *Limited) EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error) {
+	var embeddings [][]float32
+	err := l.call(ctx, len(texts), func() (err error) {
+		embeddings, err = l.embedder.EmbedBatch(ctx, texts, task)
+		return err
+	})
+	return embeddings, err
+}
+
+// call makes a call that embeds n texts, waiting for the limiter before each attempt
+func (l *Limited) call(ctx context.Context, n int, embed func() error) error {
+	for attempt := 1; ; attempt++ {
+		if err := l.limiter.Wait(ctx, n); err != nil {
+			return err
+		}
+		err := embed()
+		if err == nil || attempt == l.maxAttempts {
+			return err
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !retryable(apiErr.StatusCode) {
+			return err
+		}
+		delay := apiErr.RetryAfter
+		if delay <= 0 {
+			delay = backoff(attempt)
+		}
+		// Being rate limited holds back every caller, not just this one
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			l.limiter.Pause(delay)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// retryable reports whether a call that failed with status may succeed when made again
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// backoff returns the wait before retry attempt, doubling from initialBackoff up to maxBackoff
+// with jitter so callers that failed together do not retry together
+func backoff(attempt int) time.Duration {
+	delay := min(maxBackoff, initialBackoff<<min(attempt-1, 6))
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,126 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyEmbedder fails the calls whose number is in failures with their error
+type flakyEmbedder struct {
+	*Local
+	mu       sync.Mutex
+	calls    int
+	failures map[int]error
+}
+
+func (f *flakyEmbedder) EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error) {
+	f.mu.Lock()
+	f.calls++
+	err := f.failures[f.calls]
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return f.Local.EmbedBatch(ctx, texts, task)
+}
+
+func TestLimitedRetries(t *testing.T) {
+	flaky := &flakyEmbedder{Local: NewLocal(DefaultLocalModel, 8), failures: map[int]error{
+		1: &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 100 * time.Millisecond},
+		2: &APIError{StatusCode: http.StatusServiceUnavailable},
+		4: &APIError{StatusCode: http.StatusBadRequest},
+	}}
+	limiter := NewLimiter(0, 1)
+	limited := NewLimited(flaky, limiter, 0, 3)
+
+	start := time.Now()
+	if _, err := limited.EmbedBatch(context.Background(), []string{"a", "b"}, TaskDocument); err != nil {
+		t.Fatal(err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("made %d calls, want 3", flaky.calls)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("retried after %v, before the 100ms the API asked for", elapsed)
+	}
+
+	// Requests the API rejects are not retried
+	_, err := limited.EmbedBatch(context.Background(), []string{"a"}, TaskDocument)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || flaky.calls != 4 {
+		t.Errorf("got %v after %d calls, want the 400 after 4", err, flaky.calls)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// Ten texts a second, two at once
+	limiter := NewLimiter(600, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	limiter.Wait(ctx, 2)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("a full bucket waited %v", elapsed)
+	}
+	limiter.Wait(ctx, 2)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("an empty bucket gave two tokens after %v, want 200ms", elapsed)
+	}
+
+	// A pause holds back every caller
+	limiter.Pause(200 * time.Millisecond)
+	start = time.Now()
+	limiter.Wait(ctx, 1)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("a paused limiter gave a token after %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	limiter.Pause(time.Minute)
+	if err := limiter.Wait(cancelled, 1); err == nil {
+		t.Errorf("Wait with a cancelled context returned no error")
+	}
+}
+
+func TestEmbedBatches(t *testing.T) {
+	// The second batch fails, and the others are still saved
+	flaky := &flakyEmbedder{Local: NewLocal(DefaultLocalModel, 8), failures: map[int]error{2: errors.New("boom")}}
+	limited := NewLimited(flaky, nil, 2, 1)
+	texts := []string{"a", "b", "c", "d", "e"}
+
+	var mu sync.Mutex
+	var saved []string
+	err := EmbedBatches(context.Background(), limited, texts, TaskDocument, 1, func(ctx context.Context, batch Batch) error {
+		if len(batch.Embeddings) != len(batch.Texts) || batch.Texts[0] != texts[batch.Start] {
+			t.Errorf("batch %+v does not match its texts", batch)
+		}
+		mu.Lock()
+		saved = append(saved, batch.Texts...)
+		mu.Unlock()
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("error = %v, want the failed batch's", err)
+	}
+	if strings.Join(saved, "") != "abe" {
+		t.Errorf("saved %v, want every batch but c and d", saved)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	if got := retryAfter(header, nil); got != 7*time.Second {
+		t.Errorf("Retry-After: 7 gave %v", got)
+	}
+	body := `{"error": {"code": 429, "details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "37s"}]}}`
+	if got := retryAfter(http.Header{}, []byte(body)); got != 37*time.Second {
+		t.Errorf("retryDelay 37s gave %v", got)
+	}
+}
@@ -0,0 +1,88 @@
+package embedder
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by everything that calls an embedding API. Each embedded text
+// takes a token, and the bucket refills at the API's rate limit. When the API answers that it is
+// rate limited, every caller pauses until it asked to be called again.
+type Limiter struct {
+	mu sync.Mutex
+	// rate is the tokens added per second, and burst the most the bucket holds
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// pausedUntil is when the API may be called again after it was rate limited
+	pausedUntil time.Time
+}
+
+// NewLimiter returns a limiter of perMinute texts a minute, of which burst may be embedded at once.
+// A limiter of 0 texts a minute only pauses when the API is rate limited.
+func NewLimiter(perMinute int, burst int) *Limiter {
+	burst = max(burst, 1)
+	return &Limiter{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n tokens and returns how long to wait before using them. Tokens are taken at
+// once, even when the bucket goes into debt, so callers are served in the order they came.
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	if l.rate > 0 {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		// A batch larger than the bucket waits for a full bucket rather than forever
+		l.tokens -= min(float64(n), l.burst)
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+	return max(wait, l.pausedUntil.Sub(now))
+}
+
+// Wait blocks until n texts may be embedded or ctx is done
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	return sleep(ctx, l.reserve(n))
+}
+
+// Pause stops every caller from calling the API for d
+func (l *Limiter) Pause(d time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
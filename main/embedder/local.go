@@ -0,0 +1,82 @@
+package embedder
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Local embeds text in-process by hashing its words and word pairs into the dimensions of the
+// embedding. The same text always gets the same embedding, and texts that share words get similar
+// ones, which is enough to test retrieval without calling an API.
+type Local struct {
+	model      string
+	dimensions int
+}
+
+// NewLocal returns a local embedder with embeddings of dimensions values
+func NewLocal(model string, dimensions int) *Local {
+	return &Local{model: model, dimensions: dimensions}
+}
+
+func (l *Local) Model() string {
+	return l.model
+}
+
+func (l *Local) Dimensions() int {
+	return l.dimensions
+}
+
+// BatchSize keeps batches of local embeddings as small as those of the APIs, so they are saved as
+// often
+func (l *Local) BatchSize() int {
+	return geminiBatchSize
+}
+
+func (l *Local) EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = l.Embed(ctx, text, task)
+	}
+	return embeddings, nil
+}
+
+// Embed embeds text. Documents and queries are embedded the same way.
+func (l *Local) Embed(ctx context.Context, text string, task Task) ([]float32, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	features := make([]string, 0, 2*len(words))
+	features = append(features, words...)
+	for i := 1; i < len(words); i++ {
+		features = append(features, words[i-1]+" "+words[i])
+	}
+
+	embedding := make([]float32, l.dimensions)
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The hash picks a dimension and whether the feature adds to it or takes from it
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		embedding[sum%uint64(l.dimensions)] += sign
+	}
+
+	// Embeddings are normalized so cosine similarity and inner product agree
+	var norm float64
+	for _, value := range embedding {
+		norm += float64(value) * float64(value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range embedding {
+			embedding[i] *= scale
+		}
+	}
+	return embedding, nil
+}
@@ -0,0 +1,44 @@
+package embedder
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestLocal(t *testing.T) {
+	local := NewLocal(DefaultLocalModel, 256)
+	embed := func(text string) []float32 {
+		embedding, err := local.Embed(context.Background(), text, TaskDocument)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return embedding
+	}
+
+	doc := embed("Configure the retry policy of the HTTP client")
+	if len(doc) != 256 {
+		t.Fatalf("got %d dimensions, want 256", len(doc))
+	}
+	if !slices.Equal(doc, embed("Configure the retry policy of the HTTP client")) {
+		t.Errorf("the same text got different embeddings")
+	}
+	if norm := math.Sqrt(cosine(doc, doc)); math.Abs(norm-1) > 1e-6 {
+		t.Errorf("embedding has norm %f, want 1", norm)
+	}
+
+	related := cosine(doc, embed("How do I configure retries for the HTTP client?"))
+	unrelated := cosine(doc, embed("Webhooks are signed with a shared secret"))
+	if related <= unrelated {
+		t.Errorf("related text has similarity %f, unrelated text %f", related, unrelated)
+	}
+}
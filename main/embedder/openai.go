@@ -0,0 +1,119 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// openAIBatchSize is the most texts sent in one request. OpenAI takes up to 2048 inputs, but only
+// 300,000 tokens in all.
+const openAIBatchSize = 512
+
+// OpenAI embeds text with an OpenAI-compatible /embeddings endpoint. The same API is served by
+// OpenAI and by local servers such as Ollama (at http://localhost:11434/v1) and llama.cpp.
+type OpenAI struct {
+	apiKey string
+	model  string
+	// requestedDimensions is sent as the dimensions parameter when it is set, and dimensions is the
+	// size embeddings must have, learned from the first response when none was requested
+	requestedDimensions int
+	dimensions          atomic.Int64
+	baseURL             string
+	client              *http.Client
+}
+
+// NewOpenAI returns an embedder that calls the /embeddings endpoint under baseURL. The API key is
+// left out of requests when it is empty. With dimensions 0 the model embeds at its own size, as
+// models such as text-embedding-ada-002 and most local ones do not take the parameter.
+func NewOpenAI(apiKey string, model string, dimensions int, baseURL string) *OpenAI {
+	o := &OpenAI{
+		apiKey:              apiKey,
+		model:               model,
+		requestedDimensions: dimensions,
+		baseURL:             strings.TrimSuffix(baseURL, "/"),
+		client:              &http.Client{},
+	}
+	o.dimensions.Store(int64(dimensions))
+	return o
+}
+
+func (o *OpenAI) Model() string {
+	return o.model
+}
+
+// Dimensions returns the size of the embeddings, which is 0 until the first embedding when the
+// model embeds at its own size
+func (o *OpenAI) Dimensions() int {
+	return int(o.dimensions.Load())
+}
+
+func (o *OpenAI) BatchSize() int {
+	return openAIBatchSize
+}
+
+// openAIEmbedRequest is the request of the /embeddings endpoint
+type openAIEmbedRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// openAIEmbedResponse is the response of the /embeddings endpoint
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed embeds text. OpenAI-compatible APIs embed documents and queries the same way, so task is
+// not sent.
+func (o *OpenAI) Embed(ctx context.Context, text string, task Task) ([]float32, error) {
+	embeddings, err := o.EmbedBatch(ctx, []string{text}, task)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (o *OpenAI) EmbedBatch(ctx context.Context, texts []string, task Task) ([][]float32, error) {
+	if len(texts) > openAIBatchSize {
+		return nil, fmt.Errorf("batch of %d texts is larger than %d", len(texts), openAIBatchSize)
+	}
+	request := openAIEmbedRequest{
+		Model:          o.model,
+		Input:          texts,
+		Dimensions:     o.requestedDimensions,
+		EncodingFormat: "float",
+	}
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	var response openAIEmbedResponse
+	if err := postJSON(ctx, o.client, o.baseURL+"/embeddings", headers, request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("model %s returned %d embeddings for %d texts", o.model, len(response.Data), len(texts))
+	}
+	// Embeddings are returned with the index of their input, which need not be their order
+	embeddings := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("model %s returned an embedding for input %d of %d", o.model, data.Index, len(texts))
+		}
+		// The first embedding sets the size of a model that embeds at its own size
+		o.dimensions.CompareAndSwap(0, int64(len(data.Embedding)))
+		if err := checkDimensions(data.Embedding, o.Dimensions(), o.model); err != nil {
+			return nil, err
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,41 @@
+package embedder
+
+import "fmt"
+
+// Registry holds the configured embedders by the model they embed with. The first one is the
+// default of new deployments; which model queries use is chosen with the embedding_models table.
+type Registry struct {
+	embedders []Embedder
+	byModel   map[string]Embedder
+}
+
+// NewRegistry returns a registry of embedders, which must each have another model
+func NewRegistry(embedders ...Embedder) (*Registry, error) {
+	if len(embedders) == 0 {
+		return nil, fmt.Errorf("no embedders configured")
+	}
+	r := &Registry{embedders: embedders, byModel: make(map[string]Embedder)}
+	for _, e := range embedders {
+		if _, ok := r.byModel[e.Model()]; ok {
+			return nil, fmt.Errorf("model %s is configured twice", e.Model())
+		}
+		r.byModel[e.Model()] = e
+	}
+	return r, nil
+}
+
+// Get returns the embedder of model
+func (r *Registry) Get(model string) (Embedder, bool) {
+	e, ok := r.byModel[model]
+	return e, ok
+}
+
+// All returns every embedder, in the order they were configured
+func (r *Registry) All() []Embedder {
+	return r.embedders
+}
+
+// Primary returns the first embedder configured
+func (r *Registry) Primary() Embedder {
+	return r.embedders[0]
+}
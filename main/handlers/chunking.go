@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+
+	"github.com/itsmaleen/tech-doc-processor/helpers"
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// pageToChunk is a page whose markdown is chunked
+type pageToChunk struct {
+	id           int
+	markdownPath string
+	url          string
+	title        string
+	language     *string
+	docsVersion  *string
+	sourceID     int
+	markdown     string
+}
+
+// loadPagesToChunk returns the pages that have not been removed and match condition, a SQL
+// condition on pages and urls whose parameters are args. Pages come in the order they were
+// scraped, so of two near-duplicate pages the same one is always kept.
+func loadPagesToChunk(ctx context.Context, pgxConn *pgxpool.Pool, condition string, args ...any) ([]pageToChunk, error) {
+	rows, err := pgxConn.Query(ctx, "SELECT pages.id, markdown_content, url, pages.language, pages.docs_version, COALESCE(urls.source_id, 0) FROM pages JOIN urls ON pages.url_id = urls.id WHERE removed_at IS NULL AND "+condition+" ORDER BY pages.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pages []pageToChunk
+	for rows.Next() {
+		var page pageToChunk
+		if err := rows.Scan(&page.id, &page.markdownPath, &page.url, &page.language, &page.docsVersion, &page.sourceID); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, rows.Err()
+}
+
+// readPagesMarkdown reads the markdown of pages from storage and strips its front matter and
+// boilerplate blocks. Pages whose markdown cannot be read are logged and left out.
+func readPagesMarkdown(logger *log.Logger, supabaseURL string, supabaseStorageBucket string, pages []pageToChunk, boilerplate map[int]map[string]bool) []pageToChunk {
+	read := make([]bool, len(pages))
+	group := errgroup.Group{}
+	group.SetLimit(storageReadConcurrency)
+	for i := range pages {
+		group.Go(func() error {
+			page := &pages[i]
+			storedMarkdown, err := helpers.GetFileContentFromStorage(logger, supabaseURL, supabaseStorageBucket, page.markdownPath)
+			if err != nil {
+				logger.Printf("Failed to read markdown content for %s: %v", page.url, err)
+				return nil
+			}
+
+			// The front matter is metadata, so only the body is chunked
+			frontMatter, markdownContent, err := helpers.ParseFrontMatter(storedMarkdown)
+			if err != nil {
+				logger.Printf("Ignoring invalid front matter for %s: %v", page.url, err)
+			}
+			if frontMatter != nil {
+				page.title = frontMatter.Title
+				if frontMatter.SourceURL != "" {
+					page.url = frontMatter.SourceURL
+				}
+			}
+			page.markdown = helpers.RemoveBoilerplateBlocks(markdownContent, boilerplate[page.sourceID])
+			read[i] = true
+			return nil
+		})
+	}
+	group.Wait()
+
+	loaded := make([]pageToChunk, 0, len(pages))
+	for i, page := range pages {
+		if read[i] {
+			loaded = append(loaded, page)
+		}
+	}
+	return loaded
+}
+
+// sourceStrategy returns the chunker and chunking strategy of a source. Pages without a source, and
+// sources whose settings cannot be read, are chunked with the configured chunker and default sizes.
+func sourceStrategy(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, markdownChunker chunker.Chunker, sourceID int) (chunker.Chunker, chunker.Strategy) {
+	// Chunks are sized in tokens so they stay within the embedding model's input limit
+	strategy := chunker.Strategy{
+		Name: chunker.StrategyOf(markdownChunker),
+		Options: chunker.Options{
+			ChunkSize: chunker.DefaultChunkSize,
+			Overlap:   chunker.DefaultOverlap,
+			Tokenizer: tokenizer.Approximate{},
+		},
+		SectionSize: chunker.DefaultSectionSize,
+	}
+	if sourceID == 0 {
+		return markdownChunker, strategy
+	}
+
+	source, err := getDocumentationSource(ctx, pgxConn, sourceID)
+	if err != nil {
+		logger.Printf("Chunking source %d with the default strategy: %v", sourceID, err)
+		return markdownChunker, strategy
+	}
+	sourceChunker, err := chunker.ForStrategy(source.ChunkStrategy, markdownChunker)
+	if err != nil {
+		logger.Printf("Chunking source %d with the default strategy: %v", sourceID, err)
+		return markdownChunker, strategy
+	}
+	strategy.Name = chunker.StrategyOf(sourceChunker)
+	strategy.Options.ChunkSize = source.ChunkSize
+	strategy.Options.Overlap = source.ChunkOverlap
+	return sourceChunker, strategy
+}
+
+// chunkPages chunks pages with the strategy of their source and writes their sections and chunks,
+// returning the ids of the pages that were chunked and the number of chunks written. Pages that
+// cannot be read or chunked are logged and left out so a later run retries them. Chunks with too
+// little useful text, or that nearly repeat another chunk of their source, are recorded in
+// chunk_skips instead of written. Pending chunks are not searched, so a source can be chunked
+// again while its current chunks answer queries.
+func chunkPages(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, markdownChunker chunker.Chunker, supabaseURL string, supabaseStorageBucket string, pages []pageToChunk, pending bool) ([]int, int, error) {
+	// Boilerplate fingerprints of each source, and the sources in the order their pages come
+	boilerplate := make(map[int]map[string]bool)
+	var sourceIDs []int
+	for _, page := range pages {
+		if _, ok := boilerplate[page.sourceID]; ok {
+			continue
+		}
+		sourceIDs = append(sourceIDs, page.sourceID)
+		fingerprints, err := loadBoilerplateFingerprints(ctx, pgxConn, page.sourceID)
+		if err != nil {
+			logger.Printf("Failed to load boilerplate blocks for source %d: %v", page.sourceID, err)
+		}
+		boilerplate[page.sourceID] = fingerprints
+	}
+
+	pages = readPagesMarkdown(logger, supabaseURL, supabaseStorageBucket, pages, boilerplate)
+	pagesBySource := make(map[int][]pageToChunk)
+	for _, page := range pages {
+		pagesBySource[page.sourceID] = append(pagesBySource[page.sourceID], page)
+	}
+
+	// Chunks are embedded and matched against queries, and the sections they are grouped into are
+	// what answers are generated from
+	var sectionsToWrite []Chunk
+	var chunksToWrite []Chunk
+	var skipsToWrite []chunkSkip
+	var pageIDs []int
+	for _, sourceID := range sourceIDs {
+		sourcePages := pagesBySource[sourceID]
+		sourceChunker, strategy := sourceStrategy(ctx, logger, pgxConn, markdownChunker, sourceID)
+		version := strategy.Version()
+
+		chunkerPages := make([]chunker.Page, 0, len(sourcePages))
+		for _, page := range sourcePages {
+			chunkerPages = append(chunkerPages, chunker.Page{ID: page.id, Markdown: page.markdown})
+		}
+		results := chunker.ChunkPages(ctx, sourceChunker, chunkerPages, strategy.Options, chunker.BatchOptions{})
+
+		// Chunks that repeat the stored chunks of the source's version and language, or earlier chunks
+		// of this run, are skipped
+		duplicates := make(duplicateIndexes)
+		if sourceID != 0 {
+			sourcePageIDs := make([]int, 0, len(sourcePages))
+			for _, page := range sourcePages {
+				sourcePageIDs = append(sourcePageIDs, page.id)
+			}
+			if err := loadSourceSimHashes(ctx, pgxConn, sourceID, sourcePageIDs, duplicates); err != nil {
+				logger.Printf("Failed to load the chunk hashes of source %d: %v", sourceID, err)
+			}
+		}
+
+		for i, result := range results {
+			page := sourcePages[i]
+			if result.Err != nil {
+				logger.Printf("Failed to chunk markdown for %s: %v", page.url, result.Err)
+				continue
+			}
+
+			kept, skipped := 0, 0
+			sections := chunker.Sections(page.markdown, result.Chunks, strategy.SectionSize, strategy.Options.Tokenizer)
+			for i, section := range sections {
+				var sectionChunks []Chunk
+				for _, j := range section.Chunks {
+					chunk := newPageChunk(page, result.Chunks[j], j, version)
+					if skip, ok := checkChunkQuality(&chunk, duplicates.of(page.language, page.docsVersion)); !ok {
+						skipsToWrite = append(skipsToWrite, skip)
+						skipped++
+						continue
+					}
+					sectionChunks = append(sectionChunks, chunk)
+				}
+				// Sections are only retrieved through their chunks
+				if len(sectionChunks) == 0 {
+					continue
+				}
+
+				sectionsToWrite = append(sectionsToWrite, newPageChunk(page, section.Chunk, i, version))
+				for _, chunk := range sectionChunks {
+					chunk.section = len(sectionsToWrite) - 1
+					chunksToWrite = append(chunksToWrite, chunk)
+				}
+				kept += len(sectionChunks)
+			}
+
+			pageIDs = append(pageIDs, page.id)
+
+			logger.Printf("Successfully chunked markdown: %s originally %d bytes into %d chunks in %d sections with %s, skipping %d chunks\n\n", page.url, len(page.markdown), kept, len(sections), version, skipped)
+		}
+	}
+
+	if err := writeChunkSkips(ctx, pgxConn, pageIDs, skipsToWrite, pending); err != nil {
+		return nil, 0, err
+	}
+
+	if err := writeSectionsAndChunks(ctx, pgxConn, sectionsToWrite, chunksToWrite, pending); err != nil {
+		return nil, 0, err
+	}
+	return pageIDs, len(chunksToWrite), nil
+}
+
+// writeSectionsAndChunks writes the sections, then the chunks with the section they are in, in one
+// transaction so a failure leaves no sections without their chunks
+func writeSectionsAndChunks(ctx context.Context, pgxConn *pgxpool.Pool, sections []Chunk, chunks []Chunk, pending bool) error {
+	tx, err := pgxConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sectionIDs := make([]int64, len(sections))
+	for i, section := range sections {
+		err := tx.QueryRow(ctx, "INSERT INTO chunk_sections (page_id, text, token_count, metadata, created_at, language, docs_version, strategy_version, pending) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id", section.PageID, section.Text, section.TokenCount, section.Metadata, section.CreatedAt, section.Language, section.DocsVersion, section.StrategyVersion, pending).Scan(&sectionIDs[i])
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk section: %w", err)
+		}
+	}
+	for _, chunk := range chunks {
+		_, err := tx.Exec(ctx, "INSERT INTO chunks (page_id, section_id, text, token_count, metadata, created_at, language, docs_version, strategy_version, pending, simhash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", chunk.PageID, sectionIDs[chunk.section], chunk.Text, chunk.TokenCount, chunk.Metadata, chunk.CreatedAt, chunk.Language, chunk.DocsVersion, chunk.StrategyVersion, pending, chunk.SimHash)
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// newPageChunk returns a chunk or section of a page to write, index being its position in the page
+func newPageChunk(page pageToChunk, chunk chunker.Chunk, index int, strategyVersion string) Chunk {
+	return Chunk{
+		PageID:      page.id,
+		Language:    page.language,
+		DocsVersion: page.docsVersion,
+		Text:        chunk.Text,
+		TokenCount:  chunk.TokenCount,
+		Metadata: types.ChunkMetadata{
+			SourceURL:     page.url,
+			Title:         page.title,
+			ChunkPath:     chunk.HeadingPath,
+			Index:         index,
+			Anchor:        chunk.Anchor,
+			DeepLink:      helpers.DeepLink(page.url, chunk.Anchor),
+			StartOffset:   chunk.StartOffset,
+			EndOffset:     chunk.EndOffset,
+			TokenCount:    chunk.TokenCount,
+			ContentType:   chunk.ContentType,
+			HasCode:       chunk.HasCode,
+			CodeLanguages: chunk.CodeLanguages,
+		},
+		CreatedAt:       time.Now(),
+		StrategyVersion: strategyVersion,
+	}
+}
+
+// chunkSkip is a chunk the quality stage kept from being written and embedded, recorded with what
+// was measured of it so the thresholds can be tuned
+type chunkSkip struct {
+	chunk             Chunk
+	reason            string
+	quality           helpers.ChunkQuality
+	duplicateOfPageID *int
+	duplicateDistance *int
+}
+
+// checkChunkQuality sets the SimHash of chunk and reports whether it is worth embedding. Chunks
+// that are kept are added to duplicates so their near-copies are skipped.
+func checkChunkQuality(chunk *Chunk, duplicates *helpers.DuplicateIndex) (chunkSkip, bool) {
+	quality := helpers.MeasureChunk(chunk.Text)
+	chunk.SimHash = int64(quality.SimHash)
+	skip := chunkSkip{chunk: *chunk, quality: quality}
+
+	if reason := helpers.DefaultQualityThresholds.LowQualityReason(quality); reason != "" {
+		skip.reason = reason
+		return skip, false
+	}
+	if pageID, distance, found := duplicates.Find(quality.SimHash); found {
+		skip.reason = helpers.SkipNearDuplicate
+		skip.duplicateOfPageID = &pageID
+		skip.duplicateDistance = &distance
+		return skip, false
+	}
+	duplicates.Add(quality.SimHash, chunk.PageID)
+	return skip, true
+}
+
+// duplicateScope is the docs version and language whose chunks a chunk is compared with, so a page
+// of a new version that repeats the old one is not skipped
+type duplicateScope struct {
+	language    string
+	docsVersion string
+}
+
+// duplicateIndexes holds the near-duplicate index of each scope of a source
+type duplicateIndexes map[duplicateScope]*helpers.DuplicateIndex
+
+// of returns the index of the chunks in a language and docs version, either of which may be unknown
+func (d duplicateIndexes) of(language *string, docsVersion *string) *helpers.DuplicateIndex {
+	var scope duplicateScope
+	if language != nil {
+		scope.language = *language
+	}
+	if docsVersion != nil {
+		scope.docsVersion = *docsVersion
+	}
+	index, ok := d[scope]
+	if !ok {
+		index = helpers.NewDuplicateIndex(helpers.DefaultQualityThresholds.MaxDuplicateDistance)
+		d[scope] = index
+	}
+	return index
+}
+
+// loadSourceSimHashes adds the SimHashes of the stored chunks of a source to duplicates, leaving out
+// the chunks of the pages in excludePageIDs that are being chunked again
+func loadSourceSimHashes(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int, excludePageIDs []int, duplicates duplicateIndexes) error {
+	rows, err := pgxConn.Query(ctx, "SELECT chunks.simhash, chunks.page_id, chunks.language, chunks.docs_version FROM chunks JOIN pages ON chunks.page_id = pages.id JOIN urls ON pages.url_id = urls.id WHERE urls.source_id = $1 AND pages.removed_at IS NULL AND chunks.simhash IS NOT NULL AND NOT chunks.pending AND NOT chunks.page_id = ANY($2)", sourceID, excludePageIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var simHash int64
+		var pageID int
+		var language, docsVersion *string
+		if err := rows.Scan(&simHash, &pageID, &language, &docsVersion); err != nil {
+			return err
+		}
+		duplicates.of(language, docsVersion).Add(uint64(simHash), pageID)
+	}
+	return rows.Err()
+}
+
+// writeChunkSkips replaces the recorded skips of pages by skips. The skips of pending chunks are
+// pending too, and replace the pages' current skips when the chunks are promoted.
+func writeChunkSkips(ctx context.Context, pgxConn *pgxpool.Pool, pageIDs []int, skips []chunkSkip, pending bool) error {
+	if _, err := pgxConn.Exec(ctx, "DELETE FROM chunk_skips WHERE pending = $2 AND page_id = ANY($1)", pageIDs, pending); err != nil {
+		return fmt.Errorf("failed to delete chunk skips: %w", err)
+	}
+	for _, skip := range skips {
+		_, err := pgxConn.Exec(ctx, "INSERT INTO chunk_skips (page_id, text, reason, words, link_share, simhash, duplicate_of_page_id, duplicate_distance, strategy_version, pending) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", skip.chunk.PageID, skip.chunk.Text, skip.reason, skip.quality.Words, skip.quality.LinkShare, skip.chunk.SimHash, skip.duplicateOfPageID, skip.duplicateDistance, skip.chunk.StrategyVersion, pending)
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk skip: %w", err)
+		}
+	}
+	return nil
+}
+
+// deletePendingChunks removes the pending sections, chunks and skips of pages
+func deletePendingChunks(ctx context.Context, pgxConn *pgxpool.Pool, pageIDs []int) error {
+	if _, err := pgxConn.Exec(ctx, "DELETE FROM chunk_sections WHERE pending AND page_id = ANY($1)", pageIDs); err != nil {
+		return fmt.Errorf("failed to delete pending chunk sections: %w", err)
+	}
+	if _, err := pgxConn.Exec(ctx, "DELETE FROM chunks WHERE pending AND page_id = ANY($1)", pageIDs); err != nil {
+		return fmt.Errorf("failed to delete pending chunks: %w", err)
+	}
+	if _, err := pgxConn.Exec(ctx, "DELETE FROM chunk_skips WHERE pending AND page_id = ANY($1)", pageIDs); err != nil {
+		return fmt.Errorf("failed to delete pending chunk skips: %w", err)
+	}
+	return nil
+}
+
+// embeddingConcurrency bounds the number of batches of chunks embedded at the same time. The
+// embedder's rate limit is shared by all of them.
+const embeddingConcurrency = 4
+
+// chunkToEmbed is a chunk without an embedding
+type chunkToEmbed struct {
+	id   int
+	text string
+}
+
+// loadChunksToEmbed returns the chunks without an embedding of model that match condition, a SQL
+// condition on chunks whose parameters are args, numbered from $2
+func loadChunksToEmbed(ctx context.Context, pgxConn *pgxpool.Pool, model string, condition string, args ...any) ([]chunkToEmbed, error) {
+	query := "SELECT id, text FROM chunks WHERE NOT EXISTS (SELECT 1 FROM embeddings WHERE embeddings.chunk_id = chunks.id AND embeddings.model = $1) AND " + condition + " ORDER BY id"
+	rows, err := pgxConn.Query(ctx, query, append([]any{model}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []chunkToEmbed
+	for rows.Next() {
+		var chunk chunkToEmbed
+		if err := rows.Scan(&chunk.id, &chunk.text); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// embedChunks embeds chunks in batches and saves each batch in its own transaction as soon as it
+// is embedded, returning the number of embeddings saved. A batch that fails after the embedder's
+// retries leaves its chunks without an embedding for a later run, and does not stop the others.
+// Embeddings are stored per chunk and model, so chunks can be embedded with several models.
+func embedChunks(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, textEmbedder embedder.Embedder, chunks []chunkToEmbed) (int, error) {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.text
+	}
+
+	var saved atomic.Int64
+	err := embedder.EmbedBatches(ctx, textEmbedder, texts, embedder.TaskDocument, embeddingConcurrency, func(ctx context.Context, batch embedder.Batch) error {
+		tx, err := pgxConn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback(ctx)
+
+		if err := registerEmbeddingModel(ctx, tx, textEmbedder); err != nil {
+			return fmt.Errorf("failed to register model %s: %w", textEmbedder.Model(), err)
+		}
+		for i, embedding := range batch.Embeddings {
+			chunk := chunks[batch.Start+i]
+			_, err := tx.Exec(ctx, "INSERT INTO embeddings (chunk_id, model, embedding) VALUES ($1, $2, $3::vector) ON CONFLICT (chunk_id, model) DO UPDATE SET embedding = excluded.embedding, created_at = now()", chunk.id, textEmbedder.Model(), helpers.ConvertToVector(embedding))
+			if err != nil {
+				return fmt.Errorf("failed to save embedding of chunk %d: %w", chunk.id, err)
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit embeddings: %w", err)
+		}
+
+		total := saved.Add(int64(len(batch.Embeddings)))
+		logger.Printf("Saved %d of %d embeddings with %s", total, len(chunks), textEmbedder.Model())
+		return nil
+	})
+	return int(saved.Load()), err
+}
+
+// embedPendingChunks embeds the pending chunks of pages with every configured model, so the new
+// chunks answer queries of each model once they replace the current ones
+func embedPendingChunks(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, embedders *embedder.Registry, pageIDs []int) error {
+	for _, textEmbedder := range embedders.All() {
+		chunks, err := loadChunksToEmbed(ctx, pgxConn, textEmbedder.Model(), "pending AND page_id = ANY($2)", pageIDs)
+		if err != nil {
+			return fmt.Errorf("failed to query pending chunks: %w", err)
+		}
+		if _, err := embedChunks(ctx, logger, pgxConn, textEmbedder, chunks); err != nil {
+			return fmt.Errorf("failed to embed pending chunks with %s: %w", textEmbedder.Model(), err)
+		}
+	}
+	return nil
+}
+
+// promotePendingChunks replaces the sections, chunks and skips of pages by their pending ones in one
+// transaction, so queries see either the old chunks or the new ones
+func promotePendingChunks(ctx context.Context, pgxConn *pgxpool.Pool, pageIDs []int) error {
+	tx, err := pgxConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	statements := []string{
+		"DELETE FROM chunk_sections WHERE NOT pending AND page_id = ANY($1)",
+		"DELETE FROM chunks WHERE NOT pending AND page_id = ANY($1)",
+		"DELETE FROM chunk_skips WHERE NOT pending AND page_id = ANY($1)",
+		"UPDATE chunk_sections SET pending = false WHERE pending AND page_id = ANY($1)",
+		"UPDATE chunks SET pending = false WHERE pending AND page_id = ANY($1)",
+		"UPDATE chunk_skips SET pending = false WHERE pending AND page_id = ANY($1)",
+		"UPDATE pages SET processed_at = now() WHERE id = ANY($1)",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, pageIDs); err != nil {
+			return fmt.Errorf("failed to replace chunks: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/helpers"
+)
+
+func TestDuplicateIndexesScope(t *testing.T) {
+	en, v1, v2 := "en", "v1", "v2"
+	text := "Install the SDK with npm and configure your API key before making the first request to the service."
+	chunk := Chunk{PageID: 1, Text: text, Language: &en, DocsVersion: &v1}
+
+	duplicates := make(duplicateIndexes)
+	if _, ok := checkChunkQuality(&chunk, duplicates.of(&en, &v1)); !ok {
+		t.Fatal("first chunk was skipped")
+	}
+
+	repeat := Chunk{PageID: 2, Text: text, Language: &en, DocsVersion: &v1}
+	if skip, ok := checkChunkQuality(&repeat, duplicates.of(&en, &v1)); ok || skip.reason != helpers.SkipNearDuplicate {
+		t.Errorf("repeat in the same version: kept = %v, reason = %q, want %q", ok, skip.reason, helpers.SkipNearDuplicate)
+	}
+
+	nextVersion := Chunk{PageID: 3, Text: text, Language: &en, DocsVersion: &v2}
+	if skip, ok := checkChunkQuality(&nextVersion, duplicates.of(&en, &v2)); !ok {
+		t.Errorf("repeat in another version was skipped as %q", skip.reason)
+	}
+
+	unknown := Chunk{PageID: 4, Text: text}
+	if skip, ok := checkChunkQuality(&unknown, duplicates.of(nil, nil)); !ok {
+		t.Errorf("repeat without a version or language was skipped as %q", skip.reason)
+	}
+}
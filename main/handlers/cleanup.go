@@ -121,7 +121,7 @@ func HandleUpdatePageTitle(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 		logger.Println("Updating page titles from HTML content")
 
 		// get all html content from the database
-		rows, err := pgxConn.Query(context.Background(), "SELECT id, html_content FROM pages")
+		rows, err := pgxConn.Query(context.Background(), "SELECT pages.id, html_content, url FROM pages JOIN urls ON pages.url_id = urls.id")
 		if err != nil {
 			logger.Println("Error getting content:", err)
 			http.Error(w, "Error getting content", http.StatusInternalServerError)
@@ -133,7 +133,8 @@ func HandleUpdatePageTitle(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 		for rows.Next() {
 			var id int
 			var path string
-			err = rows.Scan(&id, &path)
+			var pageURL string
+			err = rows.Scan(&id, &path, &pageURL)
 			if err != nil {
 				logger.Println("Error scanning content:", err)
 				updateErrors = append(updateErrors, err)
@@ -147,7 +148,7 @@ func HandleUpdatePageTitle(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 				continue
 			}
 
-			title := helpers.GetTitleFromHTML(htmlContent)
+			title := helpers.ExtractPageMetadata(htmlContent, pageURL).Title
 
 			// update the page content fields
 			_, err = pgxConn.Exec(context.Background(), "UPDATE pages SET title = $1 WHERE id = $2", title, id)
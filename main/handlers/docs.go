@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/itsmaleen/tech-doc-processor/embedder"
 	"github.com/itsmaleen/tech-doc-processor/helpers"
 	"github.com/itsmaleen/tech-doc-processor/types"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func HandleQueryDocs(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey string) http.HandlerFunc {
+func HandleQueryDocs(logger *log.Logger, pgxConn *pgxpool.Pool, embedders *embedder.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests
 		if r.Method != http.MethodPost {
@@ -33,7 +35,14 @@ func HandleQueryDocs(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey str
 
 		logger.Printf("Query: %s", query)
 
-		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, geminiApiKey, query, 10)
+		textEmbedder, err := queryEmbedder(r, pgxConn, embedders)
+		if err != nil {
+			logger.Printf("Failed to choose the embedding model: %v", err)
+			writeQueryEmbedderError(w, err)
+			return
+		}
+
+		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, textEmbedder, query, parseRetrievalFilter(r), 10)
 		if err != nil {
 			http.Error(w, "Failed to retrieve top relevant chunks", http.StatusInternalServerError)
 			return
@@ -62,7 +71,7 @@ func HandleLoadDocPaths(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerF
 
 		// Get the html contend from all pages
 
-		rows, err := pgxConn.Query(context.Background(), "SELECT pages.id, url, markdown_content, title FROM pages JOIN urls ON pages.url_id = urls.id WHERE url ilike $1", fmt.Sprintf("%%%s%%", url))
+		rows, err := pgxConn.Query(context.Background(), "SELECT pages.id, url, markdown_content, title, COALESCE(description, ''), COALESCE(canonical_url, ''), last_updated FROM pages JOIN urls ON pages.url_id = urls.id WHERE url ilike $1 AND pages.removed_at IS NULL", fmt.Sprintf("%%%s%%", url))
 		if err != nil {
 			http.Error(w, "Failed to query pages", http.StatusInternalServerError)
 			return
@@ -75,7 +84,7 @@ func HandleLoadDocPaths(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerF
 		var pages []types.Page
 		for rows.Next() {
 			var page types.Page
-			err := rows.Scan(&page.ID, &page.URL, &page.Path, &page.Title)
+			err := rows.Scan(&page.ID, &page.URL, &page.Path, &page.Title, &page.Description, &page.CanonicalURL, &page.LastUpdated)
 			if err != nil {
 				http.Error(w, "Failed to scan page", http.StatusInternalServerError)
 				return
@@ -110,7 +119,7 @@ func HandleLoadDocsContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 
 		logger.Printf("URL: %s", url)
 
-		rows, err := pgxConn.Query(context.Background(), "SELECT pages.id, url, markdown_content, title FROM pages JOIN urls ON pages.url_id = urls.id WHERE url ilike $1", fmt.Sprintf("%%%s%%", url))
+		rows, err := pgxConn.Query(context.Background(), "SELECT pages.id, url, markdown_content, title, COALESCE(description, ''), COALESCE(canonical_url, ''), last_updated, COALESCE(headings, '[]') FROM pages JOIN urls ON pages.url_id = urls.id WHERE url ilike $1 AND pages.removed_at IS NULL", fmt.Sprintf("%%%s%%", url))
 		if err != nil {
 			http.Error(w, "Failed to query pages", http.StatusInternalServerError)
 			return
@@ -123,7 +132,7 @@ func HandleLoadDocsContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 		var pages []types.Page
 		for rows.Next() {
 			var page types.Page
-			err := rows.Scan(&page.ID, &page.URL, &page.Path, &page.Title)
+			err := rows.Scan(&page.ID, &page.URL, &page.Path, &page.Title, &page.Description, &page.CanonicalURL, &page.LastUpdated, &page.Headings)
 			if err != nil {
 				http.Error(w, "Failed to scan page", http.StatusInternalServerError)
 				return
@@ -136,6 +145,14 @@ func HandleLoadDocsContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 				return
 			}
 
+			page.FrontMatter, markdownContent, err = helpers.ParseFrontMatter(markdownContent)
+			if err != nil {
+				logger.Printf("Ignoring invalid front matter for %s: %v", page.Path, err)
+			}
+
+			if page.Title == "" && page.FrontMatter != nil {
+				page.Title = page.FrontMatter.Title
+			}
 			if page.Title == "" {
 				page.Title = "Untitled"
 			}
@@ -169,13 +186,19 @@ func HandleLoadPageContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 		logger.Printf("ID: %s", id)
 
 		var page types.Page
+		var removedAt *time.Time
 
-		err := pgxConn.QueryRow(context.Background(), "SELECT url, markdown_content, title FROM pages JOIN urls ON pages.url_id = urls.id WHERE pages.id=$1", id).Scan(&page.URL, &page.Path, &page.Title)
+		err := pgxConn.QueryRow(context.Background(), "SELECT url, markdown_content, title, removed_at, COALESCE(description, ''), COALESCE(canonical_url, ''), last_updated, COALESCE(headings, '[]') FROM pages JOIN urls ON pages.url_id = urls.id WHERE pages.id=$1", id).Scan(&page.URL, &page.Path, &page.Title, &removedAt, &page.Description, &page.CanonicalURL, &page.LastUpdated, &page.Headings)
 		if err != nil {
 			http.Error(w, "Failed to query pages", http.StatusInternalServerError)
 			return
 		}
 
+		if removedAt != nil {
+			http.Error(w, "Page has been removed from the source", http.StatusGone)
+			return
+		}
+
 		markdownContent, err := helpers.GetFileContentFromStorage(logger, supabaseURL, supabaseStorageBucket, page.Path)
 		if err != nil {
 			logger.Printf("Failed to read markdown content: %v", err)
@@ -183,6 +206,14 @@ func HandleLoadPageContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseUR
 			return
 		}
 
+		page.FrontMatter, markdownContent, err = helpers.ParseFrontMatter(markdownContent)
+		if err != nil {
+			logger.Printf("Ignoring invalid front matter for %s: %v", page.Path, err)
+		}
+
+		if page.Title == "" && page.FrontMatter != nil {
+			page.Title = page.FrontMatter.Title
+		}
 		if page.Title == "" {
 			page.Title = "Untitled"
 		}
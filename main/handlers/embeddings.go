@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+	"github.com/itsmaleen/tech-doc-processor/helpers"
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// errUnknownModel is returned for models that no embedder is configured for
+var errUnknownModel = errors.New("no embedder is configured for the model")
+
+// errNoDefaultEmbedder is returned when this server has no embedder of the default model, so
+// queries cannot be matched with the stored embeddings until one is configured
+var errNoDefaultEmbedder = errors.New("no embedder is configured for the default embedding model")
+
+// registerEmbeddingModel records a model in embedding_models, so it can be made the default once
+// its embeddings are saved. It is called with the model's first embeddings, since models that embed
+// at their own size only know it then.
+func registerEmbeddingModel(ctx context.Context, db dbExecutor, e embedder.Embedder) error {
+	_, err := db.Exec(ctx, "INSERT INTO embedding_models (model, dimensions) VALUES ($1, $2) ON CONFLICT (model) DO NOTHING", e.Model(), e.Dimensions())
+	return err
+}
+
+// DefaultEmbeddingModel returns the model queries are embedded with when they do not choose one, or
+// "" when none is marked as the default
+func DefaultEmbeddingModel(ctx context.Context, pgxConn *pgxpool.Pool) (string, error) {
+	var model string
+	err := pgxConn.QueryRow(ctx, "SELECT model FROM embedding_models WHERE is_default").Scan(&model)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return model, err
+}
+
+// SetDefaultEmbeddingModel makes model the default of queries in one transaction, so queries see
+// either the old default or the new one
+func SetDefaultEmbeddingModel(ctx context.Context, pgxConn *pgxpool.Pool, model string) error {
+	tx, err := pgxConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var hasEmbeddings bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM embeddings WHERE model = $1)", model).Scan(&hasEmbeddings); err != nil {
+		return fmt.Errorf("failed to check the embeddings of model %s: %w", model, err)
+	}
+	if !hasEmbeddings {
+		return fmt.Errorf("model %s has no embeddings; embed chunks with it first", model)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE embedding_models SET is_default = false WHERE is_default"); err != nil {
+		return fmt.Errorf("failed to unset the default model: %w", err)
+	}
+	tag, err := tx.Exec(ctx, "UPDATE embedding_models SET is_default = true WHERE model = $1", model)
+	if err != nil {
+		return fmt.Errorf("failed to set the default model: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("model %s is not registered; embed chunks with it first", model)
+	}
+	return tx.Commit(ctx)
+}
+
+// EmbeddingModels returns every model with embeddings, and how many of the chunks that answer
+// queries each has embedded
+func EmbeddingModels(ctx context.Context, pgxConn *pgxpool.Pool) ([]types.EmbeddingModel, error) {
+	rows, err := pgxConn.Query(ctx, `
+		SELECT embedding_models.model, embedding_models.dimensions, embedding_models.is_default,
+			(SELECT count(*) FROM embeddings JOIN chunks ON embeddings.chunk_id = chunks.id JOIN pages ON chunks.page_id = pages.id
+				WHERE embeddings.model = embedding_models.model AND NOT chunks.pending AND pages.removed_at IS NULL),
+			(SELECT count(*) FROM chunks JOIN pages ON chunks.page_id = pages.id WHERE NOT chunks.pending AND pages.removed_at IS NULL)
+		FROM embedding_models
+		ORDER BY embedding_models.created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var models []types.EmbeddingModel
+	for rows.Next() {
+		var model types.EmbeddingModel
+		if err := rows.Scan(&model.Model, &model.Dimensions, &model.IsDefault, &model.EmbeddedChunks, &model.TotalChunks); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
+
+// queryEmbedder returns the embedder of the model a request chooses with its model value, or of the
+// default model when it chooses none
+func queryEmbedder(r *http.Request, pgxConn *pgxpool.Pool, embedders *embedder.Registry) (embedder.Embedder, error) {
+	model := r.FormValue("model")
+	if model != "" {
+		return chooseQueryEmbedder(embedders, model, "")
+	}
+	defaultModel, err := DefaultEmbeddingModel(r.Context(), pgxConn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the default embedding model: %w", err)
+	}
+	return chooseQueryEmbedder(embedders, model, defaultModel)
+}
+
+// chooseQueryEmbedder returns the embedder of the requested model, or of the default model when
+// none is requested. The first configured embedder is used when no model is the default. A default
+// model this server has no embedder of is an error rather than a fallback, since embeddings of
+// another model would not match the stored ones.
+func chooseQueryEmbedder(embedders *embedder.Registry, requestedModel string, defaultModel string) (embedder.Embedder, error) {
+	if requestedModel != "" {
+		e, ok := embedders.Get(requestedModel)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownModel, requestedModel)
+		}
+		return e, nil
+	}
+	if defaultModel == "" {
+		return embedders.Primary(), nil
+	}
+	e, ok := embedders.Get(defaultModel)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errNoDefaultEmbedder, defaultModel)
+	}
+	return e, nil
+}
+
+// HandleEmbeddingModels lists the models chunks are embedded with, which one is the default, and
+// how many chunks each has embedded
+func HandleEmbeddingModels(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET requests
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		models, err := EmbeddingModels(r.Context(), pgxConn)
+		if err != nil {
+			logger.Printf("Failed to list embedding models: %v", err)
+			http.Error(w, "Failed to list embedding models", http.StatusInternalServerError)
+			return
+		}
+		helpers.Encode(w, r, http.StatusOK, models)
+	}
+}
+
+// writeQueryEmbedderError answers a request whose embedder could not be chosen
+func writeQueryEmbedderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnknownModel) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errNoDefaultEmbedder) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "Failed to choose the embedding model", http.StatusInternalServerError)
+}
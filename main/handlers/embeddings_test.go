@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+)
+
+func TestChooseQueryEmbedder(t *testing.T) {
+	embedders, err := embedder.NewRegistry(embedder.NewLocal("primary", 8), embedder.NewLocal("secondary", 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		requestedModel string
+		defaultModel   string
+		want           string
+		wantErr        error
+	}{
+		{"no default", "", "", "primary", nil},
+		{"default", "", "secondary", "secondary", nil},
+		{"requested over default", "primary", "secondary", "primary", nil},
+		{"unknown requested model", "missing", "primary", "", errUnknownModel},
+		{"default without embedder", "", "missing", "", errNoDefaultEmbedder},
+	}
+	for _, test := range tests {
+		e, err := chooseQueryEmbedder(embedders, test.requestedModel, test.defaultModel)
+		if test.wantErr != nil {
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("%s: error = %v, want %v", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if e.Model() != test.want {
+			t.Errorf("%s: model = %q, want %q", test.name, e.Model(), test.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmaleen/tech-doc-processor/helpers"
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// readinessCheckTimeout bounds each readiness check so a hung dependency fails the probe instead of
+// blocking it
+const readinessCheckTimeout = 3 * time.Second
+
+// ReadinessCheck checks that a dependency the server needs to answer requests is available
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HandleLiveness reports that the server is running
+func HandleLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// HandleReadiness runs the readiness checks and responds with 503 Service Unavailable when any of
+// them fails, so the server is taken out of rotation while a dependency is down
+func HandleReadiness(logger *log.Logger, checks []ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := types.Readiness{Ready: true, Checks: make(map[string]string, len(checks))}
+		for _, check := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+			err := check.Check(ctx)
+			cancel()
+			if err != nil {
+				logger.Printf("Readiness check %s failed: %v", check.Name, err)
+				readiness.Ready = false
+				readiness.Checks[check.Name] = err.Error()
+				continue
+			}
+			readiness.Checks[check.Name] = "ok"
+		}
+
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		helpers.Encode(w, r, status, readiness)
+	}
+}
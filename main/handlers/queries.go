@@ -4,15 +4,17 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
 	"github.com/itsmaleen/tech-doc-processor/helpers"
 	"github.com/itsmaleen/tech-doc-processor/types"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func HandleRetrievalQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey string) http.HandlerFunc {
+func HandleRetrievalQuery(logger *log.Logger, pgxConn *pgxpool.Pool, embedders *embedder.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests
 		if r.Method != http.MethodPost {
@@ -34,8 +36,16 @@ func HandleRetrievalQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKe
 
 		logger.Printf("RAG Query: %s", query)
 
+		// The query is embedded with the requested model or the default one
+		textEmbedder, err := queryEmbedder(r, pgxConn, embedders)
+		if err != nil {
+			logger.Printf("Failed to choose the embedding model: %v", err)
+			writeQueryEmbedderError(w, err)
+			return
+		}
+
 		// Retrieve relevant chunks
-		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, geminiApiKey, query, 10)
+		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, textEmbedder, query, parseRetrievalFilter(r), 10)
 		if err != nil {
 			http.Error(w, "Failed to retrieve top relevant chunks", http.StatusInternalServerError)
 			return
@@ -45,8 +55,10 @@ func HandleRetrievalQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKe
 		for _, chunk := range chunksData {
 			htmlContent := helpers.GetHTMLFromMarkdown(chunk.Text)
 			sources = append(sources, types.Source{
-				Text: htmlContent,
-				URL:  chunk.SourceURL,
+				Text:        htmlContent,
+				URL:         chunk.SourceURL,
+				DocsVersion: chunk.DocsVersion,
+				DeepLink:    chunk.DeepLink,
 			})
 		}
 
@@ -62,20 +74,114 @@ func HandleRetrievalQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKe
 	}
 }
 
-func retrieveTopRelevantChunks(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey string, query string, limit int) ([]types.ChunkData, error) {
-	embedding, err := helpers.GenerateGeminiEmbedding(geminiApiKey, query, "gemini-embedding-exp-03-07", helpers.TaskTypeRetrievalQuery)
+// retrievalFilter narrows the chunks considered by retrieveTopRelevantChunks
+type retrievalFilter struct {
+	// Languages restricts chunks to these language tags, matched exactly or by primary subtag
+	Languages []string
+	// Version restricts versioned chunks to one docs version. Empty means the latest version
+	// of each source and "all" disables the filter.
+	Version string
+}
+
+// allVersions is the version filter value that searches every docs version
+const allVersions = "all"
+
+// parseRetrievalFilter reads the optional filters of a query request
+func parseRetrievalFilter(r *http.Request) retrievalFilter {
+	return retrievalFilter{
+		Languages: helpers.NormalizeLanguageList(r.FormValue("language")),
+		Version:   strings.ToLower(strings.TrimSpace(r.FormValue("version"))),
+	}
+}
+
+// latestVersionsBySource returns, for each source with versioned pages, the version queries default to.
+// Sources whose versions cannot be ranked get "", and queries keep all of their versions.
+func latestVersionsBySource(ctx context.Context, pgxConn *pgxpool.Pool) ([]int, []string, error) {
+	rows, err := pgxConn.Query(ctx, `
+		SELECT urls.source_id, COALESCE(documentation_sources.default_version, ''), array_agg(DISTINCT pages.docs_version)
+		FROM pages
+		JOIN urls ON pages.url_id = urls.id
+		LEFT JOIN documentation_sources ON urls.source_id = documentation_sources.id
+		WHERE pages.docs_version IS NOT NULL AND pages.removed_at IS NULL AND urls.source_id IS NOT NULL
+		GROUP BY urls.source_id, documentation_sources.default_version
+	`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var sourceIDs []int
+	var versions []string
+	for rows.Next() {
+		var sourceID int
+		var defaultVersion string
+		var sourceVersions []string
+		if err := rows.Scan(&sourceID, &defaultVersion, &sourceVersions); err != nil {
+			return nil, nil, err
+		}
+		sourceIDs = append(sourceIDs, sourceID)
+		versions = append(versions, helpers.LatestVersion(sourceVersions, defaultVersion))
+	}
+	return sourceIDs, versions, rows.Err()
+}
+
+// sectionOverfetch is how many chunks are matched for each section retrieved, since the best
+// matching chunks are often in the same section
+const sectionOverfetch = 4
+
+func retrieveTopRelevantChunks(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, textEmbedder embedder.Embedder, query string, filter retrievalFilter, limit int) ([]types.ChunkData, error) {
+	embedding, err := textEmbedder.Embed(ctx, query, embedder.TaskQuery)
 	if err != nil {
 		logger.Printf("Error in similarity search: %v", err)
 		return nil, err
 	}
 
-	logger.Printf("Embedding generated for query is dimension %d", len(embedding))
+	logger.Printf("Embedding generated for query with %s is dimension %d", textEmbedder.Model(), len(embedding))
 
 	// Convert query embedding to PostgreSQL vector format
 	vectorStr := helpers.ConvertToVector(embedding)
 
-	// Use cosine similarity operator (<->) with proper vector casting
-	rows, err := pgxConn.Query(ctx, "SELECT id, text, metadata FROM chunks ORDER BY vector_embedding <=> $1::vector LIMIT $2", vectorStr, limit)
+	// Versioned chunks are limited to the requested version, or by default to each source's latest version
+	var version *string
+	var latestSourceIDs []int
+	var latestVersions []string
+	switch filter.Version {
+	case allVersions:
+	case "":
+		latestSourceIDs, latestVersions, err = latestVersionsBySource(ctx, pgxConn)
+		if err != nil {
+			logger.Printf("Error getting latest docs versions: %v", err)
+			return nil, err
+		}
+	default:
+		version = &filter.Version
+	}
+
+	// Use cosine similarity operator (<->) with proper vector casting on the embeddings of the query's
+	// model, skipping chunks of removed pages, chunks outside the source's allowed languages or the
+	// requested languages, and other docs versions.
+	// Chunks are matched, and the sections they are in are returned.
+	rows, err := pgxConn.Query(ctx, `
+		SELECT chunks.id, chunks.text, COALESCE(chunks.token_count, 0), chunks.metadata, COALESCE(chunks.docs_version, ''),
+			chunks.section_id, chunk_sections.text, COALESCE(chunk_sections.token_count, 0), chunk_sections.metadata
+		FROM chunks
+		JOIN embeddings ON embeddings.chunk_id = chunks.id AND embeddings.model = $7
+		LEFT JOIN chunk_sections ON chunks.section_id = chunk_sections.id
+		JOIN pages ON chunks.page_id = pages.id
+		JOIN urls ON pages.url_id = urls.id
+		LEFT JOIN documentation_sources ON urls.source_id = documentation_sources.id
+		WHERE pages.removed_at IS NULL AND NOT chunks.pending
+			AND (chunks.language IS NULL OR documentation_sources.allowed_languages IS NULL OR cardinality(documentation_sources.allowed_languages) = 0
+				OR chunks.language = ANY(documentation_sources.allowed_languages) OR split_part(chunks.language, '-', 1) = ANY(documentation_sources.allowed_languages))
+			AND ($3::text[] IS NULL OR chunks.language IS NULL OR chunks.language = ANY($3) OR split_part(chunks.language, '-', 1) = ANY($3))
+			AND (chunks.docs_version IS NULL
+				OR ($4::text IS NULL AND $5::int[] IS NULL)
+				OR chunks.docs_version = $4
+				OR (urls.source_id, chunks.docs_version) IN (SELECT source_id, docs_version FROM unnest($5::int[], $6::text[]) AS latest(source_id, docs_version))
+				OR urls.source_id IN (SELECT source_id FROM unnest($5::int[], $6::text[]) AS latest(source_id, docs_version) WHERE latest.docs_version = ''))
+		ORDER BY embeddings.embedding <=> $1::vector
+		LIMIT $2
+	`, vectorStr, limit*sectionOverfetch, filter.Languages, version, latestSourceIDs, latestVersions, textEmbedder.Model())
 	if err != nil {
 		logger.Printf("Error in similarity search: %v", err)
 		return nil, err
@@ -83,32 +189,67 @@ func retrieveTopRelevantChunks(ctx context.Context, logger *log.Logger, pgxConn
 	defer rows.Close()
 
 	var chunks []types.Chunk
-	for rows.Next() {
+	matched := 0
+	sectionIDs := make(map[int64]bool)
+	for len(chunks) < limit && rows.Next() {
 		var id int
 		var text string
+		var tokenCount int
 		var metadata types.ChunkMetadata
-		err = rows.Scan(&id, &text, &metadata)
+		var docsVersion string
+		var sectionID *int64
+		var sectionText *string
+		var sectionTokenCount int
+		var sectionMetadata *types.ChunkMetadata
+		err = rows.Scan(&id, &text, &tokenCount, &metadata, &docsVersion, &sectionID, &sectionText, &sectionTokenCount, &sectionMetadata)
 		if err != nil {
 			logger.Printf("Error scanning row: %v", err)
 			return nil, err
 		}
+		matched++
+
+		// Chunks made before sections were stored are returned as they are
+		if sectionID != nil && sectionText != nil && sectionMetadata != nil {
+			if sectionIDs[*sectionID] {
+				continue
+			}
+			sectionIDs[*sectionID] = true
+			text = *sectionText
+			tokenCount = sectionTokenCount
+			metadata = *sectionMetadata
+		}
 
 		chunks = append(chunks, types.Chunk{
-			ID:       id,
-			Text:     text,
-			Metadata: metadata,
+			ID:          id,
+			Text:        text,
+			TokenCount:  tokenCount,
+			DocsVersion: docsVersion,
+			Metadata:    metadata,
 		})
 	}
 
-	logger.Printf("Found %d chunks in similarity search", len(chunks))
+	if err := rows.Err(); err != nil {
+		logger.Printf("Error in similarity search: %v", err)
+		return nil, err
+	}
+
+	logger.Printf("Found %d chunks in %d sections in similarity search", matched, len(chunks))
 
 	chunksData := []types.ChunkData{}
 	for _, chunk := range chunks {
+		// Chunks made before deep links were stored link to their page
+		deepLink := chunk.Metadata.DeepLink
+		if deepLink == "" {
+			deepLink = chunk.Metadata.SourceURL
+		}
 		chunksData = append(chunksData, types.ChunkData{
-			Text:       chunk.Text,
-			SourceURL:  chunk.Metadata.SourceURL,
-			ChunkPath:  chunk.Metadata.ChunkPath,
-			ChunkIndex: chunk.Metadata.Index,
+			Text:        chunk.Text,
+			SourceURL:   chunk.Metadata.SourceURL,
+			ChunkPath:   chunk.Metadata.ChunkPath,
+			ChunkIndex:  chunk.Metadata.Index,
+			TokenCount:  chunk.TokenCount,
+			DocsVersion: chunk.DocsVersion,
+			DeepLink:    deepLink,
 		})
 	}
 
@@ -116,7 +257,7 @@ func retrieveTopRelevantChunks(ctx context.Context, logger *log.Logger, pgxConn
 }
 
 // HandleRAGQuery handles RAG-based question answering using the Gemini API
-func HandleRAGQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey string) http.HandlerFunc {
+func HandleRAGQuery(logger *log.Logger, pgxConn *pgxpool.Pool, embedders *embedder.Registry, geminiApiKey string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests
 		if r.Method != http.MethodPost {
@@ -138,13 +279,24 @@ func HandleRAGQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey stri
 
 		logger.Printf("RAG Query: %s", query)
 
+		// The query is embedded with the requested model or the default one
+		textEmbedder, err := queryEmbedder(r, pgxConn, embedders)
+		if err != nil {
+			logger.Printf("Failed to choose the embedding model: %v", err)
+			writeQueryEmbedderError(w, err)
+			return
+		}
+
 		// Retrieve relevant chunks
-		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, geminiApiKey, query, 10)
+		chunksData, err := retrieveTopRelevantChunks(r.Context(), logger, pgxConn, textEmbedder, query, parseRetrievalFilter(r), 10)
 		if err != nil {
 			http.Error(w, "Failed to retrieve top relevant chunks", http.StatusInternalServerError)
 			return
 		}
 
+		// Only send as many of the best chunks as fit the prompt's token budget
+		chunksData = helpers.FitTokenBudget(chunksData, helpers.DefaultPromptTokenBudget)
+
 		// Generate answer using the chunks as grounding
 		answerStyle := helpers.AnswerStyleVerbose
 		temperature := 0.2 // Low temperature for more deterministic answers
@@ -160,8 +312,10 @@ func HandleRAGQuery(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey stri
 		for _, chunk := range chunksData {
 			htmlContent := helpers.GetHTMLFromMarkdown(chunk.Text)
 			sources = append(sources, types.Source{
-				Text: htmlContent,
-				URL:  chunk.SourceURL,
+				Text:        htmlContent,
+				URL:         chunk.SourceURL,
+				DocsVersion: chunk.DocsVersion,
+				DeepLink:    chunk.DeepLink,
 			})
 		}
 
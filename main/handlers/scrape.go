@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,15 +16,15 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mendableai/firecrawl-go"
 
-	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
-	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
-
-	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
 
 	"github.com/itsmaleen/tech-doc-processor/helpers"
+	"github.com/itsmaleen/tech-doc-processor/types"
 )
 
 func HandleSaveSitemapURLs(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string) http.HandlerFunc {
@@ -111,6 +112,12 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 			return
 		}
 
+		source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get source: %v", err), http.StatusInternalServerError)
+			return
+		}
+
 		uniqueURLs := make(map[string]bool)
 
 		// Get urls from database
@@ -138,23 +145,28 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 
 		logger.Printf("Found %d URLs in database", len(urls))
 
+		imageClient := helpers.NewPublicClient(30 * time.Second)
+
 		// Create a map of time.Time (precise to the minute) to int as a way to track rate limiting
 		rateLimitedURLs := make(map[time.Time]int)
 		rateLimit := 20
 		rateLimitWindow := time.Minute
-
-		// Scrape the urls
-		for urlID, urlToScrape := range urls {
-			logger.Printf("Scraping URL: %d", urlID)
-
-			// Check if the url has been rate limited
-			now := time.Now()
-			minute := now.Truncate(rateLimitWindow)
+		// waitForRateLimit is called before every Jina call, since each page takes more than one
+		waitForRateLimit := func() {
+			minute := time.Now().Truncate(rateLimitWindow)
 			if rateLimitedURLs[minute] > rateLimit {
 				// Wait for the rate limit window to pass
 				time.Sleep(rateLimitWindow)
+				minute = time.Now().Truncate(rateLimitWindow)
 			}
+			rateLimitedURLs[minute]++
+		}
 
+		// Scrape the urls
+		for urlID, urlToScrape := range urls {
+			logger.Printf("Scraping URL: %d", urlID)
+
+			waitForRateLimit()
 			markdown, err := helpers.GetMarkdownUsingJinaReader(logger, urlToScrape)
 			if err != nil {
 				logger.Printf("Failed to get markdown: %v", err)
@@ -167,19 +179,47 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 				continue
 			}
 
-			var pageID int
-			err = pgxConn.QueryRow(r.Context(), "INSERT INTO pages (url_id, title) VALUES ($1, $2) RETURNING id", urlID, title).Scan(&pageID)
+			// Jina's markdown has no metadata, breadcrumbs or sidebar, so they are read from the page's HTML
+			waitForRateLimit()
+			htmlContent, err := helpers.GetHTMLUsingJinaReader(logger, urlToScrape)
+			if err != nil {
+				logger.Printf("Failed to get html for the metadata and navigation of %s: %v", urlToScrape, err)
+			}
+			var metadata types.PageMetadata
+			if htmlContent != "" {
+				metadata = helpers.ExtractPageMetadata(htmlContent, urlToScrape)
+			}
+			// The title read from the HTML knows the site name, unlike Jina's copy of the <title>
+			if metadata.Title != "" {
+				title = metadata.Title
+			}
+			metadata.Title = title
+			metadata.Headings = helpers.GetHeadingsFromMarkdown(markdown)
 
+			language := helpers.DetectPageLanguage("", urlToScrape, markdown)
+			docsVersion := helpers.DetectDocsVersion(urlToScrape, source.VersionPattern)
 
-			markdownPath := fmt.Sprintf("%d/%d/page.md", urlID, pageID)
+			var pageID int
+			err = pgxConn.QueryRow(r.Context(), "INSERT INTO pages (url_id, title, language, docs_version) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, '')) RETURNING id", urlID, title, language, docsVersion).Scan(&pageID)
+
+			markdown = captureMarkdownImages(r.Context(), logger, pgxConn, imageClient, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, urlToScrape, markdown)
+			recordPageDiagrams(r.Context(), pgxConn, logger, pageID, markdown)
 
-			err = helpers.SaveFileToStorageFromLocalFile(context.Background(), logger, supabaseURL, supabaseStorageBucket, markdownPath, markdown, supabaseAnonKey)
+			markdownPath, err := savePageMarkdown(context.Background(), logger, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, markdown, types.PageFrontMatter{
+				SourceURL:   urlToScrape,
+				Title:       title,
+				Language:    language,
+				DocsVersion: docsVersion,
+			})
 			if err != nil {
 				logger.Printf("Failed to save markdown file")
 				continue
 			}
 
-			rateLimitedURLs[minute]++
+			recordPageMetadata(r.Context(), pgxConn, logger, pageID, metadata)
+			if htmlContent != "" {
+				recordPageNavigation(r.Context(), pgxConn, logger, pageID, helpers.ExtractPageNavigation(htmlContent, urlToScrape))
+			}
 
 			// Update the markdown to the database
 			_, err = pgxConn.Exec(r.Context(), "UPDATE pages SET markdown_content = $1 WHERE id = $2", markdownPath, pageID)
@@ -199,6 +239,7 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 
 			logger.Printf("Found %d urls in markdown", len(urls))
 
+			var internalLinks []string
 			for _, foundURL := range urls {
 				// Check if the url is under same domain as the source url
 				parsedFoundURL, err := url.Parse(foundURL)
@@ -234,6 +275,8 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 					cleanedURL = foundURL
 				}
 
+				internalLinks = append(internalLinks, cleanedURL)
+
 				if uniqueURLs[cleanedURL] {
 					logger.Printf("Skipping url: %s because it is already in the database", cleanedURL)
 					continue
@@ -248,6 +291,8 @@ func HandleScrapeURLsUsingJina(logger *log.Logger, pgxConn *pgxpool.Pool, supaba
 				uniqueURLs[cleanedURL] = true
 			}
 
+			recordPageLinks(r.Context(), pgxConn, logger, pageID, internalLinks)
+
 			// Update the url to set scraped to true
 			_, err = pgxConn.Exec(r.Context(), "UPDATE urls SET scraped = TRUE WHERE id = $1", urlID)
 			if err != nil {
@@ -392,7 +437,37 @@ func HandleFirecrawlWebhook(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseU
 				return
 			}
 
-			err = helpers.SaveFileToStorageFromLocalFile(r.Context(), logger, supabaseURL, supabaseStorageBucket, fmt.Sprintf("%d/%d/page.md", urlID, pageID), data.Markdown, supabaseAnonKey)
+			language := ""
+			if data.Metadata.Language != nil {
+				language = helpers.NormalizeLanguageTag(*data.Metadata.Language)
+			}
+			if language == "" {
+				language = helpers.DetectPageLanguage(data.HTML, *data.Metadata.SourceURL, data.Markdown)
+			}
+
+			versionPattern := ""
+			if source, err := getDocumentationSource(r.Context(), pgxConn, sourceID); err == nil {
+				versionPattern = source.VersionPattern
+			}
+			docsVersion := helpers.DetectDocsVersion(*data.Metadata.SourceURL, versionPattern)
+
+			metadata := helpers.ExtractPageMetadata(data.HTML, *data.Metadata.SourceURL)
+			title := metadata.Title
+			if title == "" && data.Metadata.Title != nil {
+				title = strings.TrimSpace(*data.Metadata.Title)
+			}
+			metadata.Title = title
+
+			imageClient := helpers.NewPublicClient(30 * time.Second)
+			markdown := captureMarkdownImages(r.Context(), logger, pgxConn, imageClient, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, *data.Metadata.SourceURL, data.Markdown)
+			recordPageDiagrams(r.Context(), pgxConn, logger, pageID, markdown)
+
+			_, err = savePageMarkdown(r.Context(), logger, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, markdown, types.PageFrontMatter{
+				SourceURL:   *data.Metadata.SourceURL,
+				Title:       title,
+				Language:    language,
+				DocsVersion: docsVersion,
+			})
 			if err != nil {
 				logger.Printf("Failed to save page markdown to storage bucket %s for %s: %v", supabaseStorageBucket, *data.Metadata.SourceURL, err)
 				http.Error(w, "Failed to save page markdown to storage bucket", http.StatusInternalServerError)
@@ -400,7 +475,7 @@ func HandleFirecrawlWebhook(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseU
 			}
 
 			// Update the pages table to set markdown_content and html_content to the markdown and html content
-			_, err = pgxConn.Exec(r.Context(), "UPDATE pages SET markdown_content = $1, html_content = $2 WHERE id = $3", fmt.Sprintf("%d/%d/page.md", urlID, pageID), fmt.Sprintf("%d/%d/page.html", urlID, pageID), pageID)
+			_, err = pgxConn.Exec(r.Context(), "UPDATE pages SET markdown_content = $1, html_content = $2, language = NULLIF($3, ''), docs_version = NULLIF($4, '') WHERE id = $5", fmt.Sprintf("%d/%d/page.md", urlID, pageID), fmt.Sprintf("%d/%d/page.html", urlID, pageID), language, docsVersion, pageID)
 			if err != nil {
 				logger.Printf("Failed to update page markdown and html content: %v", err)
 				http.Error(w, "Failed to update page markdown and html content", http.StatusInternalServerError)
@@ -409,6 +484,13 @@ func HandleFirecrawlWebhook(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseU
 
 			logger.Printf("Successfully updated page markdown and html content: %s", *data.Metadata.SourceURL)
 
+			if data.Metadata.StatusCode != nil {
+				recordCrawlResult(r.Context(), pgxConn, logger, urlID, *data.Metadata.StatusCode, "")
+			}
+			recordPageLinks(r.Context(), pgxConn, logger, pageID, helpers.GetInternalLinks(*data.Metadata.SourceURL, data.Links))
+			recordPageMetadata(r.Context(), pgxConn, logger, pageID, metadata)
+			recordPageNavigation(r.Context(), pgxConn, logger, pageID, helpers.ExtractPageNavigation(data.HTML, *data.Metadata.SourceURL))
+
 			// Update the urls table to set scraped to true
 			_, err = pgxConn.Exec(r.Context(), "UPDATE urls SET scraped = TRUE WHERE id = $1", urlID)
 			if err != nil {
@@ -598,9 +680,7 @@ func HandleScrapeDocsRaw(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseURL
 }
 
 func processAndScrapeURLs(urls []string, tx pgx.Tx, r *http.Request, sourceID int, logger *log.Logger, urlsFoundInPages map[string]bool, sourceURL string, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	client := helpers.NewLinkCheckClient(30 * time.Second)
 	for _, urlStr := range urls {
 		// Insert the URL and get its ID
 		var urlID int
@@ -637,11 +717,19 @@ func processAndScrapeURLs(urls []string, tx pgx.Tx, r *http.Request, sourceID in
 		resp, err := client.Get(urlStr)
 		if err != nil {
 			logger.Printf("Failed to fetch page %s: %v", urlStr, err)
+			recordCrawlResult(r.Context(), tx, logger, urlID, 0, helpers.FetchErrorMessage(err))
 			continue
 		} else {
 			logger.Printf("Successfully fetched page %s", urlStr)
 		}
 
+		recordCrawlResult(r.Context(), tx, logger, urlID, resp.StatusCode, "")
+		if resp.StatusCode >= http.StatusBadRequest {
+			logger.Printf("Page %s returned status %d, skipping", urlStr, resp.StatusCode)
+			resp.Body.Close()
+			continue
+		}
+
 		// Read the page content
 		htmlContent, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
@@ -666,6 +754,8 @@ func processAndScrapeURLs(urls []string, tx pgx.Tx, r *http.Request, sourceID in
 			continue
 		}
 
+		recordPageLinks(r.Context(), tx, logger, pageID, helpers.GetInternalLinks(urlStr, helpers.GetHrefsFromHTML(string(htmlContent))))
+
 		// Add html to storage
 		err = helpers.SaveFileToStorageFromLocalFile(r.Context(), logger, supabaseURL, supabaseStorageBucket, fmt.Sprintf("%d/%d/page.html", urlID, pageID), string(htmlContent), supabaseAnonKey)
 		if err != nil {
@@ -691,6 +781,81 @@ func processAndScrapeURLs(urls []string, tx pgx.Tx, r *http.Request, sourceID in
 	}
 }
 
+// dbExecutor is satisfied by both pgxpool.Pool and pgx.Tx
+type dbExecutor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// savePageMarkdown stores page markdown with its front matter at {url_id}/{page_id}/page.md and returns the path
+func savePageMarkdown(ctx context.Context, logger *log.Logger, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string, urlID int, pageID int, markdown string, frontMatter types.PageFrontMatter) (string, error) {
+	markdownPath := fmt.Sprintf("%d/%d/page.md", urlID, pageID)
+	if frontMatter.CrawledAt.IsZero() {
+		frontMatter.CrawledAt = time.Now().Truncate(time.Second)
+	}
+	content, err := helpers.AddFrontMatter(markdown, frontMatter)
+	if err != nil {
+		return "", err
+	}
+	err = helpers.SaveFileToStorageFromLocalFile(ctx, logger, supabaseURL, supabaseStorageBucket, markdownPath, content, supabaseAnonKey)
+	if err != nil {
+		return "", err
+	}
+	return markdownPath, nil
+}
+
+// recordCrawlResult stores the status of the latest fetch of a URL
+func recordCrawlResult(ctx context.Context, db dbExecutor, logger *log.Logger, urlID int, statusCode int, fetchError string) {
+	_, err := db.Exec(ctx, "UPDATE urls SET status_code = NULLIF($1, 0), fetch_error = NULLIF($2, ''), checked_at = $3 WHERE id = $4", statusCode, fetchError, time.Now(), urlID)
+	if err != nil {
+		logger.Printf("Failed to record crawl result for url %d: %v", urlID, err)
+	}
+}
+
+// recordPageLinks stores the internal links found on a page for the link report
+func recordPageLinks(ctx context.Context, db dbExecutor, logger *log.Logger, pageID int, links []string) {
+	for _, link := range links {
+		_, err := db.Exec(ctx, "INSERT INTO page_links (page_id, target_url) VALUES ($1, $2) ON CONFLICT (page_id, target_url) DO NOTHING", pageID, link)
+		if err != nil {
+			logger.Printf("Failed to save link %s for page %d: %v", link, pageID, err)
+		}
+	}
+}
+
+// recordPageMetadata stores the metadata read from a page's HTML. An empty title keeps the current one.
+func recordPageMetadata(ctx context.Context, db dbExecutor, logger *log.Logger, pageID int, metadata types.PageMetadata) {
+	headings, err := json.Marshal(metadata.Headings)
+	if err != nil {
+		logger.Printf("Failed to encode headings for page %d: %v", pageID, err)
+		return
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE pages SET title = COALESCE(NULLIF($1, ''), title), description = NULLIF($2, ''), canonical_url = NULLIF($3, ''),
+			last_updated = $4, headings = $5
+		WHERE id = $6
+	`, metadata.Title, metadata.Description, metadata.CanonicalURL, metadata.LastUpdated, headings, pageID)
+	if err != nil {
+		logger.Printf("Failed to save metadata for page %d: %v", pageID, err)
+	}
+}
+
+// recordPageNavigation stores the breadcrumbs and sidebar of a page for the navigation tree
+func recordPageNavigation(ctx context.Context, db dbExecutor, logger *log.Logger, pageID int, navigation types.PageNavigation) {
+	breadcrumbs, err := json.Marshal(navigation.Breadcrumbs)
+	if err != nil {
+		logger.Printf("Failed to encode breadcrumbs for page %d: %v", pageID, err)
+		return
+	}
+	sidebar, err := json.Marshal(navigation.Sidebar)
+	if err != nil {
+		logger.Printf("Failed to encode sidebar for page %d: %v", pageID, err)
+		return
+	}
+	_, err = db.Exec(ctx, "UPDATE pages SET breadcrumbs = $1, sidebar = $2 WHERE id = $3", breadcrumbs, sidebar, pageID)
+	if err != nil {
+		logger.Printf("Failed to save navigation for page %d: %v", pageID, err)
+	}
+}
+
 func HandlePagesWithoutMarkdownContent(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests
@@ -700,7 +865,14 @@ func HandlePagesWithoutMarkdownContent(logger *log.Logger, pgxConn *pgxpool.Pool
 		}
 
 		// Get values from urls table where markdown_content is null
-		rows, err := pgxConn.Query(r.Context(), "SELECT pages.id, html_content, url, urls.id FROM pages JOIN urls ON pages.url_id = urls.id WHERE markdown_content IS NULL")
+		rows, err := pgxConn.Query(r.Context(), `
+			SELECT pages.id, html_content, url, urls.id, COALESCE(pages.title, ''), COALESCE(documentation_sources.version_pattern, ''),
+				COALESCE(documentation_sources.content_selector, ''), COALESCE(documentation_sources.remove_selectors, '{}')
+			FROM pages
+			JOIN urls ON pages.url_id = urls.id
+			LEFT JOIN documentation_sources ON urls.source_id = documentation_sources.id
+			WHERE markdown_content IS NULL
+		`)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to query URLs: %v", err), http.StatusInternalServerError)
 			return
@@ -708,36 +880,76 @@ func HandlePagesWithoutMarkdownContent(logger *log.Logger, pgxConn *pgxpool.Pool
 		defer rows.Close()
 
 		totalRows := 0
+		imageClient := helpers.NewPublicClient(30 * time.Second)
 
 		for rows.Next() {
 			var pageID int
 			var htmlContent string
 			var url string
 			var urlID int
-			err = rows.Scan(&pageID, &htmlContent, &url, &urlID)
+			var title string
+			var versionPattern string
+			var contentSelector string
+			var removeSelectors []string
+			err = rows.Scan(&pageID, &htmlContent, &url, &urlID, &title, &versionPattern, &contentSelector, &removeSelectors)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Failed to scan URL: %v", err), http.StatusInternalServerError)
 				return
 			}
 
-			// Convert HTML content to Markdown
-			markdownContent, err := ConvertHTMLToMarkdown(htmlContent, url)
+			htmlContent, err = loadPageHTML(logger, supabaseURL, supabaseStorageBucket, htmlContent)
+			if err != nil {
+				logger.Printf("Failed to load html for page %d: %v", pageID, err)
+				continue
+			}
+			metadata := helpers.ExtractPageMetadata(htmlContent, url)
+			if metadata.Title != "" {
+				title = metadata.Title
+			}
+
+			rules, err := helpers.NewExtractionRules(contentSelector, removeSelectors)
+			if err != nil {
+				logger.Printf("Ignoring extraction rules for page %d: %v", pageID, err)
+				rules = helpers.ExtractionRules{}
+			}
+
+			content, extraction := extractPageContent(htmlContent, rules)
+			content = capturePageImages(r.Context(), logger, pgxConn, imageClient, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, url, content)
+
+			cleanedMarkdownContent, err := convertPageContent(content, url, extraction)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Failed to convert HTML to Markdown: %v", err), http.StatusInternalServerError)
 				return
 			}
 
-			logger.Printf("Successfully converted HTML to Markdown: %s\n\n%s\n\n", url, markdownContent)
+			logger.Printf("Successfully converted HTML to Markdown: %s\n\n%s\n\n", url, cleanedMarkdownContent)
+
+			recordPageDiagrams(r.Context(), pgxConn, logger, pageID, cleanedMarkdownContent)
+
+			// Prefer the outline of the converted content, which follows the source's extraction rules
+			if headings := helpers.GetHeadingsFromMarkdown(cleanedMarkdownContent); len(headings) > 0 {
+				metadata.Headings = headings
+			}
+			metadata.Title = title
+			recordPageMetadata(r.Context(), pgxConn, logger, pageID, metadata)
+			recordPageNavigation(r.Context(), pgxConn, logger, pageID, helpers.ExtractPageNavigation(htmlContent, url))
+
+			language := helpers.DetectPageLanguage(htmlContent, url, cleanedMarkdownContent)
+			docsVersion := helpers.DetectDocsVersion(url, versionPattern)
 
-			cleanedMarkdownContent := CleanMarkdown(markdownContent)
 			// Add markdown to storage
-			err = helpers.SaveFileToStorageFromLocalFile(r.Context(), logger, supabaseURL, supabaseStorageBucket, fmt.Sprintf("%d/%d/page.md", urlID, pageID), cleanedMarkdownContent, supabaseAnonKey)
+			_, err = savePageMarkdown(r.Context(), logger, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, cleanedMarkdownContent, types.PageFrontMatter{
+				SourceURL:   url,
+				Title:       title,
+				Language:    language,
+				DocsVersion: docsVersion,
+			})
 			if err != nil {
 				logger.Printf("Failed to save page content to storage for %d: %v", pageID, err)
 				continue
 			}
 
-			_, err = pgxConn.Exec(r.Context(), "UPDATE pages SET markdown_content = $1 WHERE id = $2", fmt.Sprintf("%d/%d/page.md", urlID, pageID), pageID)
+			_, err = pgxConn.Exec(r.Context(), "UPDATE pages SET markdown_content = $1, language = NULLIF($2, ''), docs_version = NULLIF($3, '') WHERE id = $4", fmt.Sprintf("%d/%d/page.md", urlID, pageID), language, docsVersion, pageID)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Failed to update page %d: %v", pageID, err), http.StatusInternalServerError)
 				return
@@ -751,99 +963,192 @@ func HandlePagesWithoutMarkdownContent(logger *log.Logger, pgxConn *pgxpool.Pool
 	}
 }
 
-type ChunkMetadata struct {
-	SourceURL string   `json:"source_url"`
-	ChunkPath []string `json:"chunk_path"`
-	HasCode   bool     `json:"has_code"`
-	Text      string   `json:"text"`
-	Index     int      `json:"index"`
+// renderPageMarkdown converts a page to markdown after applying the source's extraction rules.
+// It also returns how the content was extracted.
+func renderPageMarkdown(htmlContent string, pageURL string, rules helpers.ExtractionRules) (string, string, error) {
+	content, extraction := extractPageContent(htmlContent, rules)
+	markdownContent, err := convertPageContent(content, pageURL, extraction)
+	if err != nil {
+		return "", "", err
+	}
+	return markdownContent, extraction, nil
 }
 
-type Chunk struct {
-	ID        int           `json:"id"`
-	PageID    int           `json:"page_id"`
-	Text      string        `json:"text"`
-	Embedding []float32     `json:"vector_embedding"`
-	Metadata  ChunkMetadata `json:"metadata"`
-	CreatedAt time.Time     `json:"created_at"`
+// extractPageContent returns the main content of a page, or the whole document when none is found
+func extractPageContent(htmlContent string, rules helpers.ExtractionRules) (string, string) {
+	content, extraction := helpers.ExtractContent(htmlContent, rules)
+	if extraction == "" {
+		return htmlContent, extractionFullDocument
+	}
+	return content, extraction
 }
 
-func HandleChunkingUnProcessedPages(logger *log.Logger, pgxConn *pgxpool.Pool, ragToolsServiceClient pb.MarkdownChunkerServiceClient, supabaseURL string, supabaseStorageBucket string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Only allow POST requests
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+// convertPageContent converts extracted content to markdown. Whole documents have their navigation
+// stripped heuristically since it could not be left out before conversion.
+func convertPageContent(content string, pageURL string, extraction string) (string, error) {
+	markdownContent, err := helpers.ConvertHTMLToMarkdown(content, pageURL)
+	if err != nil {
+		return "", err
+	}
+	if extraction == extractionFullDocument {
+		return CleanMarkdown(markdownContent), nil
+	}
+	return markdownContent, nil
+}
+
+// extractionFullDocument is reported when no main content was found and the whole page was converted
+const extractionFullDocument = "full_document"
+
+// Limits on the images copied from one page, since a whole document can reference hundreds.
+// Images past them are recorded but keep linking to the original.
+const (
+	maxPageImages     = 50
+	maxPageImageBytes = 50 << 20
+)
+
+// capturePageImages copies the images of the HTML content into the storage bucket under the page's
+// prefix, records them in page_images and returns the content with image sources pointing at the
+// copies. Images that cannot be downloaded keep linking to the original.
+func capturePageImages(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, client *http.Client, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string, urlID int, pageID int, pageURL string, content string) string {
+	replacements := captureImages(ctx, logger, pgxConn, client, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, helpers.GetImagesFromHTML(content, pageURL))
+	return helpers.ReplaceImageSources(content, pageURL, replacements)
+}
+
+// captureMarkdownImages is capturePageImages for pages that are scraped as markdown
+func captureMarkdownImages(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, client *http.Client, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string, urlID int, pageID int, pageURL string, markdown string) string {
+	replacements := captureImages(ctx, logger, pgxConn, client, supabaseURL, supabaseAnonKey, supabaseStorageBucket, urlID, pageID, helpers.GetImagesFromMarkdown(markdown, pageURL))
+	return helpers.ReplaceMarkdownImageSources(markdown, pageURL, replacements)
+}
+
+// captureImages copies up to maxPageImages images and maxPageImageBytes bytes into the storage
+// bucket, records every image in page_images, and returns the public URLs of the copies by source URL
+func captureImages(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, client *http.Client, supabaseURL string, supabaseAnonKey string, supabaseStorageBucket string, urlID int, pageID int, images []types.PageImage) map[string]string {
+	replacements := make(map[string]string)
+	copiedBytes := 0
+	skipped := 0
+	for i, image := range images {
+		if i >= maxPageImages || copiedBytes >= maxPageImageBytes {
+			skipped++
+		} else {
+			imageContent, contentType, err := helpers.DownloadImage(ctx, client, image.SourceURL)
+			switch {
+			case err != nil:
+				logger.Printf("Failed to download image %s for page %d: %v", image.SourceURL, pageID, err)
+			case copiedBytes+len(imageContent) > maxPageImageBytes:
+				skipped++
+			default:
+				storagePath := helpers.ImageStoragePath(urlID, pageID, image.SourceURL, contentType)
+				err = helpers.SaveBytesToStorage(ctx, logger, supabaseURL, supabaseStorageBucket, storagePath, imageContent, contentType, supabaseAnonKey)
+				if err != nil {
+					logger.Printf("Failed to save image %s for page %d: %v", image.SourceURL, pageID, err)
+				} else {
+					copiedBytes += len(imageContent)
+					image.StoragePath = storagePath
+					image.ContentType = contentType
+					replacements[image.SourceURL] = helpers.GetPublicStorageURL(supabaseURL, supabaseStorageBucket, storagePath)
+				}
+			}
 		}
 
-		// Get values from urls table where markdown_content is null
-		rows, err := pgxConn.Query(r.Context(), "SELECT pages.id, markdown_content, url FROM pages JOIN urls ON pages.url_id = urls.id WHERE processed_at IS NULL")
+		_, err := pgxConn.Exec(ctx, `
+			INSERT INTO page_images (page_id, source_url, storage_path, alt_text, caption, content_type)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+			ON CONFLICT (page_id, source_url) DO UPDATE SET
+				storage_path = COALESCE(EXCLUDED.storage_path, page_images.storage_path),
+				alt_text = EXCLUDED.alt_text,
+				caption = EXCLUDED.caption,
+				content_type = COALESCE(EXCLUDED.content_type, page_images.content_type)
+		`, pageID, image.SourceURL, image.StoragePath, image.AltText, image.Caption, image.ContentType)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to query URLs: %v", err), http.StatusInternalServerError)
-			return
+			logger.Printf("Failed to record image %s for page %d: %v", image.SourceURL, pageID, err)
 		}
-		defer rows.Close()
+	}
+	if skipped > 0 {
+		logger.Printf("Did not copy %d of the %d images of page %d, which are limited to %d images and %d bytes", skipped, len(images), pageID, maxPageImages, maxPageImageBytes)
+	}
+	return replacements
+}
 
-		var chunksToWrite []Chunk
+// recordPageDiagrams replaces the diagram records of a page with the diagram sources of its markdown
+func recordPageDiagrams(ctx context.Context, pgxConn *pgxpool.Pool, logger *log.Logger, pageID int, markdownContent string) {
+	_, err := pgxConn.Exec(ctx, "DELETE FROM page_diagrams WHERE page_id = $1", pageID)
+	if err != nil {
+		logger.Printf("Failed to clear diagrams for page %d: %v", pageID, err)
+		return
+	}
+	for _, diagram := range helpers.GetDiagramsFromMarkdown(markdownContent) {
+		_, err := pgxConn.Exec(ctx, "INSERT INTO page_diagrams (page_id, diagram_type, source, position) VALUES ($1, $2, $3, $4)", pageID, diagram.Type, diagram.Source, diagram.Position)
+		if err != nil {
+			logger.Printf("Failed to save %s diagram for page %d: %v", diagram.Type, pageID, err)
+		}
+	}
+}
 
-		var pageIDs []int
-		for rows.Next() {
-			var id int
-			var markdownPath string
-			var url string
-			err = rows.Scan(&id, &markdownPath, &url)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to scan URL: %v", err), http.StatusInternalServerError)
-				return
-			}
+// loadPageHTML returns the page HTML, reading it from storage when html_content holds a storage path
+func loadPageHTML(logger *log.Logger, supabaseURL string, supabaseStorageBucket string, htmlContent string) (string, error) {
+	if !strings.HasSuffix(htmlContent, "/page.html") {
+		return htmlContent, nil
+	}
+	return helpers.GetFileContentFromStorage(logger, supabaseURL, supabaseStorageBucket, htmlContent)
+}
 
-			markdownContent, err := helpers.GetFileContentFromStorage(logger, supabaseURL, supabaseStorageBucket, markdownPath)
-			if err != nil {
-				logger.Printf("Failed to read markdown content for %s: %v", url, err)
-				continue
-			}
+type Chunk struct {
+	ID          int                 `json:"id"`
+	PageID      int                 `json:"page_id"`
+	Language    *string             `json:"language"`
+	DocsVersion *string             `json:"docs_version"`
+	Text        string              `json:"text"`
+	TokenCount  int                 `json:"token_count"`
+	Embedding   []float32           `json:"vector_embedding"`
+	Metadata    types.ChunkMetadata `json:"metadata"`
+	CreatedAt   time.Time           `json:"created_at"`
+	// StrategyVersion identifies the chunking strategy and settings that made the chunk
+	StrategyVersion string `json:"strategy_version"`
+	// SimHash finds near-duplicates of the chunk among the chunks of its source
+	SimHash int64 `json:"simhash"`
+
+	// section is the index of the chunk's section among the sections being written
+	section int
+}
 
-			chunks, err := ragToolsServiceClient.ChunkMarkdown(r.Context(), &pb.ChunkMarkdownRequest{
-				Content:   markdownContent,
-				ChunkSize: 1000,
-				Overlap:   200,
-			})
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to chunk markdown: %v", err), http.StatusInternalServerError)
-				return
-			}
+// loadBoilerplateFingerprints returns the fingerprints of the boilerplate blocks found in a source
+func loadBoilerplateFingerprints(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) (map[string]bool, error) {
+	rows, err := pgxConn.Query(ctx, "SELECT fingerprint FROM boilerplate_blocks WHERE source_id = $1", sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-			for i, chunk := range chunks.Chunks {
-				chunkPath := GetMarkdownPath(logger, markdownContent, chunk)
-				logger.Printf("Chunk path: %v", chunkPath)
-
-				chunksToWrite = append(chunksToWrite, Chunk{
-					PageID: id,
-					Text:   chunk,
-					Metadata: ChunkMetadata{
-						SourceURL: url,
-						ChunkPath: chunkPath,
-						HasCode:   ChunkHasCode(chunk),
-						Text:      chunk,
-						Index:     i,
-					},
-					CreatedAt: time.Now(),
-				})
-			}
+	fingerprints := make(map[string]bool)
+	for rows.Next() {
+		var fingerprint string
+		if err := rows.Scan(&fingerprint); err != nil {
+			return nil, err
+		}
+		fingerprints[fingerprint] = true
+	}
+	return fingerprints, rows.Err()
+}
 
-			pageIDs = append(pageIDs, id)
+func HandleChunkingUnProcessedPages(logger *log.Logger, pgxConn *pgxpool.Pool, markdownChunker chunker.Chunker, supabaseURL string, supabaseStorageBucket string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-			// logger.Printf("Successfully chunked markdown: %s into %d chunks\n\n%s\n\n", url, len(chunks.Chunks), strings.Join(chunks.Chunks, "\n\n"))
-			logger.Printf("Successfully chunked markdown: %s originally %d bytes into %d chunks\n\n", url, len(markdownContent), len(chunks.Chunks))
+		// Read every page first so no query is open while pages are chunked
+		pages, err := loadPagesToChunk(r.Context(), pgxConn, "processed_at IS NULL")
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to query URLs: %v", err), http.StatusInternalServerError)
+			return
 		}
 
-		// Write the chunks to the database
-		for _, chunk := range chunksToWrite {
-			_, err = pgxConn.Exec(r.Context(), "INSERT INTO chunks (page_id, text, metadata, created_at) VALUES ($1, $2, $3, $4)", chunk.PageID, chunk.Text, chunk.Metadata, chunk.CreatedAt)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to insert chunk: %v", err), http.StatusInternalServerError)
-				return
-			}
+		pageIDs, chunkCount, err := chunkPages(r.Context(), logger, pgxConn, markdownChunker, supabaseURL, supabaseStorageBucket, pages, false)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to write chunks: %v", err), http.StatusInternalServerError)
+			return
 		}
 
 		// Update the pages table to set processed_at to the current time
@@ -855,11 +1160,13 @@ func HandleChunkingUnProcessedPages(logger *log.Logger, pgxConn *pgxpool.Pool, r
 		}
 
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "Successfully chunked and wrote %d chunks", len(chunksToWrite))
+		fmt.Fprintf(w, "Successfully chunked and wrote %d chunks", chunkCount)
 	}
 }
 
-func HandleSaveEmbeddings(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKey string) http.HandlerFunc {
+// HandleSaveEmbeddings embeds the chunks that have no embedding of a model, with the model of the
+// request's model value or, when it has none, with every configured model
+func HandleSaveEmbeddings(logger *log.Logger, pgxConn *pgxpool.Pool, embedders *embedder.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests
 		if r.Method != http.MethodPost {
@@ -867,91 +1174,46 @@ func HandleSaveEmbeddings(logger *log.Logger, pgxConn *pgxpool.Pool, geminiApiKe
 			return
 		}
 
-		logger.Println("Generating embeddings for chunks")
-
-		// Get chunks from database where vector_embedding is null
-		rows, err := pgxConn.Query(r.Context(), "SELECT id, text FROM chunks WHERE vector_embedding IS NULL")
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to query chunks: %v", err), http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-
-		totalRows := 0
-
-		for rows.Next() {
-			var id int
-			var text string
-			err = rows.Scan(&id, &text)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to scan chunk: %v", err), http.StatusInternalServerError)
+		textEmbedders := embedders.All()
+		if model := r.FormValue("model"); model != "" {
+			textEmbedder, ok := embedders.Get(model)
+			if !ok {
+				http.Error(w, fmt.Sprintf("%v: %s", errUnknownModel, model), http.StatusBadRequest)
 				return
 			}
+			textEmbedders = []embedder.Embedder{textEmbedder}
+		}
 
-			logger.Printf("Generating embedding for chunk %d", id)
+		// Every model is embedded with even when another fails, and the failures are reported together
+		totalSaved := 0
+		var errs []error
+		for _, textEmbedder := range textEmbedders {
+			logger.Printf("Generating embeddings for chunks with %s", textEmbedder.Model())
 
-			embedding, err := helpers.GenerateGeminiEmbedding(geminiApiKey, text, "gemini-embedding-exp-03-07", helpers.TaskTypeRetrievalDocument)
+			// Pending chunks are embedded by the re-chunk that wrote them
+			chunks, err := loadChunksToEmbed(r.Context(), pgxConn, textEmbedder.Model(), "NOT pending")
 			if err != nil {
-				if strings.Contains(err.Error(), "429") {
-					logger.Printf("Rate limit exceeded, sleeping for 60 seconds before retrying chunk %d", id)
-					time.Sleep(60 * time.Second)
-
-					// Retry the same chunk after waiting
-					embedding, err = helpers.GenerateGeminiEmbedding(geminiApiKey, text, "gemini-embedding-exp-03-07", helpers.TaskTypeRetrievalDocument)
-					if err != nil {
-						logger.Printf("Failed to generate embedding after retry: %v", err)
-						http.Error(w, fmt.Sprintf("Failed to generate embedding after retry: %v", err), http.StatusInternalServerError)
-						return
-					}
-				} else {
-					http.Error(w, fmt.Sprintf("Failed to generate embedding: %v", err), http.StatusInternalServerError)
-					return
-				}
-			}
-
-			// Convert []float32 to a string in PostgreSQL vector format
-			vectorStr := "["
-			for i, v := range embedding {
-				if i > 0 {
-					vectorStr += ","
-				}
-				vectorStr += fmt.Sprintf("%f", v)
+				logger.Printf("Failed to query chunks to embed with %s: %v", textEmbedder.Model(), err)
+				errs = append(errs, fmt.Errorf("failed to query chunks to embed with %s: %w", textEmbedder.Model(), err))
+				continue
 			}
-			vectorStr += "]"
 
-			_, err = pgxConn.Exec(r.Context(), "UPDATE chunks SET vector_embedding = $1::vector WHERE id = $2", vectorStr, id)
+			saved, err := embedChunks(r.Context(), logger, pgxConn, textEmbedder, chunks)
+			totalSaved += saved
 			if err != nil {
-				logger.Printf("Failed to update chunk %d with error: %v", id, err)
-				http.Error(w, fmt.Sprintf("Failed to update chunk: %v", err), http.StatusInternalServerError)
-				return
+				// Saved batches are kept, and the next run embeds the rest
+				logger.Printf("Saved %d of %d embeddings with %s: %v", saved, len(chunks), textEmbedder.Model(), err)
+				errs = append(errs, fmt.Errorf("saved %d of %d embeddings with %s: %w", saved, len(chunks), textEmbedder.Model(), err))
 			}
-
-			totalRows++
+		}
+		if err := errors.Join(errs...); err != nil {
+			http.Error(w, fmt.Sprintf("Saved %d embeddings, but %d of %d models failed:\n%v", totalSaved, len(errs), len(textEmbedders), err), http.StatusInternalServerError)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-		logger.Printf("Successfully generated and saved %d embeddings", totalRows)
-	}
-}
-
-func ConvertHTMLToMarkdown(htmlContent, urlString string) (string, error) {
-	url, err := url.Parse(urlString)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse URL: %v", err)
+		logger.Printf("Successfully generated and saved %d embeddings", totalSaved)
 	}
-
-	domain := fmt.Sprintf("%s://%s", url.Scheme, url.Host)
-
-	markdown, err := htmltomarkdown.ConvertString(
-		htmlContent,
-		converter.WithDomain(domain),
-	)
-
-	if err != nil {
-		return "", fmt.Errorf("failed to convert HTML to Markdown: %v", err)
-	}
-
-	return markdown, nil
 }
 
 func CleanMarkdown(markdownContent string) string {
@@ -1018,66 +1280,3 @@ func CleanMarkdown(markdownContent string) string {
 
 	return strings.Join(cleanedLines, "\n")
 }
-
-func GetMarkdownPath(logger *log.Logger, markdownContent, chunk string) []string {
-	// Find the position of the chunk in the markdown content
-	chunkPos := strings.Index(markdownContent, chunk)
-	if chunkPos == -1 {
-		logger.Printf("Chunk not found in markdown content")
-		return []string{}
-	}
-
-	// Extract the content before the chunk
-	contentBeforeChunk := markdownContent[:chunkPos]
-
-	// Split the content into lines
-	lines := strings.Split(contentBeforeChunk, "\n")
-
-	// Initialize variables to track headers and their levels
-	var path []string
-	headerLevels := make([]int, 0)
-
-	// Process each line to find headers
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-		if len(trimmedLine) == 0 {
-			continue
-		}
-
-		// Check if the line is a header (starts with #)
-		if trimmedLine[0] == '#' {
-			// Count the number of # characters
-			level := 0
-			for i := 0; i < len(trimmedLine) && trimmedLine[i] == '#'; i++ {
-				level++
-			}
-
-			// Extract the header text
-			headerText := ""
-			if level < len(trimmedLine) && trimmedLine[level] == ' ' {
-				headerText = strings.TrimSpace(trimmedLine[level:])
-			}
-
-			if headerText != "" {
-				// Remove headers of equal or higher level
-				for len(headerLevels) > 0 && headerLevels[len(headerLevels)-1] >= level {
-					headerLevels = headerLevels[:len(headerLevels)-1]
-					path = path[:len(path)-1]
-				}
-
-				// Add the new header to the path
-				headerLevels = append(headerLevels, level)
-				path = append(path, strings.Repeat("#", level)+" "+headerText)
-			}
-		}
-	}
-
-	return path
-}
-
-// TODO: fix issue where code blocks are getting chunked in the middle of the code block / code block is not being closed
-func ChunkHasCode(chunk string) bool {
-	// Check if the chunk contains any code blocks
-	codeBlock := regexp.MustCompile("```(.*)```")
-	return codeBlock.MatchString(chunk)
-}
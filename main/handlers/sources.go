@@ -0,0 +1,968 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+	"github.com/itsmaleen/tech-doc-processor/helpers"
+	"github.com/itsmaleen/tech-doc-processor/types"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+// linkCheckConcurrency bounds the number of URLs checked at the same time
+const linkCheckConcurrency = 8
+
+// storageReadConcurrency bounds the number of files read from storage at the same time
+const storageReadConcurrency = 8
+
+// sourceIDFromPath reads the {id} path value of the /api/sources/{id}/... routes
+func sourceIDFromPath(r *http.Request) (int, error) {
+	sourceID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid source id %q", r.PathValue("id"))
+	}
+	return sourceID, nil
+}
+
+// HandleCheckSourceLinks re-checks every indexed page and internal link of a source,
+// tombstoning pages that have disappeared and restoring pages that have come back
+func HandleCheckSourceLinks(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Indexed pages and the targets of their internal links
+		rows, err := pgxConn.Query(r.Context(), `
+			SELECT urls.url FROM pages JOIN urls ON pages.url_id = urls.id WHERE urls.source_id = $1
+			UNION
+			SELECT page_links.target_url FROM page_links
+			JOIN pages ON page_links.page_id = pages.id
+			JOIN urls ON pages.url_id = urls.id
+			WHERE urls.source_id = $1
+		`, sourceID)
+		if err != nil {
+			logger.Printf("Failed to query links for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to query links", http.StatusInternalServerError)
+			return
+		}
+
+		var linksToCheck []string
+		for rows.Next() {
+			var link string
+			if err := rows.Scan(&link); err != nil {
+				rows.Close()
+				http.Error(w, fmt.Sprintf("Failed to scan link: %v", err), http.StatusInternalServerError)
+				return
+			}
+			linksToCheck = append(linksToCheck, link)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			logger.Printf("Failed to read links for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to read links", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Printf("Checking %d links for source %d", len(linksToCheck), sourceID)
+
+		summary := types.LinkCheckSummary{SourceID: sourceID}
+		var summaryMu sync.Mutex
+
+		client := helpers.NewLinkCheckClient(30 * time.Second)
+		group, ctx := errgroup.WithContext(r.Context())
+		group.SetLimit(linkCheckConcurrency)
+		for _, link := range linksToCheck {
+			group.Go(func() error {
+				statusCode, checkErr := helpers.CheckLink(ctx, client, link)
+				fetchError := helpers.FetchErrorMessage(checkErr)
+
+				// Results are kept out of urls, so checked link targets are not queued for crawling
+				_, err := pgxConn.Exec(ctx, `
+					INSERT INTO link_checks (source_id, url, status_code, fetch_error, checked_at)
+					VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)
+					ON CONFLICT (source_id, url) DO UPDATE SET status_code = EXCLUDED.status_code, fetch_error = EXCLUDED.fetch_error, checked_at = EXCLUDED.checked_at
+				`, sourceID, link, statusCode, fetchError, time.Now())
+				if err != nil {
+					return fmt.Errorf("failed to record status for %s: %w", link, err)
+				}
+
+				summaryMu.Lock()
+				defer summaryMu.Unlock()
+				summary.Checked++
+				switch helpers.LinkProblem(statusCode, fetchError) {
+				case "":
+				case helpers.LinkProblemUnreachable:
+					summary.Unreachable++
+				case helpers.LinkProblemRedirectLoop:
+					summary.RedirectLoop++
+				default:
+					summary.Broken++
+				}
+				return nil
+			})
+		}
+		if err := group.Wait(); err != nil {
+			logger.Printf("Failed to check links for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to check links", http.StatusInternalServerError)
+			return
+		}
+
+		removed, restored, err := tombstoneRemovedPages(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to tombstone removed pages for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to tombstone removed pages", http.StatusInternalServerError)
+			return
+		}
+		summary.Removed = removed
+		summary.Restored = restored
+
+		logger.Printf("Checked %d links for source %d: %d broken, %d pages removed, %d restored", summary.Checked, sourceID, summary.Broken, removed, restored)
+		helpers.Encode(w, r, http.StatusOK, summary)
+	}
+}
+
+// tombstoneRemovedPages marks pages whose URL is gone as removed so they are no longer served,
+// and clears the tombstone of pages that resolve again
+func tombstoneRemovedPages(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) (int, int, error) {
+	removed, err := pgxConn.Exec(ctx, `
+		UPDATE pages SET removed_at = $2
+		FROM urls JOIN link_checks ON link_checks.source_id = urls.source_id AND link_checks.url = urls.url
+		WHERE pages.url_id = urls.id AND urls.source_id = $1 AND link_checks.status_code IN (404, 410) AND pages.removed_at IS NULL
+	`, sourceID, time.Now())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	restored, err := pgxConn.Exec(ctx, `
+		UPDATE pages SET removed_at = NULL
+		FROM urls JOIN link_checks ON link_checks.source_id = urls.source_id AND link_checks.url = urls.url
+		WHERE pages.url_id = urls.id AND urls.source_id = $1 AND link_checks.status_code < 400 AND pages.removed_at IS NOT NULL
+	`, sourceID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(removed.RowsAffected()), int(restored.RowsAffected()), nil
+}
+
+// HandleSourceLinkReport returns the broken internal links and removed pages of a source as JSON or CSV
+func HandleSourceLinkReport(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET requests
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			http.Error(w, "Format must be json or csv", http.StatusBadRequest)
+			return
+		}
+
+		report, err := buildLinkReport(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to build link report for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to build link report", http.StatusInternalServerError)
+			return
+		}
+
+		if format == "json" {
+			helpers.Encode(w, r, http.StatusOK, report)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"source-%d-link-report.csv\"", sourceID))
+		w.WriteHeader(http.StatusOK)
+
+		csvWriter := csv.NewWriter(w)
+		csvWriter.Write([]string{"type", "page_url", "target_url", "status_code", "problem", "error", "date"})
+		for _, link := range report.BrokenLinks {
+			csvWriter.Write([]string{"broken_link", link.PageURL, link.TargetURL, statusCodeString(link.StatusCode), link.Problem, link.Error, link.CheckedAt})
+		}
+		for _, page := range report.RemovedPages {
+			csvWriter.Write([]string{"removed_page", page.URL, "", statusCodeString(page.StatusCode), helpers.LinkProblemNotFound, "", page.RemovedAt})
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			logger.Printf("Failed to write link report csv: %v", err)
+		}
+	}
+}
+
+func buildLinkReport(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) (types.LinkReport, error) {
+	report := types.LinkReport{
+		SourceID:     sourceID,
+		GeneratedAt:  time.Now().Format(time.RFC3339),
+		BrokenLinks:  []types.BrokenLink{},
+		RemovedPages: []types.RemovedPage{},
+	}
+
+	rows, err := pgxConn.Query(ctx, `
+		SELECT from_urls.url, page_links.target_url, COALESCE(link_checks.status_code, 0), COALESCE(link_checks.fetch_error, ''), link_checks.checked_at
+		FROM page_links
+		JOIN pages ON page_links.page_id = pages.id
+		JOIN urls AS from_urls ON pages.url_id = from_urls.id
+		JOIN link_checks ON link_checks.source_id = from_urls.source_id AND link_checks.url = page_links.target_url
+		WHERE from_urls.source_id = $1
+			AND pages.removed_at IS NULL
+			AND (link_checks.status_code >= 400 OR link_checks.fetch_error IS NOT NULL)
+		ORDER BY from_urls.url, page_links.target_url
+	`, sourceID)
+	if err != nil {
+		return report, fmt.Errorf("failed to query broken links: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link types.BrokenLink
+		var checkedAt time.Time
+		if err := rows.Scan(&link.PageURL, &link.TargetURL, &link.StatusCode, &link.Error, &checkedAt); err != nil {
+			return report, fmt.Errorf("failed to scan broken link: %w", err)
+		}
+		link.Problem = helpers.LinkProblem(link.StatusCode, link.Error)
+		link.CheckedAt = checkedAt.Format(time.RFC3339)
+		report.BrokenLinks = append(report.BrokenLinks, link)
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to read broken links: %w", err)
+	}
+
+	rows, err = pgxConn.Query(ctx, `
+		SELECT pages.id, urls.url, COALESCE(pages.title, ''), COALESCE(link_checks.status_code, urls.status_code, 0), pages.removed_at
+		FROM pages JOIN urls ON pages.url_id = urls.id
+		LEFT JOIN link_checks ON link_checks.source_id = urls.source_id AND link_checks.url = urls.url
+		WHERE urls.source_id = $1 AND pages.removed_at IS NOT NULL
+		ORDER BY pages.removed_at DESC
+	`, sourceID)
+	if err != nil {
+		return report, fmt.Errorf("failed to query removed pages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var page types.RemovedPage
+		var removedAt time.Time
+		if err := rows.Scan(&page.PageID, &page.URL, &page.Title, &page.StatusCode, &removedAt); err != nil {
+			return report, fmt.Errorf("failed to scan removed page: %w", err)
+		}
+		page.RemovedAt = removedAt.Format(time.RFC3339)
+		report.RemovedPages = append(report.RemovedPages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to read removed pages: %w", err)
+	}
+
+	return report, nil
+}
+
+func statusCodeString(statusCode int) string {
+	if statusCode == 0 {
+		return ""
+	}
+	return strconv.Itoa(statusCode)
+}
+
+// HandleSourceSettings returns the settings of a source on GET and updates the submitted settings on POST
+func HandleSourceSettings(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			err := r.ParseForm()
+			if err != nil {
+				http.Error(w, "Failed to parse form", http.StatusBadRequest)
+				return
+			}
+
+			if r.Form.Has("allowed_languages") {
+				// An empty list clears the setting so every language is allowed
+				allowedLanguages := helpers.NormalizeLanguageList(r.FormValue("allowed_languages"))
+				_, err = pgxConn.Exec(r.Context(), "UPDATE documentation_sources SET allowed_languages = $1, updated_at = $2 WHERE id = $3", allowedLanguages, time.Now(), sourceID)
+				if err != nil {
+					logger.Printf("Failed to update allowed languages for source %d: %v", sourceID, err)
+					http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if r.Form.Has("version_pattern") {
+				versionPattern := strings.TrimSpace(r.FormValue("version_pattern"))
+				if _, err := regexp.Compile(versionPattern); err != nil {
+					http.Error(w, fmt.Sprintf("Invalid version pattern: %v", err), http.StatusBadRequest)
+					return
+				}
+				_, err = pgxConn.Exec(r.Context(), "UPDATE documentation_sources SET version_pattern = NULLIF($1, ''), updated_at = $2 WHERE id = $3", versionPattern, time.Now(), sourceID)
+				if err != nil {
+					logger.Printf("Failed to update version pattern for source %d: %v", sourceID, err)
+					http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if r.Form.Has("default_version") {
+				defaultVersion := strings.ToLower(strings.TrimSpace(r.FormValue("default_version")))
+				_, err = pgxConn.Exec(r.Context(), "UPDATE documentation_sources SET default_version = NULLIF($1, ''), updated_at = $2 WHERE id = $3", defaultVersion, time.Now(), sourceID)
+				if err != nil {
+					logger.Printf("Failed to update default version for source %d: %v", sourceID, err)
+					http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if r.Form.Has("boilerplate_threshold") {
+				// An empty value restores the default threshold
+				threshold, err := boilerplateThresholdFromForm(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				_, err = pgxConn.Exec(r.Context(), "UPDATE documentation_sources SET boilerplate_threshold = NULLIF($1, 0), updated_at = $2 WHERE id = $3", threshold, time.Now(), sourceID)
+				if err != nil {
+					logger.Printf("Failed to update boilerplate threshold for source %d: %v", sourceID, err)
+					http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if r.Form.Has("chunk_strategy") || r.Form.Has("chunk_size") || r.Form.Has("chunk_overlap") {
+				source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+				if err != nil {
+					logger.Printf("Failed to get source %d: %v", sourceID, err)
+					http.Error(w, "Source not found", http.StatusNotFound)
+					return
+				}
+				// Empty values restore the defaults. Existing chunks keep their settings until the source is re-chunked.
+				strategy, chunkSize, chunkOverlap, err := chunkSettingsFromForm(r, source)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				updates := []struct {
+					field string
+					query string
+					value any
+				}{
+					{"chunk_strategy", "UPDATE documentation_sources SET chunk_strategy = NULLIF($1, ''), updated_at = $2 WHERE id = $3", strategy},
+					{"chunk_size", "UPDATE documentation_sources SET chunk_size = $1, updated_at = $2 WHERE id = $3", chunkSize},
+					{"chunk_overlap", "UPDATE documentation_sources SET chunk_overlap = $1, updated_at = $2 WHERE id = $3", chunkOverlap},
+				}
+				for _, update := range updates {
+					if !r.Form.Has(update.field) {
+						continue
+					}
+					_, err = pgxConn.Exec(r.Context(), update.query, update.value, time.Now(), sourceID)
+					if err != nil {
+						logger.Printf("Failed to update chunk settings for source %d: %v", sourceID, err)
+						http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+						return
+					}
+				}
+			}
+
+			if r.Form.Has("content_selector") || r.Form.Has("remove_selectors") {
+				source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+				if err != nil {
+					logger.Printf("Failed to get source %d: %v", sourceID, err)
+					http.Error(w, "Source not found", http.StatusNotFound)
+					return
+				}
+				contentSelector, removeSelectors := selectorsFromForm(r, source.ContentSelector, source.RemoveSelectors)
+				if _, err := helpers.NewExtractionRules(contentSelector, removeSelectors); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				_, err = pgxConn.Exec(r.Context(), "UPDATE documentation_sources SET content_selector = NULLIF($1, ''), remove_selectors = $2, updated_at = $3 WHERE id = $4", contentSelector, removeSelectors, time.Now(), sourceID)
+				if err != nil {
+					logger.Printf("Failed to update selectors for source %d: %v", sourceID, err)
+					http.Error(w, "Failed to update source settings", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to get source %d: %v", sourceID, err)
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+
+		helpers.Encode(w, r, http.StatusOK, source)
+	}
+}
+
+// selectorsFromForm reads content_selector and remove_selectors from a request, keeping the current
+// values for fields that are not submitted. Remove selectors can be repeated or separated by newlines,
+// since a single selector may contain commas.
+func selectorsFromForm(r *http.Request, contentSelector string, removeSelectors []string) (string, []string) {
+	if r.Form.Has("content_selector") {
+		contentSelector = strings.TrimSpace(r.Form.Get("content_selector"))
+	}
+	if r.Form.Has("remove_selectors") {
+		removeSelectors = []string{}
+		for _, value := range r.Form["remove_selectors"] {
+			for _, selector := range strings.Split(value, "\n") {
+				if selector = strings.TrimSpace(selector); selector != "" {
+					removeSelectors = append(removeSelectors, selector)
+				}
+			}
+		}
+	}
+	return contentSelector, removeSelectors
+}
+
+// boilerplateThresholdFromForm reads the boilerplate_threshold percentage from a request, or 0 when it is empty
+func boilerplateThresholdFromForm(r *http.Request) (int, error) {
+	value := strings.TrimSpace(strings.TrimSuffix(r.FormValue("boilerplate_threshold"), "%"))
+	if value == "" {
+		return 0, nil
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 1 || threshold > 100 {
+		return 0, fmt.Errorf("boilerplate threshold must be a percentage between 1 and 100")
+	}
+	return threshold, nil
+}
+
+// rechunkingSources holds the ids of the sources being re-chunked
+var rechunkingSources sync.Map
+
+// backgroundJobs tracks the re-chunks running after their request returned
+var backgroundJobs sync.WaitGroup
+
+// WaitForBackgroundJobs waits until the background re-chunks have stopped, so the server can close
+// the database connection after they have cleaned up
+func WaitForBackgroundJobs() {
+	backgroundJobs.Wait()
+}
+
+// HandleRechunkSource chunks every page of a source again with its current chunk settings and embeds
+// the new chunks in the background. The source's current chunks keep answering queries until all of
+// the new ones are embedded, and are then replaced at once. Only pages that have been chunked before
+// are re-chunked, so pages still waiting for their first chunks are left to the chunking endpoint.
+// The re-chunk stops when ctx, which lasts as long as the server, is done.
+func HandleRechunkSource(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, markdownChunker chunker.Chunker, embedders *embedder.Registry, supabaseURL string, supabaseStorageBucket string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := getDocumentationSource(r.Context(), pgxConn, sourceID); err != nil {
+			logger.Printf("Failed to get source %d: %v", sourceID, err)
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+
+		pages, err := loadPagesToChunk(r.Context(), pgxConn, "urls.source_id = $1 AND markdown_content IS NOT NULL AND processed_at IS NOT NULL", sourceID)
+		if err != nil {
+			logger.Printf("Failed to load pages of source %d: %v", sourceID, err)
+			http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+			return
+		}
+
+		if _, running := rechunkingSources.LoadOrStore(sourceID, true); running {
+			http.Error(w, "Source is already being re-chunked", http.StatusConflict)
+			return
+		}
+		_, strategy := sourceStrategy(r.Context(), logger, pgxConn, markdownChunker, sourceID)
+
+		// The job outlives the request, so it runs until the server stops rather than until the request ends
+		backgroundJobs.Add(1)
+		go func() {
+			defer backgroundJobs.Done()
+			defer rechunkingSources.Delete(sourceID)
+			if err := rechunkSource(ctx, logger, pgxConn, markdownChunker, embedders, supabaseURL, supabaseStorageBucket, pages); err != nil {
+				logger.Printf("Failed to re-chunk source %d: %v", sourceID, err)
+				return
+			}
+			logger.Printf("Re-chunked %d pages of source %d with %s", len(pages), sourceID, strategy.Version())
+		}()
+
+		helpers.Encode(w, r, http.StatusAccepted, types.RechunkJob{
+			SourceID:        sourceID,
+			StrategyVersion: strategy.Version(),
+			PageCount:       len(pages),
+		})
+	}
+}
+
+// rechunkSource writes pending chunks for pages, embeds them and then replaces the pages' current
+// chunks by them. When a step fails the pending chunks are removed and the current ones stay.
+func rechunkSource(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, markdownChunker chunker.Chunker, embedders *embedder.Registry, supabaseURL string, supabaseStorageBucket string, pages []pageToChunk) (err error) {
+	pageIDs := make([]int, 0, len(pages))
+	for _, page := range pages {
+		pageIDs = append(pageIDs, page.id)
+	}
+
+	// Pending chunks left by a re-chunk that failed are removed first
+	if err := deletePendingChunks(ctx, pgxConn, pageIDs); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// The pending chunks are removed even when the re-chunk stopped because ctx is done
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+			defer cancel()
+			if cleanupErr := deletePendingChunks(cleanupCtx, pgxConn, pageIDs); cleanupErr != nil {
+				logger.Printf("Failed to remove pending chunks: %v", cleanupErr)
+			}
+		}
+	}()
+
+	chunkedIDs, chunkCount, err := chunkPages(ctx, logger, pgxConn, markdownChunker, supabaseURL, supabaseStorageBucket, pages, true)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Embedding %d pending chunks of %d pages", chunkCount, len(chunkedIDs))
+	if err := embedPendingChunks(ctx, logger, pgxConn, embedders, chunkedIDs); err != nil {
+		return err
+	}
+	// Pages that could not be chunked keep their current chunks
+	return promotePendingChunks(ctx, pgxConn, chunkedIDs)
+}
+
+// chunkSettingsFromForm reads chunk_strategy, chunk_size and chunk_overlap from a request, keeping
+// the source's values for fields that are not submitted. Sizes are nil when they are empty, and an
+// overlap of 0 means chunks do not overlap.
+func chunkSettingsFromForm(r *http.Request, source types.DocumentationSource) (string, *int, *int, error) {
+	strategy := source.ChunkStrategy
+	if r.Form.Has("chunk_strategy") {
+		strategy = strings.ToLower(strings.TrimSpace(r.FormValue("chunk_strategy")))
+		if strategy != "" && strategy != chunker.StrategyRecursive && strategy != chunker.StrategyStructured {
+			return "", nil, nil, fmt.Errorf("chunk strategy must be %s or %s", chunker.StrategyRecursive, chunker.StrategyStructured)
+		}
+	}
+
+	sizes := []*int{&source.ChunkSize, &source.ChunkOverlap}
+	for i, field := range []struct {
+		name    string
+		minimum int
+		message string
+	}{
+		{"chunk_size", 1, "chunk size must be a positive number of tokens"},
+		{"chunk_overlap", 0, "chunk overlap must be a number of tokens, or 0 for none"},
+	} {
+		if !r.Form.Has(field.name) {
+			continue
+		}
+		value := strings.TrimSpace(r.FormValue(field.name))
+		if value == "" {
+			sizes[i] = nil
+			continue
+		}
+		size, err := strconv.Atoi(value)
+		if err != nil || size < field.minimum {
+			return "", nil, nil, errors.New(field.message)
+		}
+		sizes[i] = &size
+	}
+
+	chunkSize, chunkOverlap := sizes[0], sizes[1]
+	size, overlap := chunker.DefaultChunkSize, chunker.DefaultOverlap
+	if chunkSize != nil {
+		size = *chunkSize
+	}
+	if chunkOverlap != nil {
+		overlap = *chunkOverlap
+	}
+	if overlap >= size {
+		return "", nil, nil, fmt.Errorf("chunk overlap %d must be smaller than the chunk size %d", overlap, size)
+	}
+	return strategy, chunkSize, chunkOverlap, nil
+}
+
+// HandlePreviewSourceContent fetches one URL and returns the markdown it would be saved as with the
+// source's extraction rules, without saving anything. content_selector and remove_selectors in the
+// query override the saved rules so they can be tried before saving them.
+func HandlePreviewSourceContent(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET requests
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = r.ParseForm()
+		if err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+
+		pageURL := r.Form.Get("url")
+		parsedURL, err := url.Parse(pageURL)
+		if pageURL == "" || err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			http.Error(w, "A valid http(s) url is required", http.StatusBadRequest)
+			return
+		}
+
+		source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to get source %d: %v", sourceID, err)
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+		if !helpers.SameHost(pageURL, source.URL) {
+			http.Error(w, fmt.Sprintf("The url must be on the source's host %s", source.URL), http.StatusBadRequest)
+			return
+		}
+
+		contentSelector, removeSelectors := selectorsFromForm(r, source.ContentSelector, source.RemoveSelectors)
+		rules, err := helpers.NewExtractionRules(contentSelector, removeSelectors)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		htmlContent, err := fetchPageHTML(r.Context(), pageURL)
+		if errors.Is(err, helpers.ErrPrivateAddress) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.Printf("Failed to fetch %s for preview: %v", pageURL, err)
+			http.Error(w, fmt.Sprintf("Failed to fetch page: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		markdownContent, extraction, err := renderPageMarkdown(htmlContent, pageURL, rules)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to convert HTML to Markdown: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		helpers.Encode(w, r, http.StatusOK, types.ContentPreview{
+			URL:             pageURL,
+			ContentSelector: contentSelector,
+			RemoveSelectors: removeSelectors,
+			Extraction:      extraction,
+			Markdown:        markdownContent,
+		})
+	}
+}
+
+// maxPreviewBytes is the largest page the preview downloads
+const maxPreviewBytes = 10 << 20
+
+// fetchPageHTML downloads a page for previewing. The URL comes from the caller, so only public
+// addresses are fetched.
+func fetchPageHTML(ctx context.Context, pageURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := helpers.NewPublicClient(30 * time.Second).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	body, err := helpers.ReadLimited(resp.Body, maxPreviewBytes)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// HandleSourceNavTree returns the navigation tree of a source's pages
+func HandleSourceNavTree(logger *log.Logger, pgxConn *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET requests
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to get source %d: %v", sourceID, err)
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+
+		pages, err := loadNavPages(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to load pages of source %d: %v", sourceID, err)
+			http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+			return
+		}
+
+		root := helpers.BuildNavTree(pages)
+		if root.PageID == 0 && source.Name != "" {
+			root.Title = source.Name
+		}
+
+		helpers.Encode(w, r, http.StatusOK, types.NavTree{
+			SourceID:    sourceID,
+			GeneratedAt: time.Now().Format(time.RFC3339),
+			Root:        root,
+		})
+	}
+}
+
+// loadNavPages loads the live pages of a source with their captured navigation, in crawl order
+func loadNavPages(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) ([]types.NavPage, error) {
+	rows, err := pgxConn.Query(ctx, `
+		SELECT pages.id, urls.url, COALESCE(pages.title, ''), COALESCE(pages.breadcrumbs, '[]'), COALESCE(pages.sidebar, '[]')
+		FROM pages JOIN urls ON pages.url_id = urls.id
+		WHERE urls.source_id = $1 AND pages.removed_at IS NULL
+		ORDER BY pages.id
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pages: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []types.NavPage
+	for rows.Next() {
+		var page types.NavPage
+		if err := rows.Scan(&page.ID, &page.URL, &page.Title, &page.Navigation.Breadcrumbs, &page.Navigation.Sidebar); err != nil {
+			return nil, fmt.Errorf("failed to scan page: %w", err)
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pages: %w", err)
+	}
+	return pages, nil
+}
+
+// HandleSourceBoilerplate returns the boilerplate blocks of a source on GET. On POST it fingerprints the
+// blocks of every page of the source and replaces them with the blocks repeated on more than the
+// threshold percent of pages. A threshold in the form overrides the source setting. The blocks are left
+// out of pages chunked afterwards.
+func HandleSourceBoilerplate(logger *log.Logger, pgxConn *pgxpool.Pool, supabaseURL string, supabaseStorageBucket string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sourceID, err := sourceIDFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		source, err := getDocumentationSource(r.Context(), pgxConn, sourceID)
+		if err != nil {
+			logger.Printf("Failed to get source %d: %v", sourceID, err)
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			report := types.BoilerplateReport{SourceID: sourceID, Threshold: source.BoilerplateThreshold}
+			report.Blocks, err = loadBoilerplateBlocks(r.Context(), pgxConn, sourceID)
+			if err != nil {
+				logger.Printf("Failed to load boilerplate blocks for source %d: %v", sourceID, err)
+				http.Error(w, "Failed to load boilerplate blocks", http.StatusInternalServerError)
+				return
+			}
+			helpers.Encode(w, r, http.StatusOK, report)
+			return
+		}
+
+		threshold, err := boilerplateThresholdFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if threshold == 0 {
+			threshold = source.BoilerplateThreshold
+		}
+
+		pages, err := loadSourceMarkdown(r.Context(), logger, pgxConn, supabaseURL, supabaseStorageBucket, sourceID)
+		if err != nil {
+			logger.Printf("Failed to load pages of source %d: %v", sourceID, err)
+			http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+			return
+		}
+
+		report := types.BoilerplateReport{
+			SourceID:     sourceID,
+			Threshold:    threshold,
+			PagesScanned: len(pages),
+			Blocks:       helpers.FindBoilerplateBlocks(pages, threshold),
+		}
+
+		tx, err := pgxConn.Begin(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to save boilerplate blocks", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(r.Context())
+
+		if _, err := tx.Exec(r.Context(), "DELETE FROM boilerplate_blocks WHERE source_id = $1", sourceID); err != nil {
+			logger.Printf("Failed to clear boilerplate blocks for source %d: %v", sourceID, err)
+			http.Error(w, "Failed to save boilerplate blocks", http.StatusInternalServerError)
+			return
+		}
+		for _, block := range report.Blocks {
+			_, err := tx.Exec(r.Context(), "INSERT INTO boilerplate_blocks (source_id, fingerprint, sample, page_count) VALUES ($1, $2, $3, $4)", sourceID, block.Fingerprint, block.Sample, block.PageCount)
+			if err != nil {
+				logger.Printf("Failed to save boilerplate block for source %d: %v", sourceID, err)
+				http.Error(w, "Failed to save boilerplate blocks", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(r.Context()); err != nil {
+			http.Error(w, "Failed to save boilerplate blocks", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Printf("Found %d boilerplate blocks in %d pages of source %d", len(report.Blocks), len(pages), sourceID)
+		helpers.Encode(w, r, http.StatusOK, report)
+	}
+}
+
+// loadSourceMarkdown reads the stored markdown body of every live page of a source. Pages that
+// cannot be read are logged and left out, so they are not counted among the pages scanned.
+func loadSourceMarkdown(ctx context.Context, logger *log.Logger, pgxConn *pgxpool.Pool, supabaseURL string, supabaseStorageBucket string, sourceID int) ([]string, error) {
+	rows, err := pgxConn.Query(ctx, `
+		SELECT markdown_content FROM pages JOIN urls ON pages.url_id = urls.id
+		WHERE urls.source_id = $1 AND pages.removed_at IS NULL AND markdown_content IS NOT NULL
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pages: %w", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan page: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pages: %w", err)
+	}
+
+	bodies := make([]string, len(paths))
+	read := make([]bool, len(paths))
+	group := errgroup.Group{}
+	group.SetLimit(storageReadConcurrency)
+	for i, path := range paths {
+		group.Go(func() error {
+			storedMarkdown, err := helpers.GetFileContentFromStorage(logger, supabaseURL, supabaseStorageBucket, path)
+			if err != nil {
+				logger.Printf("Skipping page %s of source %d: failed to read markdown content: %v", path, sourceID, err)
+				return nil
+			}
+			_, bodies[i], _ = helpers.ParseFrontMatter(storedMarkdown)
+			read[i] = true
+			return nil
+		})
+	}
+	group.Wait()
+
+	pages := make([]string, 0, len(bodies))
+	for i, body := range bodies {
+		if read[i] {
+			pages = append(pages, body)
+		}
+	}
+	return pages, nil
+}
+
+// loadBoilerplateBlocks returns the boilerplate blocks saved for a source, most repeated first
+func loadBoilerplateBlocks(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) ([]types.BoilerplateBlock, error) {
+	rows, err := pgxConn.Query(ctx, "SELECT fingerprint, sample, page_count FROM boilerplate_blocks WHERE source_id = $1 ORDER BY page_count DESC, fingerprint", sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query boilerplate blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []types.BoilerplateBlock{}
+	for rows.Next() {
+		var block types.BoilerplateBlock
+		if err := rows.Scan(&block.Fingerprint, &block.Sample, &block.PageCount); err != nil {
+			return nil, fmt.Errorf("failed to scan boilerplate block: %w", err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, rows.Err()
+}
+
+func getDocumentationSource(ctx context.Context, pgxConn *pgxpool.Pool, sourceID int) (types.DocumentationSource, error) {
+	var source types.DocumentationSource
+	err := pgxConn.QueryRow(ctx, `
+		SELECT id, source_url, COALESCE(source_name, ''), COALESCE(allowed_languages, '{}'), COALESCE(version_pattern, ''), COALESCE(default_version, ''),
+			COALESCE(content_selector, ''), COALESCE(remove_selectors, '{}'), COALESCE(boilerplate_threshold, $2),
+			COALESCE(chunk_strategy, ''), COALESCE(chunk_size, $3), COALESCE(chunk_overlap, $4)
+		FROM documentation_sources WHERE id = $1
+	`, sourceID, helpers.DefaultBoilerplateThreshold, chunker.DefaultChunkSize, chunker.DefaultOverlap).Scan(&source.ID, &source.URL, &source.Name, &source.AllowedLanguages, &source.VersionPattern, &source.DefaultVersion,
+		&source.ContentSelector, &source.RemoveSelectors, &source.BoilerplateThreshold, &source.ChunkStrategy, &source.ChunkSize, &source.ChunkOverlap)
+	if err != nil {
+		return source, fmt.Errorf("failed to get documentation source: %w", err)
+	}
+	return source, nil
+}
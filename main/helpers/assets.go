@@ -0,0 +1,287 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+	"golang.org/x/net/html"
+)
+
+// maxImageSize is the largest image downloaded into the storage bucket
+const maxImageSize = 10 << 20
+
+// Diagram types stored in page_diagrams
+const (
+	DiagramMermaid  = "mermaid"
+	DiagramPlantUML = "plantuml"
+)
+
+// imageExtensions maps image content types to the extension of the stored copy
+var imageExtensions = map[string]string{
+	"image/png": ".png", "image/jpeg": ".jpg", "image/gif": ".gif", "image/webp": ".webp",
+	"image/svg+xml": ".svg", "image/avif": ".avif", "image/x-icon": ".ico", "image/bmp": ".bmp",
+}
+
+// diagramLanguages maps the info string of a fenced code block to its diagram type
+var diagramLanguages = map[string]string{
+	"mermaid": DiagramMermaid, "plantuml": DiagramPlantUML, "puml": DiagramPlantUML, "uml": DiagramPlantUML,
+}
+
+var fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// markdownImageRegex matches ![alt](src "title") with an optional title and angle brackets around src
+var markdownImageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^\s<>()]+)>?(?:\s+"([^"]*)")?\s*\)`)
+
+// GetImagesFromHTML returns the images of the content with absolute URLs, their alt text and the
+// caption of the figure they are in. Inline data URIs are skipped.
+func GetImagesFromHTML(htmlContent string, pageURL string) []types.PageImage {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var images []types.PageImage
+	for _, img := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "img" }) {
+		imageURL := resolveImageURL(img, pageURL)
+		if imageURL == "" || seen[imageURL] {
+			continue
+		}
+		seen[imageURL] = true
+		images = append(images, types.PageImage{
+			SourceURL: imageURL,
+			AltText:   strings.TrimSpace(getAttr(img, "alt")),
+			Caption:   figureCaption(img),
+		})
+	}
+	return images
+}
+
+// ReplaceImageSources resolves the image sources of the content against the page URL and points the
+// ones in replacements, keyed by absolute URL, at our copies
+func ReplaceImageSources(htmlContent string, pageURL string, replacements map[string]string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return htmlContent
+	}
+
+	for _, img := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "img" }) {
+		imageURL := resolveImageURL(img, pageURL)
+		if imageURL == "" {
+			continue
+		}
+		if replacement, ok := replacements[imageURL]; ok {
+			imageURL = replacement
+		}
+		setAttr(img, "src", imageURL)
+		removeAttr(img, "srcset")
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return htmlContent
+	}
+	return buf.String()
+}
+
+// GetImagesFromMarkdown returns the images of markdown outside code blocks with absolute URLs, their
+// alt text, and their title as the caption. Inline data URIs are skipped.
+func GetImagesFromMarkdown(markdown string, pageURL string) []types.PageImage {
+	seen := make(map[string]bool)
+	var images []types.PageImage
+	mapMarkdownImages(markdown, func(match []string) string {
+		imageURL := resolveImageSource(match[2], pageURL)
+		if imageURL != "" && !seen[imageURL] {
+			seen[imageURL] = true
+			images = append(images, types.PageImage{
+				SourceURL: imageURL,
+				AltText:   strings.TrimSpace(match[1]),
+				Caption:   strings.TrimSpace(match[3]),
+			})
+		}
+		return match[0]
+	})
+	return images
+}
+
+// ReplaceMarkdownImageSources resolves the image sources of markdown against the page URL and points
+// the ones in replacements, keyed by absolute URL, at our copies
+func ReplaceMarkdownImageSources(markdown string, pageURL string, replacements map[string]string) string {
+	return mapMarkdownImages(markdown, func(match []string) string {
+		imageURL := resolveImageSource(match[2], pageURL)
+		if imageURL == "" {
+			return match[0]
+		}
+		if replacement, ok := replacements[imageURL]; ok {
+			imageURL = replacement
+		}
+		if match[3] != "" {
+			return fmt.Sprintf("![%s](%s \"%s\")", match[1], imageURL, match[3])
+		}
+		return fmt.Sprintf("![%s](%s)", match[1], imageURL)
+	})
+}
+
+// mapMarkdownImages replaces each image of markdown outside fenced code blocks with what replace
+// returns for its markdownImageRegex submatches
+func mapMarkdownImages(markdown string, replace func(match []string) string) string {
+	lines := strings.Split(markdown, "\n")
+	fence := ""
+	for i, line := range lines {
+		if fence != "" {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+				fence = ""
+			}
+			continue
+		}
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence = match[1]
+			continue
+		}
+		lines[i] = markdownImageRegex.ReplaceAllStringFunc(line, func(image string) string {
+			return replace(markdownImageRegex.FindStringSubmatch(image))
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolveImageSource returns the absolute http(s) URL of an image source without its fragment, or ""
+// for data URIs and other schemes
+func resolveImageSource(src string, pageURL string) string {
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	resolved, err := base.Parse(src)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+// resolveImageURL returns the absolute URL of an image, preferring the lazy-loading data-src
+// attribute when src only holds a placeholder
+func resolveImageURL(img *html.Node, pageURL string) string {
+	src := strings.TrimSpace(getAttr(img, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		src = strings.TrimSpace(getAttr(img, "data-src"))
+	}
+	return resolveImageSource(src, pageURL)
+}
+
+// figureCaption returns the <figcaption> text of the figure an image is in
+func figureCaption(img *html.Node) string {
+	for p := img.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "figure" {
+			caption := findFirst(p, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "figcaption" })
+			if caption == nil {
+				return ""
+			}
+			return innerText(caption)
+		}
+	}
+	return ""
+}
+
+// DownloadImage fetches an image, rejecting responses that are not images or are too large
+func DownloadImage(ctx context.Context, client *http.Client, imageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	content, err := ReadLimited(resp.Body, maxImageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(content))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+	return content, contentType, nil
+}
+
+// ImageStoragePath returns where the copy of an image is stored: {url_id}/{page_id}/images/<hash>.<ext>
+func ImageStoragePath(urlID int, pageID int, imageURL string, contentType string) string {
+	hash := sha256.Sum256([]byte(imageURL))
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		extension = ".bin"
+		if parsedURL, err := url.Parse(imageURL); err == nil && path.Ext(parsedURL.Path) != "" {
+			extension = strings.ToLower(path.Ext(parsedURL.Path))
+		}
+	}
+	return fmt.Sprintf("%d/%d/images/%s%s", urlID, pageID, hex.EncodeToString(hash[:8]), extension)
+}
+
+// GetDiagramsFromMarkdown returns the Mermaid and PlantUML sources of the fenced code blocks in markdown
+func GetDiagramsFromMarkdown(markdown string) []types.PageDiagram {
+	var diagrams []types.PageDiagram
+	lines := strings.Split(markdown, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := fenceRegex.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		fence, language := match[1], strings.ToLower(match[2])
+
+		var source []string
+		j := i + 1
+		for ; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+				break
+			}
+			source = append(source, lines[j])
+		}
+		i = j
+
+		body := strings.TrimSpace(strings.Join(source, "\n"))
+		diagramType, ok := diagramLanguages[language]
+		if !ok && strings.HasPrefix(body, "@startuml") {
+			diagramType, ok = DiagramPlantUML, true
+		}
+		if ok && body != "" {
+			diagrams = append(diagrams, types.PageDiagram{Type: diagramType, Source: body, Position: len(diagrams)})
+		}
+	}
+	return diagrams
+}
+
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, attr := range n.Attr {
+		if attr.Key != key {
+			attrs = append(attrs, attr)
+		}
+	}
+	n.Attr = attrs
+}
@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+func TestGetImagesFromHTML(t *testing.T) {
+	content := `<div>
+<figure><img src="img/arch.png" alt=" Architecture overview "><figcaption>Figure 1: How <em>requests</em> flow</figcaption></figure>
+<p><img src="data:image/gif;base64,R0lGOD" data-src="/assets/lazy.webp" alt=""></p>
+<p><img src="https://cdn.example.com/logo.svg#dark" alt="Logo"><img src="img/arch.png" alt="duplicate"></p>
+<p><img src="data:image/png;base64,iVBOR" alt="inline"></p>
+</div>`
+
+	want := []types.PageImage{
+		{SourceURL: "https://docs.example.com/guide/img/arch.png", AltText: "Architecture overview", Caption: "Figure 1: How requests flow"},
+		{SourceURL: "https://docs.example.com/assets/lazy.webp"},
+		{SourceURL: "https://cdn.example.com/logo.svg", AltText: "Logo"},
+	}
+	got := GetImagesFromHTML(content, "https://docs.example.com/guide/page")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetImagesFromHTML() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplaceImageSources(t *testing.T) {
+	content := `<p><img src="img/arch.png" srcset="img/arch@2x.png 2x" alt="Architecture"><img src="other.png"></p>`
+	got := ReplaceImageSources(content, "https://docs.example.com/guide/page", map[string]string{
+		"https://docs.example.com/guide/img/arch.png": "https://storage.example.com/1/2/images/abc.png",
+	})
+
+	for _, want := range []string{`src="https://storage.example.com/1/2/images/abc.png"`, `src="https://docs.example.com/guide/other.png"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ReplaceImageSources() = %s, want it to contain %s", got, want)
+		}
+	}
+	if strings.Contains(got, "srcset") {
+		t.Errorf("ReplaceImageSources() = %s, want srcset removed", got)
+	}
+}
+
+func TestGetImagesFromMarkdown(t *testing.T) {
+	markdown := "# Guide\n\n![ Architecture ](img/arch.png \"How requests flow\") and ![Logo](<https://cdn.example.com/logo.svg#dark>)\n\n" +
+		"```md\n![not an image](in-code.png)\n```\n\n![inline](data:image/png;base64,iVBOR) ![again](img/arch.png)\n"
+
+	want := []types.PageImage{
+		{SourceURL: "https://docs.example.com/guide/img/arch.png", AltText: "Architecture", Caption: "How requests flow"},
+		{SourceURL: "https://cdn.example.com/logo.svg", AltText: "Logo"},
+	}
+	got := GetImagesFromMarkdown(markdown, "https://docs.example.com/guide/page")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetImagesFromMarkdown() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplaceMarkdownImageSources(t *testing.T) {
+	markdown := "![Architecture](img/arch.png \"Overview\") ![Other](other.png)\n\n```md\n![Code](img/arch.png)\n```"
+	got := ReplaceMarkdownImageSources(markdown, "https://docs.example.com/guide/page", map[string]string{
+		"https://docs.example.com/guide/img/arch.png": "https://storage.example.com/1/2/images/abc.png",
+	})
+
+	want := "![Architecture](https://storage.example.com/1/2/images/abc.png \"Overview\") ![Other](https://docs.example.com/guide/other.png)\n\n```md\n![Code](img/arch.png)\n```"
+	if got != want {
+		t.Errorf("ReplaceMarkdownImageSources() = %q, want %q", got, want)
+	}
+}
+
+func TestImageStoragePath(t *testing.T) {
+	got := ImageStoragePath(12, 34, "https://docs.example.com/img/arch.png", "image/png")
+	if !strings.HasPrefix(got, "12/34/images/") || !strings.HasSuffix(got, ".png") {
+		t.Errorf("ImageStoragePath() = %s", got)
+	}
+	if other := ImageStoragePath(12, 34, "https://docs.example.com/img/other.png", "image/png"); other == got {
+		t.Errorf("ImageStoragePath() returned %s for two different images", got)
+	}
+	if got := ImageStoragePath(1, 2, "https://docs.example.com/img/photo.JPEG?v=2", "application/octet-stream"); !strings.HasSuffix(got, ".jpeg") {
+		t.Errorf("ImageStoragePath() = %s, want the URL's extension", got)
+	}
+}
+
+func TestGetDiagramsFromMarkdown(t *testing.T) {
+	markdown := "# Flow\n\n```mermaid\ngraph TD;\n  A-->B;\n```\n\n```go\nfmt.Println(\"not a diagram\")\n```\n\n" +
+		"~~~~\n@startuml\nAlice -> Bob\n@enduml\n~~~~\n\n```plantuml\n```\n\n```puml\nclass Widget\n```\n"
+
+	want := []types.PageDiagram{
+		{Type: DiagramMermaid, Source: "graph TD;\n  A-->B;", Position: 0},
+		{Type: DiagramPlantUML, Source: "@startuml\nAlice -> Bob\n@enduml", Position: 1},
+		{Type: DiagramPlantUML, Source: "class Widget", Position: 2},
+	}
+	got := GetDiagramsFromMarkdown(markdown)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDiagramsFromMarkdown() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// DefaultBoilerplateThreshold is the share of a source's pages, in percent, a block must appear on
+// to be boilerplate when the source has no threshold of its own
+const DefaultBoilerplateThreshold = 30
+
+// minBoilerplatePages keeps small sources from marking every shared block as boilerplate
+const minBoilerplatePages = 3
+
+// minBoilerplateBlockLength is the normalized length below which blocks are too generic to fingerprint
+const minBoilerplateBlockLength = 16
+
+var (
+	markdownLinkTargetRegex = regexp.MustCompile(`(!?\[[^\]]*\])\([^)]*\)`)
+	blockHeadingRegex       = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+)
+
+// MarkdownBlocks splits markdown into the blocks separated by blank lines. Fenced code blocks are
+// kept whole. Each block is returned as its start and end offset in markdown.
+func MarkdownBlocks(markdown string) [][2]int {
+	var blocks [][2]int
+	start, end := -1, 0
+	fence := ""
+	offset := 0
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			if fence == "" {
+				fence = match[1]
+			} else if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+		}
+		if strings.TrimSpace(line) != "" {
+			if start < 0 {
+				start = offset
+			}
+			end = offset + len(strings.TrimRight(line, "\r\n"))
+		} else if fence == "" && start >= 0 {
+			blocks = append(blocks, [2]int{start, end})
+			start = -1
+		}
+		offset += len(line)
+	}
+	if start >= 0 {
+		blocks = append(blocks, [2]int{start, end})
+	}
+	return blocks
+}
+
+// BlockFingerprint returns the fingerprint of a markdown block, or "" when the block is a heading or
+// too short to be told apart from ordinary text. Link targets are ignored since boilerplate such
+// as "Edit this page" links differs per page.
+func BlockFingerprint(block string) string {
+	if headingLevel(block) > 0 {
+		return ""
+	}
+	normalized := markdownLinkTargetRegex.ReplaceAllString(block, "$1")
+	normalized = strings.ToLower(strings.Join(strings.Fields(normalized), " "))
+	if len(normalized) < minBoilerplateBlockLength {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:16])
+}
+
+// FindBoilerplateBlocks returns the blocks that appear on more than threshold percent of pages, and
+// on at least three pages, most repeated first
+func FindBoilerplateBlocks(pages []string, threshold int) []types.BoilerplateBlock {
+	if threshold <= 0 {
+		threshold = DefaultBoilerplateThreshold
+	}
+	counts := make(map[string]int)
+	samples := make(map[string]string)
+	for _, page := range pages {
+		seen := make(map[string]bool)
+		for _, span := range MarkdownBlocks(page) {
+			block := page[span[0]:span[1]]
+			fingerprint := BlockFingerprint(block)
+			if fingerprint == "" || seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			counts[fingerprint]++
+			if _, ok := samples[fingerprint]; !ok {
+				samples[fingerprint] = block
+			}
+		}
+	}
+
+	blocks := []types.BoilerplateBlock{}
+	for fingerprint, count := range counts {
+		if count >= minBoilerplatePages && count*100 > threshold*len(pages) {
+			blocks = append(blocks, types.BoilerplateBlock{Fingerprint: fingerprint, Sample: samples[fingerprint], PageCount: count})
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].PageCount != blocks[j].PageCount {
+			return blocks[i].PageCount > blocks[j].PageCount
+		}
+		return blocks[i].Fingerprint < blocks[j].Fingerprint
+	})
+	return blocks
+}
+
+// RemoveBoilerplateBlocks removes the blocks with the given fingerprints from markdown, along with
+// the headings whose whole section was removed, leaving the rest of the text as it is
+func RemoveBoilerplateBlocks(markdown string, fingerprints map[string]bool) string {
+	if len(fingerprints) == 0 {
+		return markdown
+	}
+	spans := MarkdownBlocks(markdown)
+	removed := make([]bool, len(spans))
+	levels := make([]int, len(spans))
+	for i, span := range spans {
+		block := markdown[span[0]:span[1]]
+		removed[i] = fingerprints[BlockFingerprint(block)]
+		levels[i] = headingLevel(block)
+	}
+
+	// A heading goes when its section, up to the next heading of the same or a higher level, had
+	// blocks removed and has no content left
+	for i := range spans {
+		if levels[i] == 0 {
+			continue
+		}
+		removedAny, kept := false, false
+		for j := i + 1; j < len(spans) && (levels[j] == 0 || levels[j] > levels[i]); j++ {
+			if removed[j] {
+				removedAny = true
+			} else if levels[j] == 0 {
+				kept = true
+			}
+		}
+		removed[i] = removedAny && !kept
+	}
+
+	var sb strings.Builder
+	last := 0
+	for i, span := range spans {
+		if !removed[i] {
+			continue
+		}
+		sb.WriteString(markdown[last:span[0]])
+		last = span[1]
+		// Drop the blank lines that separated the removed block from the next one
+		for last < len(markdown) && (markdown[last] == '\n' || markdown[last] == '\r') {
+			last++
+		}
+	}
+	sb.WriteString(markdown[last:])
+	return sb.String()
+}
+
+// headingLevel returns the level of a block that is a single ATX heading, or 0 for other blocks
+func headingLevel(block string) int {
+	if !blockHeadingRegex.MatchString(block) || strings.Contains(strings.TrimSpace(block), "\n") {
+		return 0
+	}
+	trimmed := strings.TrimLeft(block, " ")
+	return len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+}
@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMarkdownBlocks(t *testing.T) {
+	markdown := "# Title\n\nFirst paragraph\nstill first.\n\n```sh\necho one\n\necho two\n```\n\n\nLast"
+	var blocks []string
+	for _, span := range MarkdownBlocks(markdown) {
+		blocks = append(blocks, markdown[span[0]:span[1]])
+	}
+	want := []string{"# Title", "First paragraph\nstill first.", "```sh\necho one\n\necho two\n```", "Last"}
+	if fmt.Sprint(blocks) != fmt.Sprint(want) || len(blocks) != len(want) {
+		t.Errorf("blocks = %q, want %q", blocks, want)
+	}
+}
+
+func TestFindAndRemoveBoilerplateBlocks(t *testing.T) {
+	footer := "Was this page helpful? [Yes](https://docs.example.com/feedback?page=%d) [No](https://docs.example.com/feedback?page=%d&no)"
+	license := "Content is available under the Apache 2.0 license."
+	var pages []string
+	for i := 0; i < 10; i++ {
+		page := fmt.Sprintf("# Page %d\n\nThis page explains topic number %d in detail.\n\n", i, i)
+		if i < 2 {
+			page += license + "\n\n"
+		}
+		if i < 4 {
+			page += "## Prerequisites\n\nInstall the CLI and log in before you start.\n\n"
+		}
+		page += fmt.Sprintf(footer, i, i) + "\n"
+		pages = append(pages, page)
+	}
+
+	blocks := FindBoilerplateBlocks(pages, 30)
+	if len(blocks) != 2 {
+		t.Fatalf("found %d blocks, want the footer and the prerequisites: %+v", len(blocks), blocks)
+	}
+	if blocks[0].PageCount != 10 || blocks[1].PageCount != 4 {
+		t.Errorf("page counts = %d, %d, want 10, 4", blocks[0].PageCount, blocks[1].PageCount)
+	}
+	if blocks[1].Sample != "Install the CLI and log in before you start." {
+		t.Errorf("sample = %q", blocks[1].Sample)
+	}
+	if len(FindBoilerplateBlocks(pages, 50)) != 1 {
+		t.Errorf("a 50%% threshold should only keep the footer")
+	}
+
+	fingerprints := make(map[string]bool)
+	for _, block := range blocks {
+		fingerprints[block.Fingerprint] = true
+	}
+	// The prerequisites heading goes with its section, and the page heading keeps its content
+	got := RemoveBoilerplateBlocks(pages[0], fingerprints)
+	want := "# Page 0\n\nThis page explains topic number 0 in detail.\n\n" + license + "\n\n"
+	if got != want {
+		t.Errorf("RemoveBoilerplateBlocks() = %q, want %q", got, want)
+	}
+
+	// Headings of sections with content left, or with nothing removed, stay
+	markdown := "## Setup\n\n" + blocks[1].Sample + "\n\nRun the installer.\n\n## Next steps\n\n### Empty\n\n## Help\n\n### Feedback\n\n" + fmt.Sprintf(footer, 0, 0) + "\n"
+	got = RemoveBoilerplateBlocks(markdown, fingerprints)
+	want = "## Setup\n\nRun the installer.\n\n## Next steps\n\n### Empty\n\n"
+	if got != want {
+		t.Errorf("RemoveBoilerplateBlocks() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,441 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// minMainContentLength is the amount of text a generic or scored candidate needs to count as the main content
+const minMainContentLength = 100
+
+// contentSelector matches the element a docs framework renders the page body into
+type contentSelector struct {
+	// tag is the element name, or empty for any element
+	tag string
+	// attr and value match an attribute; for "class" the value is matched against each class name
+	attr  string
+	value string
+	// ancestorClass, when set, requires an ancestor with this class name
+	ancestorClass string
+}
+
+// frameworkSelectors are checked in order before falling back to <main>, <article> and scoring
+var frameworkSelectors = []contentSelector{
+	// Docusaurus
+	{tag: "div", attr: "class", value: "theme-doc-markdown"},
+	// MkDocs Material
+	{tag: "article", attr: "class", value: "md-content__inner"},
+	// Sphinx with the Read the Docs theme, also used by MkDocs' readthedocs theme
+	{attr: "itemprop", value: "articleBody"},
+	// Sphinx basic and alabaster themes
+	{tag: "div", attr: "class", value: "body", ancestorClass: "documentwrapper"},
+	// GitBook
+	{tag: "section", attr: "class", value: "markdown-section"},
+	{attr: "data-testid", value: "page.contentEditor"},
+}
+
+// genericSelectors are the semantic elements tried after the framework selectors, tightest first
+var genericSelectors = []contentSelector{
+	{tag: "article"},
+	{tag: "main"},
+	{attr: "role", value: "main"},
+}
+
+// boilerplateTags are removed from the extracted content
+var boilerplateTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "nav": true, "aside": true,
+	"footer": true, "form": true, "button": true, "input": true, "select": true, "textarea": true, "iframe": true,
+}
+
+// boilerplateRoles are ARIA roles of page chrome
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+	"dialog": true, "alertdialog": true,
+}
+
+// boilerplateClassParts are the parts of class names, split on "-" and "_", that mark page chrome
+var boilerplateClassParts = map[string]bool{
+	"announcement": true, "announcementbar": true, "banner": true, "breadcrumb": true, "breadcrumbs": true,
+	"consent": true, "cookie": true, "cookies": true, "edit": true, "feedback": true, "footer": true, "gdpr": true,
+	"menu": true, "nav": true, "navbar": true, "pager": true, "pagination": true, "share": true, "sidebar": true,
+	"skip": true, "social": true, "sphinxsidebar": true, "tableofcontents": true, "toc": true,
+}
+
+// boilerplateNames are whole class names and ids of page chrome. Ids are matched whole because
+// headings and sections derive theirs from their text.
+var boilerplateNames = map[string]bool{
+	"cookie-banner": true, "cookie-consent": true, "edit-this-page": true, "footer": true, "header": true,
+	"navbar": true, "on-this-page": true, "sidebar": true, "table-of-contents": true, "toc": true,
+}
+
+var (
+	positiveCandidateRegex = regexp.MustCompile(`(?i)article|body|content|entry|main|markdown|page|post|prose|text|doc`)
+	negativeCandidateRegex = regexp.MustCompile(`(?i)banner|breadcrumb|comment|cookie|footer|header|menu|meta|nav|pagination|popup|related|share|sidebar|social|sponsor|toc|widget`)
+	hiddenStyleRegex       = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+)
+
+// How the content of a page was found
+const (
+	ExtractionSelector  = "selector"
+	ExtractionAutomatic = "automatic"
+)
+
+// ExtractionRules are the per-source overrides applied before the automatic extraction
+type ExtractionRules struct {
+	// ContentSelector selects the content root. When it matches nothing the automatic extraction is used.
+	ContentSelector cascadia.Matcher
+	// RemoveSelectors select elements removed from the page before extracting its content
+	RemoveSelectors []cascadia.Matcher
+}
+
+// NewExtractionRules compiles the CSS selectors of a source, which may be empty
+func NewExtractionRules(contentSelector string, removeSelectors []string) (ExtractionRules, error) {
+	var rules ExtractionRules
+	if strings.TrimSpace(contentSelector) != "" {
+		selector, err := cascadia.ParseGroup(contentSelector)
+		if err != nil {
+			return rules, fmt.Errorf("invalid content selector %q: %w", contentSelector, err)
+		}
+		rules.ContentSelector = selector
+	}
+	for _, removeSelector := range removeSelectors {
+		if strings.TrimSpace(removeSelector) == "" {
+			continue
+		}
+		selector, err := cascadia.ParseGroup(removeSelector)
+		if err != nil {
+			return rules, fmt.Errorf("invalid remove selector %q: %w", removeSelector, err)
+		}
+		rules.RemoveSelectors = append(rules.RemoveSelectors, selector)
+	}
+	return rules, nil
+}
+
+// ExtractMainContent returns the HTML of the main article of a page with navigation, headers,
+// footers, banners and tables of contents removed. It reports false when no main content is found,
+// in which case the caller should convert the whole document.
+func ExtractMainContent(htmlContent string) (string, bool) {
+	content, method := ExtractContent(htmlContent, ExtractionRules{})
+	return content, method != ""
+}
+
+// ExtractContent applies the rules of a source and then extracts the main content like
+// ExtractMainContent. It returns how the content was found, or an empty method when it was not.
+func ExtractContent(htmlContent string, rules ExtractionRules) (string, string) {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", ""
+	}
+	removeNodes(doc, func(n *html.Node) bool {
+		return n.Data == "script" || n.Data == "style" || n.Data == "noscript" || n.Data == "template"
+	})
+	for _, selector := range rules.RemoveSelectors {
+		for _, n := range cascadia.QueryAll(doc, selector) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+
+	// A configured content root is kept as is, since the source's remove selectors already cover its boilerplate
+	method := ExtractionSelector
+	var content *html.Node
+	if rules.ContentSelector != nil {
+		content = cascadia.Query(doc, rules.ContentSelector)
+	}
+	if content == nil {
+		method = ExtractionAutomatic
+		content = findMainContent(doc)
+		if content == nil {
+			return "", ""
+		}
+		removeNodes(content, isBoilerplate)
+	}
+	if textLength(content) == 0 {
+		return "", ""
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, content); err != nil {
+		return "", ""
+	}
+	return buf.String(), method
+}
+
+// findMainContent tries the docs framework selectors, then the semantic elements, then scores the DOM
+func findMainContent(doc *html.Node) *html.Node {
+	for _, selector := range frameworkSelectors {
+		if n := findFirst(doc, selector.matches); n != nil && textLength(n) > 0 {
+			return n
+		}
+	}
+
+	for _, selector := range genericSelectors {
+		matches := findAll(doc, selector.matches)
+		// Several articles usually means a listing page, so leave it to the other selectors
+		if len(matches) != 1 {
+			continue
+		}
+		if textLength(matches[0]) >= minMainContentLength {
+			return matches[0]
+		}
+	}
+
+	return findBestScoredCandidate(doc)
+}
+
+func (s contentSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (s.tag != "" && n.Data != s.tag) {
+		return false
+	}
+	if s.attr != "" {
+		value := getAttr(n, s.attr)
+		if s.attr == "class" {
+			if !hasClass(n, s.value) {
+				return false
+			}
+		} else if value != s.value {
+			return false
+		}
+	}
+	if s.ancestorClass != "" {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if hasClass(p, s.ancestorClass) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// findBestScoredCandidate scores the parents of paragraphs readability-style: longer paragraphs
+// with more commas add to their parent and, at half weight, their grandparent. Scores are then
+// adjusted by class names and link density.
+func findBestScoredCandidate(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || n.Data == "body" || n.Data == "html" {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialCandidateScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	for _, paragraph := range findAll(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && (n.Data == "p" || n.Data == "pre" || n.Data == "td")
+	}) {
+		if isBoilerplate(paragraph) || hasBoilerplateAncestor(paragraph) {
+			continue
+		}
+		text := innerText(paragraph)
+		if len(text) < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(paragraph.Parent, score)
+		if paragraph.Parent != nil {
+			addScore(paragraph.Parent.Parent, score/2)
+		}
+	}
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil || textLength(best) < minMainContentLength {
+		return nil
+	}
+	return best
+}
+
+func initialCandidateScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.Data {
+	case "div", "section":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "form", "ol", "ul", "dl", "dd", "dt", "li", "address":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	for _, name := range []string{getAttr(n, "class"), getAttr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeCandidateRegex.MatchString(name) {
+			score -= 25
+		}
+		if positiveCandidateRegex.MatchString(name) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// isBoilerplate reports whether an element is page chrome rather than article content
+func isBoilerplate(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return true
+	}
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if boilerplateTags[n.Data] {
+		return true
+	}
+	// Docs frameworks put the page title inside <header>, so only drop headers without headings
+	if n.Data == "header" && findFirst(n, isHeading) == nil {
+		return true
+	}
+	if boilerplateRoles[getAttr(n, "role")] {
+		return true
+	}
+	if boilerplateNames[strings.ToLower(getAttr(n, "id"))] && !isHeading(n) && !startsWithHeading(n) {
+		return true
+	}
+	// Docs frameworks hide the tab panels that are not selected, which hold content all the same
+	if getAttr(n, "role") != "tabpanel" {
+		for _, attr := range n.Attr {
+			if attr.Key == "hidden" || (attr.Key == "aria-hidden" && attr.Val == "true") || (attr.Key == "style" && hiddenStyleRegex.MatchString(attr.Val)) {
+				return true
+			}
+		}
+	}
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		if boilerplateNames[strings.ToLower(class)] {
+			return true
+		}
+		for _, part := range strings.FieldsFunc(strings.ToLower(class), func(r rune) bool { return r == '-' || r == '_' }) {
+			if boilerplateClassParts[part] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// startsWithHeading reports whether the first child element of n is a heading, as in a document section
+func startsWithHeading(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return isHeading(c)
+		}
+	}
+	return false
+}
+
+func hasBoilerplateAncestor(n *html.Node) bool {
+	for p := n.Parent; p != nil && p.Data != "body"; p = p.Parent {
+		if isBoilerplate(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHeading(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return true
+	}
+	return false
+}
+
+// removeNodes removes every descendant matching remove. Code blocks are kept as they are.
+func removeNodes(n *html.Node, remove func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if remove(c) {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode && c.Data != "pre" {
+			removeNodes(c, remove)
+		}
+		c = next
+	}
+}
+
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if match(c) {
+			return c
+		}
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if match(c) {
+			nodes = append(nodes, c)
+		}
+		nodes = append(nodes, findAll(c, match)...)
+	}
+	return nodes
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, name := range strings.Fields(getAttr(n, "class")) {
+		if name == class {
+			return true
+		}
+	}
+	return false
+}
+
+// innerText returns the text of a node with whitespace collapsed
+func innerText(n *html.Node) string {
+	var sb strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func textLength(n *html.Node) int {
+	return len(innerText(n))
+}
+
+// linkDensity returns the share of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+	linkText := 0
+	for _, link := range findAll(n, func(c *html.Node) bool { return c.Type == html.ElementNode && c.Data == "a" }) {
+		linkText += textLength(link)
+	}
+	return math.Min(float64(linkText)/float64(total), 1)
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestExtractMainContent compares the content extracted from each testdata/extract/*.html page
+// with its .golden file. Pages without main content have an empty golden file.
+// Run `go test ./helpers -run TestExtractMainContent -update` to regenerate them.
+func TestExtractMainContent(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "extract", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no test pages found")
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, ok := ExtractMainContent(string(input))
+			if ok {
+				content += "\n"
+			}
+
+			goldenPath := strings.TrimSuffix(page, ".html") + ".golden"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content != string(golden) {
+				t.Errorf("extracted content does not match %s\n--- got ---\n%s\n--- want ---\n%s", goldenPath, content, golden)
+			}
+		})
+	}
+}
+
+func TestExtractContentWithRules(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "extract", "docusaurus.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		contentSelector string
+		removeSelectors []string
+		wantMethod      string
+		want            []string
+		notWant         []string
+	}{
+		{
+			name:            "content selector with remove selectors",
+			contentSelector: "main article",
+			removeSelectors: []string{"nav.theme-doc-breadcrumbs", ".theme-doc-footer, .theme-doc-toc-mobile"},
+			wantMethod:      ExtractionSelector,
+			want:            []string{"<article>", "<h1>Installation</h1>", "npm install @acme/cli"},
+			notWant:         []string{"Breadcrumbs", "Edit this page", "On this page"},
+		},
+		{
+			name:            "unmatched content selector falls back to automatic extraction",
+			contentSelector: "#does-not-exist",
+			removeSelectors: []string{".theme-admonition"},
+			wantMethod:      ExtractionAutomatic,
+			want:            []string{"<h1>Installation</h1>"},
+			notWant:         []string{"--global", "Edit this page"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := NewExtractionRules(tt.contentSelector, tt.removeSelectors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, method := ExtractContent(string(input), rules)
+			if method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", method, tt.wantMethod)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(content, s) {
+					t.Errorf("content does not contain %q:\n%s", s, content)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(content, s) {
+					t.Errorf("content contains %q:\n%s", s, content)
+				}
+			}
+		})
+	}
+}
+
+func TestNewExtractionRulesInvalidSelector(t *testing.T) {
+	if _, err := NewExtractionRules("main >", nil); err == nil {
+		t.Error("expected an error for an invalid content selector")
+	}
+	if _, err := NewExtractionRules("", []string{"div[unclosed"}); err == nil {
+		t.Error("expected an error for an invalid remove selector")
+	}
+}
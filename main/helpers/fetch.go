@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a URL resolves to a loopback, private or link-local address
+var ErrPrivateAddress = errors.New("refusing to connect to a private address")
+
+// ErrTooLarge is returned when a response body is larger than allowed
+var ErrTooLarge = errors.New("response is too large")
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddress reports whether ip can be reached on the internet, so servers may fetch URLs that
+// callers choose from it
+func IsPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// NewPublicClient returns a link check client that refuses to connect to addresses that are not
+// public. The address is checked once it is resolved, so neither DNS names pointing at internal
+// hosts nor redirects to them get through.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	client := NewLinkCheckClient(timeout)
+	// No proxy, since the proxy's address is the one the dialer would check
+	client.Transport = &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return client
+}
+
+// ReadLimited reads r to the end, failing with ErrTooLarge once it is longer than maxBytes
+func ReadLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxBytes)
+	}
+	return body, nil
+}
+
+// SameHost reports whether two URLs are on the same host, ignoring a leading www.
+func SameHost(a string, b string) bool {
+	parsedA, errA := url.Parse(a)
+	parsedB, errB := url.Parse(b)
+	if errA != nil || errB != nil || parsedA.Hostname() == "" {
+		return false
+	}
+	hostA := strings.TrimPrefix(strings.ToLower(parsedA.Hostname()), "www.")
+	hostB := strings.TrimPrefix(strings.ToLower(parsedB.Hostname()), "www.")
+	return hostA == hostB
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+	for address, want := range tests {
+		if got := IsPublicAddress(netip.MustParseAddr(address)); got != want {
+			t.Errorf("IsPublicAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestPublicClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewPublicClient(5 * time.Second).Do(req)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("error = %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if body, err := ReadLimited(strings.NewReader("hello"), 5); err != nil || string(body) != "hello" {
+		t.Errorf("ReadLimited at the limit = %q, %v", body, err)
+	}
+	if _, err := ReadLimited(strings.NewReader("hello!"), 5); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	if !SameHost("https://www.acme.dev/docs/install", "https://acme.dev/docs") {
+		t.Errorf("www.acme.dev and acme.dev are not the same host")
+	}
+	if SameHost("http://169.254.169.254/latest", "https://acme.dev/docs") {
+		t.Errorf("a metadata address matched the source's host")
+	}
+	if SameHost("https://acme.dev.evil.com/", "https://acme.dev/") {
+		t.Errorf("a host ending in another one matched it")
+	}
+}
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelimiter = "---"
+
+// ContentHash returns the hash stored in the front matter of a page's markdown body
+func ContentHash(body string) string {
+	hash := sha256.Sum256([]byte(body))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// AddFrontMatter prefixes markdown with a YAML front matter block, replacing any existing one.
+// The content hash is computed from the markdown body.
+func AddFrontMatter(markdown string, frontMatter types.PageFrontMatter) (string, error) {
+	_, body, err := ParseFrontMatter(markdown)
+	if err != nil {
+		return "", err
+	}
+	frontMatter.ContentHash = ContentHash(body)
+	frontMatter.CrawledAt = frontMatter.CrawledAt.UTC()
+
+	encoded, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode front matter: %w", err)
+	}
+	return frontMatterDelimiter + "\n" + string(encoded) + frontMatterDelimiter + "\n\n" + body, nil
+}
+
+// ParseFrontMatter splits stored page markdown into its front matter and body. Markdown without a
+// front matter block, including markdown that merely starts with a thematic break, is returned as the
+// body with a nil front matter.
+func ParseFrontMatter(markdown string) (*types.PageFrontMatter, string, error) {
+	content := strings.TrimPrefix(markdown, "\ufeff")
+	lines := strings.SplitAfter(content, "\n")
+	if strings.TrimRight(lines[0], "\r\n") != frontMatterDelimiter {
+		return nil, markdown, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == frontMatterDelimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, markdown, nil
+	}
+	block := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	// A block that is not a YAML mapping is part of the document, e.g. text between two thematic breaks
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(block), &fields); err != nil || fields == nil {
+		return nil, markdown, nil
+	}
+
+	var frontMatter types.PageFrontMatter
+	if err := yaml.Unmarshal([]byte(block), &frontMatter); err != nil {
+		return nil, markdown, fmt.Errorf("failed to decode front matter: %w", err)
+	}
+	return &frontMatter, strings.TrimLeft(body, "\r\n"), nil
+}
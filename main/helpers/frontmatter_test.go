@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	body := "# Installation\n\nRun `npm install`.\n"
+	crawledAt := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	content, err := AddFrontMatter(body, types.PageFrontMatter{
+		SourceURL: "https://docs.example.com/install",
+		Title:     "Installation: getting started",
+		CrawledAt: crawledAt,
+		Language:  "en",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "---\nsource_url: https://docs.example.com/install\n") {
+		t.Errorf("unexpected front matter:\n%s", content)
+	}
+
+	frontMatter, parsedBody, err := ParseFrontMatter(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsedBody != body {
+		t.Errorf("body = %q, want %q", parsedBody, body)
+	}
+	want := types.PageFrontMatter{
+		SourceURL:   "https://docs.example.com/install",
+		Title:       "Installation: getting started",
+		CrawledAt:   crawledAt,
+		ContentHash: ContentHash(body),
+		Language:    "en",
+	}
+	if frontMatter == nil || *frontMatter != want {
+		t.Errorf("front matter = %+v, want %+v", frontMatter, want)
+	}
+
+	// Saving again replaces the block instead of nesting it
+	resaved, err := AddFrontMatter(content, types.PageFrontMatter{SourceURL: "https://docs.example.com/install", CrawledAt: crawledAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(resaved, "source_url:") != 1 {
+		t.Errorf("front matter was added twice:\n%s", resaved)
+	}
+}
+
+func TestParseFrontMatterWithoutBlock(t *testing.T) {
+	tests := []string{
+		"# Title\n\nNo front matter.",
+		"---\n\nText between thematic breaks.\n\n---\n\nMore text.",
+		"---\nnever closed: true\n",
+		"",
+	}
+	for _, markdown := range tests {
+		frontMatter, body, err := ParseFrontMatter(markdown)
+		if err != nil {
+			t.Errorf("ParseFrontMatter(%q) returned error %v", markdown, err)
+		}
+		if frontMatter != nil || body != markdown {
+			t.Errorf("ParseFrontMatter(%q) = %+v, %q, want no front matter", markdown, frontMatter, body)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Problems reported for internal links that do not resolve
+const (
+	LinkProblemNotFound     = "not_found"
+	LinkProblemClientError  = "client_error"
+	LinkProblemServerError  = "server_error"
+	LinkProblemRedirectLoop = "redirect_loop"
+	LinkProblemUnreachable  = "unreachable"
+)
+
+// ErrRedirectLoop is returned when a URL redirects back to itself or never settles
+var ErrRedirectLoop = errors.New("redirect loop")
+
+const maxRedirects = 10
+
+// NewLinkCheckClient returns an HTTP client that stops following redirects once they loop
+func NewLinkCheckClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrRedirectLoop
+			}
+			for _, previous := range via {
+				if previous.URL.String() == req.URL.String() {
+					return ErrRedirectLoop
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// CheckLink requests the URL and returns the final status code. Servers that
+// reject HEAD requests are retried with GET.
+func CheckLink(ctx context.Context, client *http.Client, linkURL string) (int, error) {
+	statusCode, err := requestStatus(ctx, client, http.MethodHead, linkURL)
+	if err != nil {
+		return 0, err
+	}
+	if statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented {
+		return requestStatus(ctx, client, http.MethodGet, linkURL)
+	}
+	return statusCode, nil
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method string, linkURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, linkURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrRedirectLoop) {
+			return 0, ErrRedirectLoop
+		}
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// FetchErrorMessage returns the message stored in fetch_error for a failed request
+func FetchErrorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrRedirectLoop) {
+		return ErrRedirectLoop.Error()
+	}
+	return err.Error()
+}
+
+// LinkProblem classifies a crawl result, returning an empty string for healthy links
+func LinkProblem(statusCode int, fetchError string) string {
+	switch {
+	case fetchError == ErrRedirectLoop.Error():
+		return LinkProblemRedirectLoop
+	case fetchError != "":
+		return LinkProblemUnreachable
+	case IsPageGone(statusCode):
+		return LinkProblemNotFound
+	case statusCode >= 500:
+		return LinkProblemServerError
+	case statusCode >= 400:
+		return LinkProblemClientError
+	}
+	return ""
+}
+
+// IsPageGone reports whether a status code means the page has been removed
+func IsPageGone(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// GetHrefsFromHTML returns the href of every link in a page's HTML as written, so they can be
+// resolved against the page URL with GetInternalLinks
+func GetHrefsFromHTML(htmlContent string) []string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+	var hrefs []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" && attr.Val != "" {
+					hrefs = append(hrefs, attr.Val)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return hrefs
+}
+
+// GetInternalLinks keeps the links on the same host as the page, without fragments or query strings
+func GetInternalLinks(pageURL string, links []string) []string {
+	parsedPageURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var internalLinks []string
+	for _, link := range links {
+		parsedLink, err := parsedPageURL.Parse(link)
+		if err != nil || parsedLink.Host != parsedPageURL.Host {
+			continue
+		}
+		parsedLink.Fragment = ""
+		parsedLink.RawQuery = ""
+		cleanedLink := parsedLink.String()
+		if !seen[cleanedLink] {
+			seen[cleanedLink] = true
+			internalLinks = append(internalLinks, cleanedLink)
+		}
+	}
+	return internalLinks
+}
+
+// DeepLink returns the page URL with its fragment set to anchor, or the page URL when there is no
+// anchor or the URL cannot be parsed
+func DeepLink(pageURL string, anchor string) string {
+	if anchor == "" {
+		return pageURL
+	}
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	parsedURL.Fragment = anchor
+	parsedURL.RawFragment = ""
+	return parsedURL.String()
+}
@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLinkProblem(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		fetchError string
+		want       string
+	}{
+		{http.StatusOK, "", ""},
+		{http.StatusMovedPermanently, "", ""},
+		{http.StatusNotFound, "", LinkProblemNotFound},
+		{http.StatusGone, "", LinkProblemNotFound},
+		{http.StatusForbidden, "", LinkProblemClientError},
+		{http.StatusTooManyRequests, "", LinkProblemClientError},
+		{http.StatusInternalServerError, "", LinkProblemServerError},
+		{http.StatusBadGateway, "", LinkProblemServerError},
+		{0, ErrRedirectLoop.Error(), LinkProblemRedirectLoop},
+		{0, "dial tcp: lookup acme.invalid: no such host", LinkProblemUnreachable},
+	}
+	for _, test := range tests {
+		if got := LinkProblem(test.statusCode, test.fetchError); got != test.want {
+			t.Errorf("LinkProblem(%d, %q) = %q, want %q", test.statusCode, test.fetchError, got, test.want)
+		}
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewLinkCheckClient(5 * time.Second)
+	tests := map[string]string{
+		"/ok":       "",
+		"/gone":     LinkProblemNotFound,
+		"/loop":     LinkProblemRedirectLoop,
+		"/get-only": "",
+	}
+	for path, want := range tests {
+		statusCode, err := CheckLink(context.Background(), client, server.URL+path)
+		if got := LinkProblem(statusCode, FetchErrorMessage(err)); got != want {
+			t.Errorf("%s: problem = %q (status %d, error %v), want %q", path, got, statusCode, err, want)
+		}
+	}
+}
+
+func TestGetInternalLinksFromHTML(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="install">Install</a>
+		<a href="/docs/api?lang=go#auth">API</a>
+		<a href="https://docs.acme.dev/docs/guide">Guide</a>
+		<a href="../blog/">Blog</a>
+		<a href="mailto:support@acme.dev">Support</a>
+		<a href="https://github.com/acme/sdk">GitHub</a>
+		<a href="#top">Top</a>
+	</body></html>`
+	got := GetInternalLinks("https://docs.acme.dev/docs/start/", GetHrefsFromHTML(htmlContent))
+	want := []string{
+		"https://docs.acme.dev/docs/start/install",
+		"https://docs.acme.dev/docs/api",
+		"https://docs.acme.dev/docs/guide",
+		"https://docs.acme.dev/docs/blog/",
+		"https://docs.acme.dev/docs/start/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetInternalLinks = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package helpers
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+var languageTagRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// urlLanguageSegments are the path segments docs sites commonly use for translations
+var urlLanguageSegments = map[string]bool{
+	"ar": true, "bg": true, "cs": true, "da": true, "de": true, "el": true, "en": true, "en-gb": true, "en-us": true,
+	"es": true, "es-419": true, "es-es": true, "fa": true, "fi": true, "fr": true, "he": true, "hi": true, "hu": true,
+	"id": true, "it": true, "ja": true, "ja-jp": true, "ko": true, "ko-kr": true, "nl": true, "no": true, "pl": true,
+	"pt": true, "pt-br": true, "pt-pt": true, "ro": true, "ru": true, "sv": true, "th": true, "tr": true, "uk": true,
+	"vi": true, "zh": true, "zh-cn": true, "zh-hans": true, "zh-hant": true, "zh-hk": true, "zh-tw": true,
+}
+
+// stopwords used to tell apart languages written in the Latin script. Each word is in one list
+// only, since words common to several languages, such as "de" or "que", cannot tell them apart.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "to", "that", "with", "you", "this", "are", "it", "from", "which", "your"},
+	"de": {"der", "das", "und", "ist", "nicht", "mit", "sie", "ein", "eine", "zu", "wird", "auch"},
+	"fr": {"le", "les", "et", "est", "des", "une", "pour", "dans", "vous", "avec", "sur", "pas"},
+	"es": {"el", "los", "las", "y", "está", "pero", "también", "puede", "cuando", "usted", "hay", "muy"},
+	"pt": {"os", "uma", "não", "você", "com", "em", "são", "também", "pode", "isso", "ao", "seu"},
+	"it": {"il", "gli", "è", "per", "che", "questa", "della", "sono", "questo", "anche", "di", "nel"},
+	"nl": {"het", "een", "van", "dat", "voor", "met", "niet", "je", "zijn", "wordt", "ook", "kunt"},
+}
+
+// NormalizeLanguageTag lowercases a BCP 47 style tag and returns "" if it is not a valid tag
+func NormalizeLanguageTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	if !languageTagRegex.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
+
+// PrimaryLanguage returns the primary subtag of a language tag, e.g. "zh" for "zh-cn"
+func PrimaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(tag, "-")
+	return primary
+}
+
+// NormalizeLanguageList parses a comma separated list of language tags
+func NormalizeLanguageList(value string) []string {
+	var languages []string
+	for _, tag := range strings.Split(value, ",") {
+		if normalized := NormalizeLanguageTag(tag); normalized != "" {
+			languages = append(languages, normalized)
+		}
+	}
+	return languages
+}
+
+// DetectPageLanguage detects the language of a page from the <html lang> attribute, hreflang
+// alternates, URL segments and finally the text itself. Any of the inputs may be empty.
+func DetectPageLanguage(htmlContent string, pageURL string, text string) string {
+	contentLanguage, scriptConfident := DetectTextLanguage(text)
+
+	declared := ""
+	if htmlContent != "" {
+		declared = getDeclaredLanguage(htmlContent, pageURL)
+	}
+	if declared == "" {
+		declared = GetLanguageFromURL(pageURL)
+	}
+
+	// Translated pages often keep the template's lang attribute, so trust a non-Latin script
+	// over a declaration that disagrees with it
+	if declared != "" && scriptConfident && PrimaryLanguage(declared) != contentLanguage {
+		return contentLanguage
+	}
+	if declared != "" {
+		return declared
+	}
+	return contentLanguage
+}
+
+// getDeclaredLanguage reads <html lang>, falling back to the hreflang alternate pointing at the page itself
+func getDeclaredLanguage(htmlContent string, pageURL string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+
+	var htmlLang, hreflang string
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "html":
+				htmlLang = NormalizeLanguageTag(getAttr(n, "lang"))
+			case "link":
+				if strings.EqualFold(getAttr(n, "rel"), "alternate") && hreflang == "" && sameURL(getAttr(n, "href"), pageURL) {
+					hreflang = NormalizeLanguageTag(getAttr(n, "hreflang"))
+				}
+			case "body":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	if htmlLang != "" {
+		return htmlLang
+	}
+	return hreflang
+}
+
+// GetLanguageFromURL returns the language of a locale segment near the start of the URL path
+func GetLanguageFromURL(pageURL string) string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	for i, segment := range segments {
+		if i >= 3 {
+			break
+		}
+		tag := NormalizeLanguageTag(segment)
+		if urlLanguageSegments[tag] {
+			return tag
+		}
+	}
+	return ""
+}
+
+// DetectTextLanguage guesses the language of a text from its script and, for Latin text, its
+// stopwords. The boolean reports whether the guess comes from a distinctive non-Latin script.
+func DetectTextLanguage(text string) (string, bool) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		}
+	}
+	if letters < 50 {
+		return "", false
+	}
+
+	// Japanese mixes kana with Han characters, so any meaningful amount of kana wins
+	if counts["ja"]*20 > letters {
+		return "ja", true
+	}
+	best, bestCount := "", 0
+	for language, count := range counts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	if bestCount*10 > letters*3 {
+		return best, true
+	}
+
+	return detectLatinLanguage(text), false
+}
+
+// detectLatinLanguage returns the language whose stopwords a text uses most, or "" when it uses
+// fewer than three or two languages' stopwords equally
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	wordCounts := make(map[string]int)
+	for _, word := range words {
+		wordCounts[word]++
+	}
+
+	best, bestScore, runnerUpScore := "", 0, 0
+	for language, stopwords := range latinStopwords {
+		score := 0
+		for _, stopword := range stopwords {
+			score += wordCounts[stopword]
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUpScore = language, score, bestScore
+		case score > runnerUpScore:
+			runnerUpScore = score
+		}
+	}
+	if bestScore < 3 || bestScore == runnerUpScore {
+		return ""
+	}
+	return best
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// sameURL reports whether href, resolved against the page URL, points at the page itself
+func sameURL(href string, pageURL string) bool {
+	if href == "" || pageURL == "" {
+		return false
+	}
+	parsedPageURL, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	parsedHref, err := parsedPageURL.Parse(href)
+	if err != nil {
+		return false
+	}
+	return parsedHref.Host == parsedPageURL.Host && strings.TrimSuffix(parsedHref.Path, "/") == strings.TrimSuffix(parsedPageURL.Path, "/")
+}
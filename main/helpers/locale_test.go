@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectTextLanguage(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		want          string
+		wantConfident bool
+	}{
+		{"english", "Install the package with your package manager, and then create a configuration file that lists the options you want to use with this tool.", "en", false},
+		{"spanish", "Instala el paquete con tu gestor de paquetes y luego crea un archivo de configuración. También puede usar las opciones cuando el servidor está listo, pero los cambios necesitan reiniciar.", "es", false},
+		{"french", "Installez le paquet avec votre gestionnaire, puis créez un fichier de configuration dans le dossier du projet. Vous pouvez aussi modifier les options pour chaque environnement, et le serveur est redémarré.", "fr", false},
+		{"german", "Installieren Sie das Paket mit Ihrem Paketmanager und erstellen Sie eine Konfigurationsdatei. Der Server wird danach neu gestartet und ist sofort bereit, auch wenn nicht alle Optionen gesetzt sind.", "de", false},
+		{"dutch", "Installeer het pakket met je pakketbeheerder en maak daarna een configuratiebestand. De server wordt opnieuw gestart en je kunt ook de opties van het project wijzigen voor elke omgeving.", "nl", false},
+		{"portuguese", "Instale o pacote com o seu gerenciador e depois crie um arquivo de configuração. Você também pode alterar as opções do projeto em cada ambiente, e o servidor não precisa ser reiniciado.", "pt", false},
+		{"too short", "Install the package and run it.", "", false},
+		{"no stopwords", "Kubernetes Helm Terraform Ansible Prometheus Grafana Elasticsearch Kibana Logstash", "", false},
+		{"tied stopwords", "Lorem ipsum dolor sit amet consectetur adipiscing elit the and that il gli per sed do eiusmod", "", false},
+		{"russian with english words", "Установите пакет с помощью менеджера пакетов npm, затем создайте файл конфигурации в корне проекта и запустите сервер.", "ru", true},
+		{"japanese", "パッケージマネージャーでパッケージをインストールしてから、プロジェクトのルートに設定ファイルを作成してください。サーバーを起動します。", "ja", true},
+	}
+	for _, test := range tests {
+		got, confident := DetectTextLanguage(test.text)
+		if got != test.want || confident != test.wantConfident {
+			t.Errorf("%s: DetectTextLanguage = %q, %v, want %q, %v", test.name, got, confident, test.want, test.wantConfident)
+		}
+	}
+}
+
+func TestLatinStopwordsAreDistinct(t *testing.T) {
+	languages := make(map[string]string)
+	for language, stopwords := range latinStopwords {
+		for _, stopword := range stopwords {
+			if other, ok := languages[stopword]; ok {
+				t.Errorf("%q is a stopword of both %s and %s", stopword, other, language)
+			}
+			languages[stopword] = language
+			if stopword != strings.ToLower(stopword) {
+				t.Errorf("stopword %q of %s is not lowercase", stopword, language)
+			}
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/strikethrough"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownConverter is the conversion profile for docs pages: CommonMark with GFM tables and
+// strikethrough, plus the docs plugin for code languages, admonitions, diagrams and heading anchors
+var markdownConverter = converter.NewConverter(
+	converter.WithPlugins(
+		base.NewBasePlugin(),
+		commonmark.NewCommonmarkPlugin(),
+		table.NewTablePlugin(table.WithHeaderPromotion(true), table.WithSkipEmptyRows(true)),
+		strikethrough.NewStrikethroughPlugin(),
+		&docsPlugin{},
+	),
+)
+
+// ConvertHTMLToMarkdown converts a docs page to markdown, resolving relative links against the page URL
+func ConvertHTMLToMarkdown(htmlContent string, pageURL string) (string, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %v", err)
+	}
+
+	domain := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
+	markdown, err := markdownConverter.ConvertString(htmlContent, converter.WithDomain(domain))
+	if err != nil {
+		return "", fmt.Errorf("failed to convert HTML to Markdown: %v", err)
+	}
+	return markdown, nil
+}
+
+// alertTypes maps the admonition types of Docusaurus, MkDocs, Sphinx and GitBook to GitHub alert types
+var alertTypes = map[string]string{
+	"note": "NOTE", "info": "NOTE", "information": "NOTE", "seealso": "NOTE", "abstract": "NOTE", "summary": "NOTE",
+	"example": "NOTE", "quote": "NOTE", "question": "NOTE", "secondary": "NOTE",
+	"tip": "TIP", "hint": "TIP", "success": "TIP", "check": "TIP", "done": "TIP",
+	"important": "IMPORTANT", "attention": "IMPORTANT",
+	"warning": "WARNING", "caution": "WARNING", "warn": "WARNING",
+	"danger": "CAUTION", "error": "CAUTION", "failure": "CAUTION", "fail": "CAUTION", "bug": "CAUTION",
+}
+
+// admonitionClassPrefixes are the class prefixes that carry the admonition type, e.g. "admonition-tip"
+var admonitionClassPrefixes = []string{"theme-admonition-", "admonition-", "hint-", "callout-", "alert--"}
+
+// permalinkClasses are the classes of the "#" and "¶" links docs frameworks add to headings
+var permalinkClasses = []string{"hash-link", "headerlink", "anchor-link", "anchorjs-link", "header-anchor", "heading-anchor"}
+
+// ignoredCodeLanguages are highlighter classes that do not name a language
+var ignoredCodeLanguages = map[string]bool{"default": true, "none": true, "notranslate": true, "nohighlight": true}
+
+var (
+	headingIDRegex   = regexp.MustCompile(`^[A-Za-z][\w\-.:]*$`)
+	blankLinesRegex  = regexp.MustCompile(`\n{3,}`)
+	permalinkTextSet = "#¶§🔗\u200b "
+)
+
+// docsPlugin extends the converter with the structures docs pages rely on
+type docsPlugin struct{}
+
+func (p *docsPlugin) Name() string {
+	return "docs"
+}
+
+func (p *docsPlugin) Init(conv *converter.Converter) error {
+	conv.Register.PreRenderer(p.handlePreRender, converter.PriorityEarly)
+	conv.Register.Renderer(p.renderAdmonition, converter.PriorityEarly)
+	conv.Register.Renderer(p.renderHeading, converter.PriorityEarly)
+	return nil
+}
+
+// handlePreRender normalizes code blocks and heading anchors before the document is rendered
+func (p *docsPlugin) handlePreRender(_ converter.Context, doc *html.Node) {
+	for _, table := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && hasClass(n, "highlighttable") }) {
+		unwrapLineNumberTable(table)
+	}
+	removeNodes(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && (hasClass(n, "linenos") || hasClass(n, "linenodiv") || hasClass(n, "line-numbers-rows"))
+	})
+
+	for _, language := range []string{DiagramMermaid, DiagramPlantUML} {
+		for _, n := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && hasClass(n, language) }) {
+			convertDiagramSource(n, language)
+		}
+	}
+	for _, pre := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "pre" }) {
+		if language := findCodeLanguage(pre); language != "" {
+			setAttr(pre, "class", "language-"+language)
+			if code := findFirst(pre, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "code" }); code != nil {
+				setAttr(code, "class", "language-"+language)
+			}
+		}
+	}
+
+	for _, heading := range findAll(doc, isHeading) {
+		if id := headingAnchor(heading); id != "" {
+			setAttr(heading, "id", id)
+		}
+		removeNodes(heading, func(n *html.Node) bool {
+			return isPermalink(n, getAttr(heading, "id")) || isEmptyAnchor(n)
+		})
+	}
+}
+
+// findCodeLanguage reads the language of a code block from its classes or data attributes, or from
+// the wrappers highlighters put around it such as Sphinx's <div class="highlight-python">
+func findCodeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre}
+	if code := findFirst(pre, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "code" }); code != nil {
+		candidates = append([]*html.Node{code}, candidates...)
+	}
+	for p, depth := pre.Parent, 0; p != nil && depth < 3; p, depth = p.Parent, depth+1 {
+		candidates = append(candidates, p)
+	}
+
+	for _, n := range candidates {
+		for _, attr := range []string{"data-language", "data-lang"} {
+			if language := normalizeCodeLanguage(getAttr(n, attr)); language != "" {
+				return language
+			}
+		}
+		for _, class := range strings.Fields(getAttr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-", "highlight-"} {
+				if strings.HasPrefix(class, prefix) {
+					if language := normalizeCodeLanguage(strings.TrimPrefix(class, prefix)); language != "" {
+						return language
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeCodeLanguage(language string) string {
+	// GitHub prefixes the language with its grammar scope, e.g. highlight-source-go
+	language = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(language)), "source-")
+	if language == "" || ignoredCodeLanguages[language] || strings.ContainsAny(language, " `") {
+		return ""
+	}
+	return language
+}
+
+// convertDiagramSource turns the source of a Mermaid or PlantUML diagram into a code block of that
+// language. Diagrams that have already been rendered to SVG are left alone since their source is gone.
+func convertDiagramSource(n *html.Node, language string) {
+	if findFirst(n, func(c *html.Node) bool { return c.Type == html.ElementNode && c.Data == "svg" }) != nil {
+		return
+	}
+	source := strings.TrimSpace(textContent(n))
+	if source == "" {
+		return
+	}
+
+	code := &html.Node{Type: html.ElementNode, Data: "code", DataAtom: atom.Code, Attr: []html.Attribute{{Key: "class", Val: "language-" + language}}}
+	code.AppendChild(&html.Node{Type: html.TextNode, Data: source})
+
+	if n.Data == "pre" {
+		for c := n.FirstChild; c != nil; c = n.FirstChild {
+			n.RemoveChild(c)
+		}
+		n.AppendChild(code)
+		return
+	}
+	pre := &html.Node{Type: html.ElementNode, Data: "pre", DataAtom: atom.Pre}
+	pre.AppendChild(code)
+	n.Parent.InsertBefore(pre, n)
+	n.Parent.RemoveChild(n)
+}
+
+// unwrapLineNumberTable replaces a Pygments table with line numbers by the code cell's content
+func unwrapLineNumberTable(table *html.Node) {
+	codeCell := findFirst(table, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "td" && hasClass(n, "code") })
+	if codeCell == nil || table.Parent == nil {
+		return
+	}
+	for c := codeCell.FirstChild; c != nil; c = codeCell.FirstChild {
+		codeCell.RemoveChild(c)
+		table.Parent.InsertBefore(c, table)
+	}
+	table.Parent.RemoveChild(table)
+}
+
+// headingAnchor returns the id a heading can be linked to: its own, a named anchor inside it, or the
+// id of the section it opens as Sphinx renders them
+func headingAnchor(heading *html.Node) string {
+	if id := getAttr(heading, "id"); headingIDRegex.MatchString(id) {
+		return id
+	}
+	anchor := findFirst(heading, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "a" && getAttr(n, "href") == "" && (getAttr(n, "id") != "" || getAttr(n, "name") != "")
+	})
+	if anchor != nil {
+		for _, id := range []string{getAttr(anchor, "id"), getAttr(anchor, "name")} {
+			if headingIDRegex.MatchString(id) {
+				return id
+			}
+		}
+	}
+	if parent := heading.Parent; parent != nil && startsWithHeading(parent) && findFirst(parent, isHeading) == heading {
+		if id := getAttr(parent, "id"); headingIDRegex.MatchString(id) && (parent.Data == "section" || hasClass(parent, "section")) {
+			return id
+		}
+	}
+	return ""
+}
+
+// isPermalink reports whether a link inside a heading is only a permalink to the heading itself
+func isPermalink(n *html.Node, headingID string) bool {
+	if n.Type != html.ElementNode || n.Data != "a" {
+		return false
+	}
+	for _, class := range permalinkClasses {
+		if hasClass(n, class) {
+			return true
+		}
+	}
+	href := getAttr(n, "href")
+	return href != "" && href == "#"+headingID && strings.Trim(textContent(n), permalinkTextSet) == ""
+}
+
+// isEmptyAnchor reports whether n is a named anchor such as <a name="intro"></a> that only marks a position
+func isEmptyAnchor(n *html.Node) bool {
+	return n.Type == html.ElementNode && n.Data == "a" && getAttr(n, "href") == "" && strings.TrimSpace(textContent(n)) == ""
+}
+
+// renderHeading renders ATX headings with their anchor as a {#id} attribute
+func (p *docsPlugin) renderHeading(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	if !isHeading(n) {
+		return converter.RenderTryNext
+	}
+	id := getAttr(n, "id")
+	if !headingIDRegex.MatchString(id) {
+		return converter.RenderTryNext
+	}
+
+	var buf bytes.Buffer
+	ctx.RenderChildNodes(ctx, &buf, n)
+	content := strings.Join(strings.Fields(buf.String()), " ")
+	if content == "" {
+		return converter.RenderSuccess
+	}
+
+	w.WriteString("\n\n")
+	w.WriteString(strings.Repeat("#", int(n.Data[1]-'0')))
+	w.WriteString(" ")
+	w.WriteString(content)
+	w.WriteString(" {#")
+	w.WriteString(id)
+	w.WriteString("}\n\n")
+	return converter.RenderSuccess
+}
+
+// renderAdmonition renders note, tip and warning boxes as GitHub alert blockquotes, e.g. "> [!NOTE]"
+func (p *docsPlugin) renderAdmonition(ctx converter.Context, w converter.Writer, n *html.Node) converter.RenderStatus {
+	if n.Type != html.ElementNode || (n.Data != "div" && n.Data != "aside" && n.Data != "details" && n.Data != "section") {
+		return converter.RenderTryNext
+	}
+	alertType, ok := admonitionType(n)
+	if !ok {
+		return converter.RenderTryNext
+	}
+
+	title := ""
+	if titleNode := findFirst(n, isAdmonitionTitle); titleNode != nil {
+		title = strings.Join(strings.Fields(textContent(titleNode)), " ")
+		titleNode.Parent.RemoveChild(titleNode)
+	}
+
+	var buf bytes.Buffer
+	ctx.RenderChildNodes(ctx, &buf, n)
+	content := strings.TrimSpace(blankLinesRegex.ReplaceAllString(buf.String(), "\n\n"))
+
+	lines := []string{"[!" + alertType + "]"}
+	if title != "" && alertTypes[strings.ToLower(title)] != alertType {
+		lines = append(lines, "**"+title+"**", "")
+	}
+	if content != "" {
+		lines = append(lines, strings.Split(content, "\n")...)
+	} else if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	w.WriteString("\n\n")
+	for i, line := range lines {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		if line == "" {
+			w.WriteString(">")
+		} else {
+			w.WriteString("> " + line)
+		}
+	}
+	w.WriteString("\n\n")
+	return converter.RenderSuccess
+}
+
+// admonitionType returns the GitHub alert type of an admonition container
+func admonitionType(n *html.Node) (string, bool) {
+	classes := strings.Fields(strings.ToLower(getAttr(n, "class")))
+	isAdmonition := false
+	for _, class := range classes {
+		if class == "admonition" || class == "theme-admonition" || class == "hint" || class == "callout" {
+			isAdmonition = true
+		}
+	}
+	if !isAdmonition {
+		return "", false
+	}
+
+	for _, class := range classes {
+		for _, prefix := range admonitionClassPrefixes {
+			if alertType, ok := alertTypes[strings.TrimPrefix(class, prefix)]; ok && strings.HasPrefix(class, prefix) {
+				return alertType, true
+			}
+		}
+	}
+	for _, class := range classes {
+		if alertType, ok := alertTypes[class]; ok {
+			return alertType, true
+		}
+	}
+	return "NOTE", true
+}
+
+func isAdmonitionTitle(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if n.Data == "summary" || hasClass(n, "admonition-title") || hasClass(n, "hint-title") || hasClass(n, "callout-title") {
+		return true
+	}
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		if strings.HasPrefix(class, "admonitionHeading") || strings.HasPrefix(class, "admonition-heading") {
+			return true
+		}
+	}
+	return false
+}
+
+func setAttr(n *html.Node, key string, value string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+// textContent returns the text of a node as is, unlike innerText which collapses whitespace
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	return sb.String()
+}
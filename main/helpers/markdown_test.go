@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConvertHTMLToMarkdown compares the markdown of each testdata/markdown/*.html snippet with its .md
+// golden file. Run `go test ./helpers -run TestConvertHTMLToMarkdown -update` to regenerate them.
+func TestConvertHTMLToMarkdown(t *testing.T) {
+	snippets, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippets) == 0 {
+		t.Fatal("no test snippets found")
+	}
+
+	for _, snippet := range snippets {
+		name := strings.TrimSuffix(filepath.Base(snippet), ".html")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(snippet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			markdown, err := ConvertHTMLToMarkdown(string(input), "https://docs.example.com/guide/page")
+			if err != nil {
+				t.Fatal(err)
+			}
+			markdown += "\n"
+
+			goldenPath := strings.TrimSuffix(snippet, ".html") + ".md"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(markdown), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if markdown != string(golden) {
+				t.Errorf("markdown does not match %s\n--- got ---\n%s\n--- want ---\n%s", goldenPath, markdown, golden)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+	"golang.org/x/net/html"
+)
+
+// titleSeparators split a page title from the site name appended to it, e.g. "Install | Acme Docs"
+var titleSeparators = []string{" | ", " – ", " — ", " · ", " :: ", " - "}
+
+// lastUpdatedMetaNames are the <meta> names and properties that carry a page's modification date
+var lastUpdatedMetaNames = []string{"article:modified_time", "og:updated_time", "last-modified", "dcterms.modified", "dc.date.modified", "revised"}
+
+// lastUpdatedClasses mark the "Last updated on ..." elements docs frameworks render on pages
+var lastUpdatedClasses = []string{"theme-last-updated", "last-updated", "lastupdated", "git-revision-date", "md-source-file", "last-modified", "page-last-updated"}
+
+// dateLayouts are the date formats parsed from last-updated text and attributes
+var dateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05.000Z", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02",
+	"January 2, 2006", "Jan 2, 2006", "Jan 02, 2006", "2 January 2006", "02 Jan 2006", "2006/01/02",
+	time.RFC1123, time.RFC1123Z,
+}
+
+var (
+	lastUpdatedTextRegex = regexp.MustCompile(`(?i)(?:last\s+updated|last\s+modified|last\s+update|updated)(?:\s+on)?\s*:?\s*([A-Za-z]{3,9}\.?\s+\d{1,2},?\s+\d{4}|\d{4}-\d{2}-\d{2}(?:[T ][\d:.]+Z?)?|\d{1,2}\s+[A-Za-z]{3,9}\s+\d{4}|\d{4}/\d{2}/\d{2})`)
+	markdownHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	headingAttrRegex     = regexp.MustCompile(`\s*\{#([A-Za-z][\w\-.:]*)\}$`)
+)
+
+// ExtractPageMetadata reads a page's title, description, canonical URL, last-updated date and heading
+// outline. The title falls back from og:title to the first <h1> to the <title> without the site name.
+func ExtractPageMetadata(htmlContent string, pageURL string) types.PageMetadata {
+	metadata := types.PageMetadata{Headings: []types.Heading{}}
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return metadata
+	}
+
+	meta := make(map[string]string)
+	var documentTitle, canonical string
+	for _, n := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode }) {
+		switch n.Data {
+		case "meta":
+			key := strings.ToLower(getAttr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(getAttr(n, "name"))
+			}
+			if key == "" {
+				key = strings.ToLower(getAttr(n, "http-equiv"))
+			}
+			if _, ok := meta[key]; key != "" && !ok {
+				meta[key] = strings.TrimSpace(getAttr(n, "content"))
+			}
+		case "title":
+			if documentTitle == "" && !hasSVGAncestor(n) {
+				documentTitle = innerText(n)
+			}
+		case "link":
+			if canonical == "" && strings.EqualFold(getAttr(n, "rel"), "canonical") {
+				canonical = resolveURL(getAttr(n, "href"), pageURL)
+			}
+		}
+	}
+
+	// The outline and the <h1> come from the main content so that navigation headings are left out
+	content := doc
+	if mainContent, ok := ExtractMainContent(htmlContent); ok {
+		if parsed, err := html.Parse(strings.NewReader(mainContent)); err == nil {
+			content = parsed
+		}
+	}
+	metadata.Headings = headingOutline(content)
+
+	h1 := ""
+	for _, heading := range metadata.Headings {
+		if heading.Level == 1 {
+			h1 = heading.Text
+			break
+		}
+	}
+	siteName := meta["og:site_name"]
+	for _, title := range []string{StripSiteName(meta["og:title"], siteName), h1, StripSiteName(documentTitle, siteName)} {
+		if title != "" {
+			metadata.Title = title
+			break
+		}
+	}
+
+	metadata.Description = meta["description"]
+	if metadata.Description == "" {
+		metadata.Description = meta["og:description"]
+	}
+	metadata.CanonicalURL = canonical
+	if metadata.CanonicalURL == "" {
+		metadata.CanonicalURL = resolveURL(meta["og:url"], pageURL)
+	}
+	metadata.LastUpdated = findLastUpdated(doc, meta)
+	return metadata
+}
+
+// StripSiteName removes the site name from a page title such as "Install | Acme Docs". Without a
+// known site name the last separated part is removed.
+func StripSiteName(title string, siteName string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return ""
+	}
+	for _, separator := range titleSeparators {
+		if siteName != "" && strings.HasSuffix(title, separator+siteName) {
+			return strings.TrimSpace(strings.TrimSuffix(title, separator+siteName))
+		}
+		if siteName != "" && strings.HasPrefix(title, siteName+separator) {
+			return strings.TrimSpace(strings.TrimPrefix(title, siteName+separator))
+		}
+	}
+	if siteName != "" && title != siteName {
+		// og:title and <title> of sites that declare their name usually omit it
+		return title
+	}
+	for _, separator := range titleSeparators {
+		if i := strings.LastIndex(title, separator); i > 0 {
+			return strings.TrimSpace(title[:i])
+		}
+	}
+	return title
+}
+
+// GetHeadingsFromMarkdown returns the heading outline of markdown, skipping fenced code blocks
+func GetHeadingsFromMarkdown(markdown string) []types.Heading {
+	headings := []types.Heading{}
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			if fence == "" {
+				fence = match[1][:1]
+			} else if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		match := markdownHeadingRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		heading := types.Heading{Level: len(match[1]), Text: match[2]}
+		if anchor := headingAttrRegex.FindStringSubmatch(heading.Text); anchor != nil {
+			heading.Anchor = anchor[1]
+			heading.Text = strings.TrimSpace(headingAttrRegex.ReplaceAllString(heading.Text, ""))
+		}
+		headings = append(headings, heading)
+	}
+	return headings
+}
+
+// headingOutline lists the headings of a document with their anchors, leaving out permalink symbols
+func headingOutline(doc *html.Node) []types.Heading {
+	headings := []types.Heading{}
+	for _, n := range findAll(doc, isHeading) {
+		anchor := headingAnchor(n)
+		removeNodes(n, func(c *html.Node) bool { return isPermalink(c, anchor) })
+		text := strings.Trim(innerText(n), permalinkTextSet)
+		if text == "" {
+			continue
+		}
+		headings = append(headings, types.Heading{Level: int(n.Data[1] - '0'), Text: text, Anchor: anchor})
+	}
+	return headings
+}
+
+// findLastUpdated reads the modification date from <meta> tags, then from the "Last updated" line of the page
+func findLastUpdated(doc *html.Node, meta map[string]string) *time.Time {
+	for _, name := range lastUpdatedMetaNames {
+		if date := parseDate(meta[name]); date != nil {
+			return date
+		}
+	}
+
+	for _, n := range findAll(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		class := strings.ToLower(getAttr(n, "class"))
+		for _, lastUpdatedClass := range lastUpdatedClasses {
+			if strings.Contains(class, lastUpdatedClass) {
+				return true
+			}
+		}
+		return false
+	}) {
+		if timeNode := findFirst(n, func(c *html.Node) bool { return c.Type == html.ElementNode && c.Data == "time" }); timeNode != nil {
+			if date := parseDate(getAttr(timeNode, "datetime")); date != nil {
+				return date
+			}
+		}
+		text := innerText(n)
+		if date := parseDate(text); date != nil {
+			return date
+		}
+		if match := lastUpdatedTextRegex.FindStringSubmatch(text); match != nil {
+			if date := parseDate(match[1]); date != nil {
+				return date
+			}
+		}
+	}
+
+	if body := findFirst(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "body" }); body != nil {
+		if match := lastUpdatedTextRegex.FindStringSubmatch(innerText(body)); match != nil {
+			return parseDate(match[1])
+		}
+	}
+	return nil
+}
+
+func parseDate(value string) *time.Time {
+	value = strings.TrimSuffix(strings.TrimSpace(value), ".")
+	if value == "" {
+		return nil
+	}
+	value = strings.Replace(value, "Sept ", "Sep ", 1)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			date = date.UTC()
+			return &date
+		}
+	}
+	return nil
+}
+
+func resolveURL(href string, pageURL string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return href
+	}
+	resolved, err := base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return resolved.String()
+}
+
+func hasSVGAncestor(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "svg" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+func TestExtractPageMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		wantTitle string
+		wantDesc  string
+		wantURL   string
+		wantDate  string
+	}{
+		{
+			name: "og title without site name",
+			html: `<html><head><title>Install | Acme Docs</title>
+				<meta property="og:title" content="Install | Acme Docs"><meta property="og:site_name" content="Acme Docs">
+				<meta name="description" content="How to install Acme."><link rel="canonical" href="/docs/install">
+				<meta property="article:modified_time" content="2026-09-30T12:00:00+02:00"></head>
+				<body><main><h1>Installing Acme</h1></main></body></html>`,
+			wantTitle: "Install",
+			wantDesc:  "How to install Acme.",
+			wantURL:   "https://docs.example.com/docs/install",
+			wantDate:  "2026-09-30T10:00:00Z",
+		},
+		{
+			name: "first h1 before document title",
+			html: `<html><head><title>Docs - Acme</title><meta property="og:description" content="Fallback description"></head>
+				<body><nav><h1>Menu</h1></nav><article><h1>Configuration</h1><p>` + longText + `</p>
+				<footer class="theme-last-updated">Last updated on <time datetime="2026-08-01T00:00:00Z">Aug 1, 2026</time></footer></article></body></html>`,
+			wantTitle: "Configuration",
+			wantDesc:  "Fallback description",
+			wantDate:  "2026-08-01T00:00:00Z",
+		},
+		{
+			name:      "document title with site suffix",
+			html:      `<html><head><title>API Reference — Acme</title></head><body><p>Last updated: March 3, 2025</p></body></html>`,
+			wantTitle: "API Reference",
+			wantDate:  "2025-03-03T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := ExtractPageMetadata(tt.html, "https://docs.example.com/docs/install/")
+			if metadata.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", metadata.Title, tt.wantTitle)
+			}
+			if metadata.Description != tt.wantDesc {
+				t.Errorf("description = %q, want %q", metadata.Description, tt.wantDesc)
+			}
+			if metadata.CanonicalURL != tt.wantURL {
+				t.Errorf("canonical url = %q, want %q", metadata.CanonicalURL, tt.wantURL)
+			}
+			gotDate := ""
+			if metadata.LastUpdated != nil {
+				gotDate = metadata.LastUpdated.Format(time.RFC3339)
+			}
+			if gotDate != tt.wantDate {
+				t.Errorf("last updated = %q, want %q", gotDate, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestExtractPageMetadataHeadings(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "extract", "docusaurus.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := ExtractPageMetadata(string(input), "https://docs.example.com/install")
+	if len(metadata.Headings) == 0 || metadata.Headings[0].Level != 1 || metadata.Headings[0].Text != "Installation" {
+		t.Fatalf("unexpected outline: %+v", metadata.Headings)
+	}
+	for _, heading := range metadata.Headings {
+		if heading.Text == "On this page" {
+			t.Errorf("outline contains navigation heading: %+v", metadata.Headings)
+		}
+	}
+}
+
+func TestGetHeadingsFromMarkdown(t *testing.T) {
+	markdown := "# Install {#install}\n\nText\n\n```sh\n# not a heading\n```\n\n## Options ##\n"
+	want := []types.Heading{
+		{Level: 1, Text: "Install", Anchor: "install"},
+		{Level: 2, Text: "Options"},
+	}
+	if got := GetHeadingsFromMarkdown(markdown); !reflect.DeepEqual(got, want) {
+		t.Errorf("headings = %+v, want %+v", got, want)
+	}
+}
+
+func TestStripSiteName(t *testing.T) {
+	tests := []struct {
+		title    string
+		siteName string
+		want     string
+	}{
+		{"Install | Acme Docs", "Acme Docs", "Install"},
+		{"Acme Docs :: Install", "Acme Docs", "Install"},
+		{"Install - Quick start", "Acme Docs", "Install - Quick start"},
+		{"Install - Quick start | Acme", "", "Install - Quick start"},
+		{"  Overview  ", "", "Overview"},
+	}
+	for _, tt := range tests {
+		if got := StripSiteName(tt.title, tt.siteName); got != tt.want {
+			t.Errorf("StripSiteName(%q, %q) = %q, want %q", tt.title, tt.siteName, got, tt.want)
+		}
+	}
+}
+
+const longText = "Configure Acme with a YAML file in the project root. Every option has a default, so an empty file is a valid configuration."
@@ -0,0 +1,327 @@
+package helpers
+
+import (
+	"math"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+	"golang.org/x/net/html"
+)
+
+// sidebarMarkers identify the sidebar navigation of docs frameworks by class, id or aria-label
+var sidebarMarkers = []string{"sidebar", "md-nav--primary", "wy-menu", "book-summary", "toctree", "docs-nav", "side-nav", "sidenav"}
+
+// breadcrumbSeparators are the characters rendered between breadcrumb items
+const breadcrumbSeparators = "»›>/|·  "
+
+// ExtractPageNavigation captures the breadcrumbs and sidebar links of a page. Only links to the
+// page's own site are kept.
+func ExtractPageNavigation(htmlContent string, pageURL string) types.PageNavigation {
+	navigation := types.PageNavigation{Breadcrumbs: []types.NavLink{}, Sidebar: []types.NavLink{}}
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return navigation
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return navigation
+	}
+
+	if container := findFirst(doc, isBreadcrumbContainer); container != nil {
+		navigation.Breadcrumbs = breadcrumbLinks(container, base)
+	}
+
+	// The sidebar is the marked container with the most links, since frameworks nest several marked elements
+	var best []types.NavLink
+	for _, container := range findAll(doc, isSidebarContainer) {
+		if links := sidebarLinks(container, base); len(links) > len(best) {
+			best = links
+		}
+	}
+	if best != nil {
+		navigation.Sidebar = best
+	}
+	return navigation
+}
+
+// NormalizeNavPath returns the path of a page URL as used in the navigation tree, without index
+// files, .html extensions or trailing slashes
+func NormalizeNavPath(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	p := path.Clean("/" + parsed.Path)
+	for _, suffix := range []string{"/index.html", "/index.htm", "/index"} {
+		p = strings.TrimSuffix(p, suffix)
+	}
+	p = strings.TrimSuffix(strings.TrimSuffix(p, ".html"), ".htm")
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// MergeSidebarOrder merges the sidebars captured from several pages into one ordering of page paths.
+// Links first seen on a later page are placed after the link that precedes them in that page's sidebar,
+// so sections that are only expanded on their own pages end up in place.
+func MergeSidebarOrder(sidebars [][]types.NavLink) []string {
+	var order []string
+	for _, sidebar := range sidebars {
+		position := -1
+		for _, link := range sidebar {
+			if link.URL == "" {
+				continue
+			}
+			p := NormalizeNavPath(link.URL)
+			if i := slices.Index(order, p); i >= 0 {
+				position = i
+				continue
+			}
+			position++
+			order = slices.Insert(order, position, p)
+		}
+	}
+	return order
+}
+
+// BuildNavTree builds a navigation tree from the URL paths of a source's pages. Section titles come
+// from sidebar labels and breadcrumbs, and siblings are ordered as they appear in the sidebar.
+// Pages are expected in crawl order; when two pages share a path the first one is used.
+func BuildNavTree(pages []types.NavPage) *types.NavNode {
+	root := &types.NavNode{Path: "/"}
+	if len(pages) == 0 {
+		return root
+	}
+
+	paths := make([][]string, len(pages))
+	for i, page := range pages {
+		paths[i] = pathSegments(NormalizeNavPath(page.URL))
+	}
+	prefix := paths[0]
+	for _, segments := range paths[1:] {
+		n := 0
+		for n < len(prefix) && n < len(segments) && prefix[n] == segments[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	root.Path = "/" + strings.Join(prefix, "/")
+
+	var sidebars [][]types.NavLink
+	labels := make(map[string]string)
+	for _, page := range pages {
+		sidebars = append(sidebars, page.Navigation.Sidebar)
+		for _, link := range page.Navigation.Sidebar {
+			if _, ok := labels[NormalizeNavPath(link.URL)]; !ok && link.URL != "" {
+				labels[NormalizeNavPath(link.URL)] = link.Title
+			}
+		}
+	}
+
+	nodes := map[string]*types.NavNode{root.Path: root}
+	ancestors := func(segments []string) []*types.NavNode {
+		chain := []*types.NavNode{root}
+		for i := len(prefix) + 1; i <= len(segments); i++ {
+			p := "/" + strings.Join(segments[:i], "/")
+			node, ok := nodes[p]
+			if !ok {
+				node = &types.NavNode{Path: p}
+				nodes[p] = node
+				parent := chain[len(chain)-1]
+				parent.Children = append(parent.Children, node)
+			}
+			chain = append(chain, node)
+		}
+		return chain
+	}
+
+	for i, page := range pages {
+		chain := ancestors(paths[i])
+		node := chain[len(chain)-1]
+		if node.PageID == 0 {
+			node.PageID = page.ID
+			node.URL = page.URL
+			node.Title = page.Title
+		}
+
+		// Breadcrumbs name the sections above the page: linked items by their URL, the others by position
+		crumbs := page.Navigation.Breadcrumbs
+		if len(crumbs) > 0 {
+			crumbs = crumbs[:len(crumbs)-1]
+		}
+		for j, crumb := range crumbs {
+			if crumb.URL != "" {
+				if _, ok := labels[NormalizeNavPath(crumb.URL)]; !ok {
+					labels[NormalizeNavPath(crumb.URL)] = crumb.Title
+				}
+				continue
+			}
+			if k := len(chain) - 1 - (len(crumbs) - j); k >= 0 {
+				if _, ok := labels[chain[k].Path]; !ok {
+					labels[chain[k].Path] = crumb.Title
+				}
+			}
+		}
+	}
+
+	positions := make(map[string]int)
+	for i, p := range MergeSidebarOrder(sidebars) {
+		positions[p] = i
+	}
+	finishNavNode(root, labels, positions)
+	return root
+}
+
+// finishNavNode names untitled sections and orders children by sidebar position, then title.
+// It returns the sidebar position of the node, which is the earliest position in its subtree.
+func finishNavNode(node *types.NavNode, labels map[string]string, positions map[string]int) int {
+	if node.Title == "" {
+		node.Title = labels[node.Path]
+	}
+	if node.Title == "" && node.Path != "/" {
+		node.Title = titleFromSegment(path.Base(node.Path))
+	}
+
+	position := math.MaxInt
+	if p, ok := positions[node.Path]; ok {
+		position = p
+	}
+	childPositions := make(map[*types.NavNode]int, len(node.Children))
+	for _, child := range node.Children {
+		childPositions[child] = finishNavNode(child, labels, positions)
+		position = min(position, childPositions[child])
+	}
+	sort.SliceStable(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if childPositions[a] != childPositions[b] {
+			return childPositions[a] < childPositions[b]
+		}
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	})
+	for i, child := range node.Children {
+		child.Order = i
+	}
+	return position
+}
+
+func isBreadcrumbContainer(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	return strings.Contains(strings.ToLower(getAttr(n, "aria-label")), "breadcrumb") ||
+		strings.Contains(strings.ToLower(getAttr(n, "class")), "breadcrumb")
+}
+
+// isSidebarContainer matches marked elements that do not wrap the page content, such as a
+// "with-sidebar" layout element
+func isSidebarContainer(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data == "body" || n.Data == "html" {
+		return false
+	}
+	marks := strings.ToLower(getAttr(n, "class") + " " + getAttr(n, "id") + " " + getAttr(n, "aria-label"))
+	for _, marker := range sidebarMarkers {
+		if strings.Contains(marks, marker) {
+			return findFirst(n, func(c *html.Node) bool {
+				return c.Type == html.ElementNode && (c.Data == "main" || c.Data == "article" || c.Data == "h1")
+			}) == nil
+		}
+	}
+	return false
+}
+
+// breadcrumbLinks reads one item per innermost list item, or per link when the breadcrumbs are not a list
+func breadcrumbLinks(container *html.Node, base *url.URL) []types.NavLink {
+	items := findAll(container, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "li" &&
+			findFirst(n, func(c *html.Node) bool { return c != n && c.Type == html.ElementNode && c.Data == "li" }) == nil
+	})
+	if len(items) == 0 {
+		items = findAll(container, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "a" })
+	}
+
+	links := []types.NavLink{}
+	for _, item := range items {
+		title := strings.Trim(innerText(item), breadcrumbSeparators)
+		if title == "" {
+			title = getAttr(item, "aria-label")
+		}
+		if title == "" {
+			continue
+		}
+		link := types.NavLink{Title: title}
+		anchor := item
+		if item.Data != "a" {
+			anchor = findFirst(item, func(c *html.Node) bool { return c.Type == html.ElementNode && c.Data == "a" })
+		}
+		if anchor != nil {
+			link.URL = siteLink(getAttr(anchor, "href"), base)
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// sidebarLinks lists the links of a sidebar with their list nesting depth
+func sidebarLinks(container *html.Node, base *url.URL) []types.NavLink {
+	links := []types.NavLink{}
+	seen := make(map[string]bool)
+	for _, anchor := range findAll(container, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "a" }) {
+		linkURL := siteLink(getAttr(anchor, "href"), base)
+		title := innerText(anchor)
+		if linkURL == "" || title == "" || seen[linkURL] {
+			continue
+		}
+		seen[linkURL] = true
+
+		depth := -1
+		for p := anchor.Parent; p != nil && p != container; p = p.Parent {
+			if p.Type == html.ElementNode && (p.Data == "ul" || p.Data == "ol") {
+				depth++
+			}
+		}
+		links = append(links, types.NavLink{Title: title, URL: linkURL, Depth: max(depth, 0)})
+	}
+	return links
+}
+
+// siteLink resolves href against the page and returns it without its fragment, or "" when it
+// leaves the site or only points within the page
+func siteLink(href string, base *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	resolved, err := base.Parse(href)
+	if err != nil || resolved.Host != base.Host || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+func pathSegments(p string) []string {
+	var segments []string
+	for _, segment := range strings.Split(p, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// titleFromSegment turns a URL path segment such as "getting-started" into "Getting started"
+func titleFromSegment(segment string) string {
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+	title := strings.Join(strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }), " ")
+	if title == "" {
+		return segment
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
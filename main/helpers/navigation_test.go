@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+func TestExtractPageNavigation(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "extract", "docusaurus.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	navigation := ExtractPageNavigation(string(input), "https://acme.dev/docs/installation")
+	wantBreadcrumbs := []types.NavLink{
+		{Title: "Home", URL: "https://acme.dev/"},
+		{Title: "Installation"},
+	}
+	if !reflect.DeepEqual(navigation.Breadcrumbs, wantBreadcrumbs) {
+		t.Errorf("breadcrumbs = %+v, want %+v", navigation.Breadcrumbs, wantBreadcrumbs)
+	}
+	wantSidebar := []types.NavLink{
+		{Title: "Introduction", URL: "https://acme.dev/docs/intro"},
+		{Title: "Installation", URL: "https://acme.dev/docs/installation"},
+	}
+	if !reflect.DeepEqual(navigation.Sidebar, wantSidebar) {
+		t.Errorf("sidebar = %+v, want %+v", navigation.Sidebar, wantSidebar)
+	}
+}
+
+func TestNormalizeNavPath(t *testing.T) {
+	tests := map[string]string{
+		"https://acme.dev/docs/guides/":           "/docs/guides",
+		"https://acme.dev/docs/guides/index.html": "/docs/guides",
+		"https://acme.dev/docs/install.html#top":  "/docs/install",
+		"https://acme.dev":                        "/",
+	}
+	for input, want := range tests {
+		if got := NormalizeNavPath(input); got != want {
+			t.Errorf("NormalizeNavPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestMergeSidebarOrder(t *testing.T) {
+	link := func(p string) types.NavLink { return types.NavLink{Title: p, URL: "https://acme.dev" + p} }
+	// The guides section is collapsed on the intro page and expanded on the guide pages
+	sidebars := [][]types.NavLink{
+		{link("/docs/intro"), link("/docs/guides"), link("/docs/reference")},
+		{link("/docs/intro"), link("/docs/guides"), link("/docs/guides/deploy"), link("/docs/guides/scale"), link("/docs/reference")},
+	}
+	want := []string{"/docs/intro", "/docs/guides", "/docs/guides/deploy", "/docs/guides/scale", "/docs/reference"}
+	if got := MergeSidebarOrder(sidebars); !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestBuildNavTree(t *testing.T) {
+	sidebar := []types.NavLink{
+		{Title: "Introduction", URL: "https://acme.dev/docs/intro"},
+		{Title: "Deploying", URL: "https://acme.dev/docs/guides/deploy"},
+		{Title: "Scaling", URL: "https://acme.dev/docs/guides/scale"},
+		{Title: "CLI", URL: "https://acme.dev/docs/reference/cli"},
+	}
+	pages := []types.NavPage{
+		{ID: 4, URL: "https://acme.dev/docs/reference/cli", Title: "CLI reference", Navigation: types.PageNavigation{Sidebar: sidebar}},
+		{ID: 2, URL: "https://acme.dev/docs/guides/scale/", Title: "Scaling Acme", Navigation: types.PageNavigation{
+			Breadcrumbs: []types.NavLink{{Title: "Docs", URL: "https://acme.dev/docs"}, {Title: "How-to guides"}, {Title: "Scaling Acme"}},
+			Sidebar:     sidebar,
+		}},
+		{ID: 3, URL: "https://acme.dev/docs/guides/deploy", Title: "Deploying Acme", Navigation: types.PageNavigation{Sidebar: sidebar}},
+		{ID: 1, URL: "https://acme.dev/docs/intro", Title: "Introduction"},
+		{ID: 5, URL: "https://acme.dev/docs/intro.html", Title: "Duplicate introduction"},
+	}
+
+	root := BuildNavTree(pages)
+	if root.Path != "/docs" || root.Title != "Docs" {
+		t.Errorf("root = %q %q, want /docs Docs", root.Path, root.Title)
+	}
+
+	var outline []string
+	var walk func(node *types.NavNode, depth int)
+	walk = func(node *types.NavNode, depth int) {
+		for _, child := range node.Children {
+			outline = append(outline, strings.Repeat("  ", depth)+child.Title+" "+child.Path)
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	want := []string{
+		"Introduction /docs/intro",
+		"How-to guides /docs/guides",
+		"  Deploying Acme /docs/guides/deploy",
+		"  Scaling Acme /docs/guides/scale",
+		"Reference /docs/reference",
+		"  CLI reference /docs/reference/cli",
+	}
+	if !reflect.DeepEqual(outline, want) {
+		t.Errorf("outline =\n%s\nwant\n%s", strings.Join(outline, "\n"), strings.Join(want, "\n"))
+	}
+
+	intro := root.Children[0]
+	if intro.PageID != 1 || intro.Order != 0 || len(intro.Children) != 0 {
+		t.Errorf("intro = %+v, want page 1 at order 0", intro)
+	}
+	if guides := root.Children[1]; guides.PageID != 0 || guides.Order != 1 {
+		t.Errorf("guides = %+v, want a section at order 1", guides)
+	}
+}
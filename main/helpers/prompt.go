@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"github.com/itsmaleen/tech-doc-processor/tokenizer"
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+// DefaultPromptTokenBudget is how many tokens of retrieved chunks are sent to the model with a query
+const DefaultPromptTokenBudget = 8000
+
+// FitTokenBudget returns the leading chunks whose tokens add up to at most budget. Chunks stored
+// before token counts were recorded are counted with the approximate tokenizer. The first chunk is
+// always kept so that a query is never answered without context.
+func FitTokenBudget(chunks []types.ChunkData, budget int) []types.ChunkData {
+	total := 0
+	for i, chunk := range chunks {
+		tokens := chunk.TokenCount
+		if tokens <= 0 {
+			tokens = tokenizer.Approximate{}.Count(chunk.Text)
+		}
+		if i > 0 && total+tokens > budget {
+			return chunks[:i]
+		}
+		total += tokens
+	}
+	return chunks
+}
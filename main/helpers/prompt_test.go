@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/itsmaleen/tech-doc-processor/types"
+)
+
+func TestFitTokenBudget(t *testing.T) {
+	chunks := []types.ChunkData{
+		{Text: "first", TokenCount: 300},
+		{Text: "second", TokenCount: 500},
+		{Text: "third", TokenCount: 300},
+	}
+
+	tests := []struct {
+		budget int
+		want   int
+	}{
+		{1100, 3},
+		{1000, 2},
+		{799, 1},
+		// The best chunk is kept even when it is over the budget
+		{100, 1},
+	}
+	for _, tt := range tests {
+		if got := FitTokenBudget(chunks, tt.budget); len(got) != tt.want {
+			t.Errorf("FitTokenBudget(%d) kept %d chunks, want %d", tt.budget, len(got), tt.want)
+		}
+	}
+
+	// Chunks without a stored count are counted from their text
+	uncounted := []types.ChunkData{{Text: "one two"}, {Text: "three four five six"}}
+	if got := FitTokenBudget(uncounted, 3); len(got) != 1 {
+		t.Errorf("FitTokenBudget of uncounted chunks kept %d chunks, want 1", len(got))
+	}
+}
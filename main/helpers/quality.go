@@ -0,0 +1,207 @@
+package helpers
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Reasons a chunk is skipped instead of embedded
+const (
+	SkipLowText       = "low_text"
+	SkipLinkList      = "link_list"
+	SkipNearDuplicate = "near_duplicate"
+)
+
+// QualityThresholds decide which chunks are worth embedding
+type QualityThresholds struct {
+	// MinWords is the fewest words outside links and headings a chunk without code needs
+	MinWords int
+	// MaxLinkShare is the largest share of a chunk's words that may be link text
+	MaxLinkShare float64
+	// MaxDuplicateDistance is the largest number of bits the SimHashes of near-duplicates differ in
+	MaxDuplicateDistance int
+}
+
+// DefaultQualityThresholds drop "Edit this page" lines, link lists and chunks repeated with small
+// changes across a source, while keeping short code examples. Unrelated chunks differ in about 32
+// of the 64 SimHash bits, and changing a word of a chunk changes a few.
+var DefaultQualityThresholds = QualityThresholds{
+	MinWords:             8,
+	MaxLinkShare:         0.6,
+	MaxDuplicateDistance: 6,
+}
+
+// ChunkQuality is what the quality stage measures of a chunk
+type ChunkQuality struct {
+	// Words counts the words outside links and headings
+	Words int
+	// LinkShare is the share of the chunk's words that are link text or bare URLs
+	LinkShare float64
+	// HasCode is set when the chunk has a fenced code block that is not empty
+	HasCode bool
+	SimHash uint64
+}
+
+var (
+	markdownLinkRegex  = regexp.MustCompile(`(!?)\[([^\]]*)\]\([^)]*\)`)
+	bareURLRegex       = regexp.MustCompile(`https?://\S+`)
+	tableDividerRegex  = regexp.MustCompile(`^\s*\|?[\s:|-]+\|?\s*$`)
+	wordSeparatorRegex = regexp.MustCompile(`[^\p{L}\p{N}_]+`)
+)
+
+// MeasureChunk counts the useful words and link text of a chunk and computes its SimHash
+func MeasureChunk(text string) ChunkQuality {
+	words, linkWords := 0, 0
+	hasCode := false
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			if fence == "" {
+				fence = match[1]
+			} else if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		// Code is useful however it is written
+		if fence != "" {
+			codeWords := countWords(line)
+			words += codeWords
+			hasCode = hasCode || codeWords > 0
+			continue
+		}
+		if blockHeadingRegex.MatchString(line) || tableDividerRegex.MatchString(line) {
+			continue
+		}
+
+		for _, match := range markdownLinkRegex.FindAllStringSubmatch(line, -1) {
+			// Images are left out rather than counted as links
+			if match[1] == "" {
+				linkWords += countWords(match[2])
+			}
+		}
+		line = markdownLinkRegex.ReplaceAllString(line, " ")
+		linkWords += len(bareURLRegex.FindAllString(line, -1))
+		words += countWords(bareURLRegex.ReplaceAllString(line, " "))
+	}
+
+	quality := ChunkQuality{Words: words, HasCode: hasCode, SimHash: SimHash(text)}
+	if total := words + linkWords; total > 0 {
+		quality.LinkShare = float64(linkWords) / float64(total)
+	}
+	return quality
+}
+
+// countWords counts the runs of letters and digits in text
+func countWords(text string) int {
+	return len(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// LowQualityReason returns SkipLinkList or SkipLowText when a chunk has too little useful text to
+// be worth embedding, or "" when it should be kept. Chunks with code are kept however short, since
+// a one-line command is often what a query looks for.
+func (t QualityThresholds) LowQualityReason(quality ChunkQuality) string {
+	if quality.LinkShare > t.MaxLinkShare {
+		return SkipLinkList
+	}
+	if quality.Words < t.MinWords && !quality.HasCode {
+		return SkipLowText
+	}
+	return ""
+}
+
+// SimHash returns the 64-bit SimHash of the three-word shingles of text, ignoring case, punctuation
+// and link targets. Texts that differ in a few words have hashes that differ in a few bits.
+func SimHash(text string) uint64 {
+	text = markdownLinkRegex.ReplaceAllString(text, "$2")
+	words := strings.Fields(strings.ToLower(wordSeparatorRegex.ReplaceAllString(text, " ")))
+	if len(words) == 0 {
+		return 0
+	}
+
+	shingle := min(3, len(words))
+	var weights [64]int
+	for i := 0; i+shingle <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingle], " ")))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// DuplicateIndex finds near-duplicate chunks by their SimHashes. Hashes are split into one more band
+// than the distance allowed, so near-duplicates share at least one band exactly and only hashes that
+// share a band are compared.
+type DuplicateIndex struct {
+	maxDistance int
+	bands       []map[uint64][]int
+	hashes      []uint64
+	pageIDs     []int
+}
+
+// NewDuplicateIndex returns an index of hashes that are near-duplicates when they differ in at most
+// maxDistance bits
+func NewDuplicateIndex(maxDistance int) *DuplicateIndex {
+	maxDistance = max(0, min(maxDistance, 63))
+	bands := make([]map[uint64][]int, maxDistance+1)
+	for i := range bands {
+		bands[i] = make(map[uint64][]int)
+	}
+	return &DuplicateIndex{maxDistance: maxDistance, bands: bands}
+}
+
+// band returns the bits of band i of hash. The last band takes the bits left over.
+func (d *DuplicateIndex) band(hash uint64, i int) uint64 {
+	width := 64 / len(d.bands)
+	start := i * width
+	end := start + width
+	if i == len(d.bands)-1 {
+		end = 64
+	}
+	// Shifting by 64 gives 0, so a single band keeps every bit
+	mask := uint64(1)<<(end-start) - 1
+	return (hash >> start) & mask
+}
+
+// Add adds the hash of a chunk of a page
+func (d *DuplicateIndex) Add(hash uint64, pageID int) {
+	entry := len(d.hashes)
+	d.hashes = append(d.hashes, hash)
+	d.pageIDs = append(d.pageIDs, pageID)
+	for i := range d.bands {
+		key := d.band(hash, i)
+		d.bands[i][key] = append(d.bands[i][key], entry)
+	}
+}
+
+// Find returns the page of the closest near-duplicate of hash and the number of bits they differ in
+func (d *DuplicateIndex) Find(hash uint64) (pageID int, distance int, found bool) {
+	distance = d.maxDistance + 1
+	for i := range d.bands {
+		for _, entry := range d.bands[i][d.band(hash, i)] {
+			if n := bits.OnesCount64(d.hashes[entry] ^ hash); n < distance {
+				pageID, distance, found = d.pageIDs[entry], n, true
+			}
+		}
+	}
+	return pageID, distance, found
+}
@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestLowQualityReason(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"edit link", "[Edit this page](https://github.com/example/docs/edit/main/intro.md)", SkipLinkList},
+		{"link list", "## Related\n\n- [Installation](/install)\n- [Configuration guide](/config)\n- [API reference](/api)", SkipLinkList},
+		{"heading only", "# Getting started\n\n## Next steps", SkipLowText},
+		{"short line", "Last updated on March 3", SkipLowText},
+		{"prose", "The client retries requests that fail with a network error, waiting longer after each attempt.", ""},
+		{"prose with a link", "Set the `timeout` option to limit how long a request may take, as described in [Timeouts](/timeouts).", ""},
+		{"code", "```go\nclient := api.NewClient(api.WithTimeout(5 * time.Second))\ndefer client.Close()\n```", ""},
+		{"install command", "```bash\nnpm install @acme/sdk\n```", ""},
+		{"empty code block", "## Example\n\n```bash\n```", SkipLowText},
+	}
+	for _, tt := range tests {
+		quality := MeasureChunk(tt.text)
+		if got := DefaultQualityThresholds.LowQualityReason(quality); got != tt.want {
+			t.Errorf("%s: LowQualityReason = %q, want %q (%d words, %.2f link share)", tt.name, got, tt.want, quality.Words, quality.LinkShare)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	intro := "Install the command line tool with the package manager of your platform, then run the login command to authenticate with your account. "
+	body := "Projects group the services, databases and secrets of one application. Each project has its own settings, members and billing, " +
+		"and its resources are deployed to the region chosen when it is created. You can move a project to another team later, " +
+		"but its region cannot be changed, so pick the region closest to your users. Environments such as staging and production " +
+		"live inside a project and share its members, while their variables and deployments are kept apart."
+	text := intro + "Then create a project." + body
+	nearCopy := intro + "Then create a new project." + body
+	other := "Webhooks send an HTTP request to your endpoint whenever an event happens in your workspace, signed with the secret shown on the settings page."
+
+	if d := bits.OnesCount64(SimHash(text) ^ SimHash(nearCopy)); d > DefaultQualityThresholds.MaxDuplicateDistance {
+		t.Errorf("near copies differ in %d bits", d)
+	}
+	if d := bits.OnesCount64(SimHash(text) ^ SimHash(other)); d < 16 {
+		t.Errorf("different texts differ in only %d bits", d)
+	}
+	if SimHash("Read the [guide](https://a.example.com).") != SimHash("read the [GUIDE](https://b.example.com/other)") {
+		t.Errorf("SimHash depends on case or link targets")
+	}
+}
+
+func TestDuplicateIndex(t *testing.T) {
+	index := NewDuplicateIndex(3)
+	index.Add(0xF0F0_F0F0_F0F0_F0F0, 1)
+	index.Add(0x0123_4567_89AB_CDEF, 2)
+
+	// Every pattern of up to three flipped bits is found, wherever the bits are
+	for _, flipped := range []uint64{0, 1, 1<<63 | 1, 1<<15 | 1<<16 | 1<<47} {
+		pageID, distance, found := index.Find(0x0123_4567_89AB_CDEF ^ flipped)
+		if !found || pageID != 2 || distance != bits.OnesCount64(flipped) {
+			t.Errorf("Find with bits %x flipped = page %d, distance %d, found %v", flipped, pageID, distance, found)
+		}
+	}
+	if _, _, found := index.Find(0x0123_4567_89AB_CDEF ^ 0xF); found {
+		t.Errorf("Find matched a hash four bits away")
+	}
+}
@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// markdownRenderer renders CommonMark with the GitHub Flavored Markdown extensions. Raw HTML is
+// passed through because scraped pages contain it, and is then cleaned by htmlPolicy.
+// Heading attributes such as `## Install {#install}` become heading ids.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAttribute()),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+var codeLanguageClassRegex = regexp.MustCompile(`^language-[\w+#.-]+$`)
+
+// htmlPolicy is the allowlist applied to all HTML rendered from scraped markdown. It extends the
+// user generated content policy with code language classes for syntax highlighting, heading ids
+// for anchors, table alignment and task list checkboxes.
+var htmlPolicy = newHTMLPolicy()
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(codeLanguageClassRegex).OnElements("code")
+	policy.AllowAttrs("id").Matching(headingIDRegex).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	policy.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	return policy
+}
+
+// GetHTMLFromMarkdown renders markdown to sanitized HTML that is safe to return to the frontend
+func GetHTMLFromMarkdown(markdown string) string {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(markdown), &buf); err != nil {
+		// Fall back to the escaped text so the source is still shown
+		return "<pre>" + html.EscapeString(markdown) + "</pre>"
+	}
+	return string(htmlPolicy.SanitizeBytes(buf.Bytes()))
+}
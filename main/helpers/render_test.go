@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetHTMLFromMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "code block keeps newlines and language",
+			markdown: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+			want:     []string{`<pre><code class="language-go">func main() {` + "\n\tfmt.Println(&#34;hi&#34;)\n}\n</code></pre>"},
+		},
+		{
+			name:     "nested lists",
+			markdown: "- one\n  - one.a\n  - one.b\n- two\n",
+			want:     []string{"<ul>\n<li>one\n<ul>\n<li>one.a</li>\n<li>one.b</li>\n</ul>\n</li>\n<li>two</li>\n</ul>"},
+		},
+		{
+			name:     "table",
+			markdown: "| Flag | Default |\n| :--- | ---: |\n| `--port` | 8080 |\n",
+			want:     []string{"<table>", "<th", "Flag</th>", "<code>--port</code></td>", `<td style="text-align: right">8080</td>`},
+		},
+		{
+			name:     "heading anchor",
+			markdown: "## Install the CLI {#install-the-cli}\n",
+			want:     []string{`<h2 id="install-the-cli">Install the CLI</h2>`},
+		},
+		{
+			name:     "task list",
+			markdown: "- [x] done\n- [ ] todo\n",
+			want:     []string{`<input checked="" disabled="" type="checkbox"> done`, `<input disabled="" type="checkbox"> todo`},
+		},
+		{
+			name:     "scripts and event handlers are removed",
+			markdown: "Hello <script>alert(1)</script><img src=\"x.png\" onerror=\"alert(2)\">\n\n<div onclick=\"alert(3)\">text</div>\n",
+			want:     []string{`<img src="x.png">`, "text"},
+			notWant:  []string{"<script", "alert", "onclick", "onerror"},
+		},
+		{
+			name:     "javascript links are removed",
+			markdown: "[click](javascript:alert(1)) and [docs](https://docs.example.com)\n",
+			want:     []string{`<a href="https://docs.example.com" rel="nofollow noopener" target="_blank">docs</a>`},
+			notWant:  []string{"javascript:"},
+		},
+		{
+			name:     "arbitrary classes are removed",
+			markdown: "<pre><code class=\"language-sh evil\">ls</code></pre>\n\n<p class=\"x\" style=\"position:fixed\">p</p>\n",
+			want:     []string{"<code>ls</code>", "<p>p</p>"},
+			notWant:  []string{"evil", "position"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetHTMLFromMarkdown(tt.markdown)
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("html does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("html contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
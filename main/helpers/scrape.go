@@ -83,112 +83,6 @@ func CleanMarkdownByStartingFromTitle(markdown string, title string) string {
 	return markdown
 }
 
-func GetHTMLFromMarkdown(markdown string) string {
-	// Split the markdown into lines
-	lines := strings.Split(markdown, "\n")
-	var html strings.Builder
-
-	// Process each line
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		// Handle headers
-		if strings.HasPrefix(line, "#") {
-			level := 0
-			for i := 0; i < len(line) && line[i] == '#'; i++ {
-				level++
-			}
-			if level <= 6 {
-				content := strings.TrimSpace(line[level:])
-				html.WriteString(fmt.Sprintf("<h%d>%s</h%d>", level, content, level))
-				continue
-			}
-		}
-
-		// Handle links [text](url)
-		linkRegex := regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
-		line = linkRegex.ReplaceAllString(line, `<a href="$2">$1</a>`)
-
-		// Handle bold **text**
-		boldRegex := regexp.MustCompile(`\*\*([^*]+)\*\*`)
-		line = boldRegex.ReplaceAllString(line, `<strong>$1</strong>`)
-
-		// Handle italic *text*
-		italicRegex := regexp.MustCompile(`\*([^*]+)\*`)
-		line = italicRegex.ReplaceAllString(line, `<em>$1</em>`)
-
-		// Handle code blocks ```
-		if strings.HasPrefix(line, "```") {
-			// Start of code block
-			html.WriteString("<pre><code>")
-			i++
-			// Collect code block content
-			for i < len(lines) && !strings.HasPrefix(lines[i], "```") {
-				html.WriteString(lines[i] + "")
-				i++
-			}
-			html.WriteString("</code></pre>")
-			continue
-		}
-
-		// Handle inline code `code`
-		codeRegex := regexp.MustCompile("`([^`]+)`")
-		line = codeRegex.ReplaceAllString(line, `<code>$1</code>`)
-
-		// Handle lists
-		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
-			html.WriteString("<ul>")
-			for i < len(lines) && (strings.HasPrefix(lines[i], "- ") || strings.HasPrefix(lines[i], "* ")) {
-				content := strings.TrimSpace(lines[i][2:])
-				html.WriteString(fmt.Sprintf("<li>%s</li>", content))
-				i++
-			}
-			html.WriteString("</ul>")
-			continue
-		}
-
-		// Handle numbered lists
-		if regexp.MustCompile(`^\d+\.`).MatchString(line) {
-			html.WriteString("<ol>")
-			for i < len(lines) && regexp.MustCompile(`^\d+\.`).MatchString(lines[i]) {
-				content := strings.TrimSpace(regexp.MustCompile(`^\d+\.`).ReplaceAllString(lines[i], ""))
-				html.WriteString(fmt.Sprintf("<li>%s</li>", content))
-				i++
-			}
-			html.WriteString("</ol>")
-			continue
-		}
-
-		// Handle blockquotes
-		if strings.HasPrefix(line, ">") {
-			html.WriteString("<blockquote>")
-			for i < len(lines) && strings.HasPrefix(lines[i], ">") {
-				content := strings.TrimSpace(lines[i][1:])
-				html.WriteString(fmt.Sprintf("<p>%s</p>", content))
-				i++
-			}
-			html.WriteString("</blockquote>")
-			continue
-		}
-
-		// Handle horizontal rules
-		if strings.TrimSpace(line) == "---" || strings.TrimSpace(line) == "***" {
-			html.WriteString("<hr>")
-			continue
-		}
-
-		// Default to paragraph
-		html.WriteString(fmt.Sprintf("<p>%s</p>", line))
-	}
-
-	return html.String()
-}
-
 func GetURLsFromHTML(logger *log.Logger, htmlContent string, baseURL string) []string {
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
@@ -334,6 +228,16 @@ func GetURLsFromMarkdown(logger *log.Logger, markdown string) ([]string, error)
 }
 
 func GetMarkdownUsingJinaReader(logger *log.Logger, inputURL string) (string, error) {
+	return getUsingJinaReader(inputURL, "")
+}
+
+// GetHTMLUsingJinaReader returns the HTML of a page as rendered by Jina Reader
+func GetHTMLUsingJinaReader(logger *log.Logger, inputURL string) (string, error) {
+	return getUsingJinaReader(inputURL, "html")
+}
+
+// getUsingJinaReader reads a page with Jina Reader in returnFormat, or markdown when it is empty
+func getUsingJinaReader(inputURL string, returnFormat string) (string, error) {
 	// Confirm that the url is a valid url
 	_, err := url.Parse(inputURL)
 	if err != nil {
@@ -350,25 +254,29 @@ func GetMarkdownUsingJinaReader(logger *log.Logger, inputURL string) (string, er
 	if err != nil {
 		return "", err
 	}
+	if returnFormat != "" {
+		req.Header.Set("X-Return-Format", returnFormat)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
 	}
 
-	markdown := string(body)
+	content := string(body)
 
 	// Warning: Target URL returned error 404: Not Found
-	if strings.Contains(markdown, "Warning: Target URL returned error 404: Not Found") {
+	if strings.Contains(content, "Warning: Target URL returned error 404: Not Found") {
 		return "", fmt.Errorf("target url returned error 404: not found")
 	}
 
-	return markdown, nil
+	return content, nil
 }
 
 func GetTitleFromJinaMarkdown(logger *log.Logger, markdown string) (string, error) {
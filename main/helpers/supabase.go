@@ -10,7 +10,12 @@ import (
 )
 
 func SaveFileToStorageFromLocalFile(ctx context.Context, logger *log.Logger, supabaseS3URL string, bucketName string, fileName string, content string, anonKey string) error {
-	file := bytes.NewReader([]byte(content))
+	return SaveBytesToStorage(ctx, logger, supabaseS3URL, bucketName, fileName, []byte(content), "", anonKey)
+}
+
+// SaveBytesToStorage uploads binary content such as images, setting the content type when it is known
+func SaveBytesToStorage(ctx context.Context, logger *log.Logger, supabaseS3URL string, bucketName string, fileName string, content []byte, contentType string, anonKey string) error {
+	file := bytes.NewReader(content)
 
 	// Construct the URL for the Supabase storage API
 	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", supabaseS3URL, bucketName, fileName)
@@ -25,6 +30,9 @@ func SaveFileToStorageFromLocalFile(ctx context.Context, logger *log.Logger, sup
 	req.Header.Set("apikey", anonKey)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", anonKey))
 	req.Header.Set("x-upsert", "true")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	// Create an HTTP client and send the request
 	httpClient := &http.Client{}
@@ -44,6 +52,11 @@ func SaveFileToStorageFromLocalFile(ctx context.Context, logger *log.Logger, sup
 	return nil
 }
 
+// GetPublicStorageURL returns the public URL of a file in the storage bucket
+func GetPublicStorageURL(supabaseURL string, bucketName string, path string) string {
+	return fmt.Sprintf("%s/storage/v1/object/public/%s/%s", supabaseURL, bucketName, path)
+}
+
 func GetFileContentFromStorage(logger *log.Logger, supabaseURL string, bucketName string, path string) (string, error) {
 	url := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", supabaseURL, bucketName, path)
 	response, err := http.Get(url)
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionSegmentRegex matches path segments such as v2, v1.4, 3.x or 2.1.0 but not bare numbers like 2024
+var versionSegmentRegex = regexp.MustCompile(`^v\d+(\.\d+){0,2}(\.x)?$|^\d+(\.\d+){1,2}(\.x)?$|^\d+\.x$`)
+
+// versionAliases are the named versions docs sites publish next to numbered ones, in order of preference
+var versionAliases = []string{"latest", "stable", "current", "next", "main", "master"}
+
+// releasedVersionAliases are the aliases that point at the newest released version
+var releasedVersionAliases = []string{"latest", "stable", "current"}
+
+// DetectDocsVersion returns the docs version of a page URL. A per-source pattern takes precedence
+// and uses its first capture group when it has one; otherwise the first version-like path segment is used.
+func DetectDocsVersion(pageURL string, versionPattern string) string {
+	if versionPattern != "" {
+		if pattern, err := regexp.Compile(versionPattern); err == nil {
+			match := pattern.FindStringSubmatch(pageURL)
+			if len(match) > 1 && match[1] != "" {
+				return strings.ToLower(match[1])
+			}
+			if len(match) == 1 {
+				return strings.ToLower(match[0])
+			}
+		}
+	}
+
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	for i, segment := range segments {
+		if i >= 4 {
+			break
+		}
+		segment = strings.ToLower(segment)
+		if versionSegmentRegex.MatchString(segment) || isVersionAlias(segment) {
+			return segment
+		}
+	}
+	return ""
+}
+
+func isVersionAlias(segment string) bool {
+	for _, alias := range versionAliases {
+		if segment == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// LatestVersion picks the version queries default to: the configured default when it exists,
+// then a released alias such as "latest", then the highest numbered version. It returns "" when none
+// of the versions can be ranked, such as dates or codenames matched by a source's version pattern.
+func LatestVersion(versions []string, defaultVersion string) string {
+	available := make(map[string]bool)
+	for _, version := range versions {
+		available[version] = true
+	}
+
+	if defaultVersion != "" && available[defaultVersion] {
+		return defaultVersion
+	}
+	for _, alias := range releasedVersionAliases {
+		if available[alias] {
+			return alias
+		}
+	}
+
+	latest := ""
+	for _, version := range versions {
+		if !versionSegmentRegex.MatchString(version) {
+			continue
+		}
+		if latest == "" || CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+
+	// Only unreleased aliases such as "main" or "next" are left
+	for _, alias := range versionAliases {
+		if available[alias] {
+			return alias
+		}
+	}
+	return ""
+}
+
+// CompareVersions compares two numbered versions such as v1.10 and 1.9.x,
+// returning a negative number, zero or a positive number
+func CompareVersions(a string, b string) int {
+	partsA := versionParts(a)
+	partsB := versionParts(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var partA, partB int
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		if partA != partB {
+			return partA - partB
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(version), "v"), ".x")
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
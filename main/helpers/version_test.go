@@ -0,0 +1,58 @@
+package helpers
+
+import "testing"
+
+func TestDetectDocsVersion(t *testing.T) {
+	tests := []struct {
+		url     string
+		pattern string
+		want    string
+	}{
+		{"https://acme.dev/docs/v2/install", "", "v2"},
+		{"https://acme.dev/docs/1.4.x/install", "", "1.4.x"},
+		{"https://acme.dev/Latest/install", "", "latest"},
+		{"https://acme.dev/blog/2024/release", "", ""},
+		{"https://acme.dev/docs/install", "", ""},
+		{"https://acme.dev/docs/2024-10/install", `/docs/(\d{4}-\d{2})/`, "2024-10"},
+	}
+	for _, test := range tests {
+		if got := DetectDocsVersion(test.url, test.pattern); got != test.want {
+			t.Errorf("DetectDocsVersion(%s, %q) = %q, want %q", test.url, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	tests := []struct {
+		versions       []string
+		defaultVersion string
+		want           string
+	}{
+		{[]string{"v1.9", "v1.10", "v1.2"}, "", "v1.10"},
+		{[]string{"v2", "latest", "next"}, "", "latest"},
+		{[]string{"v2", "v3", "next"}, "v2", "v2"},
+		// A default without pages is ignored
+		{[]string{"v2", "v3"}, "v4", "v3"},
+		{[]string{"main", "next"}, "", "next"},
+		// Versions from a custom pattern cannot be ranked
+		{[]string{"2024-10", "2025-01"}, "", ""},
+		{[]string{"2024-10", "2025-01"}, "2025-01", "2025-01"},
+	}
+	for _, test := range tests {
+		if got := LatestVersion(test.versions, test.defaultVersion); got != test.want {
+			t.Errorf("LatestVersion(%v, %q) = %q, want %q", test.versions, test.defaultVersion, got, test.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	if CompareVersions("v1.10", "1.9.x") <= 0 {
+		t.Errorf("v1.10 is not after 1.9.x")
+	}
+	if CompareVersions("v2", "2.0") != 0 {
+		t.Errorf("v2 and 2.0 are not equal")
+	}
+	if CompareVersions("1.2.3", "1.3") >= 0 {
+		t.Errorf("1.2.3 is not before 1.3")
+	}
+}
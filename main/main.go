@@ -12,6 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+	"github.com/itsmaleen/tech-doc-processor/handlers"
 	"github.com/mendableai/firecrawl-go"
 )
 
@@ -28,6 +31,9 @@ func main() {
 	}
 }
 
+// ragToolsStartupTimeout is how long the server waits for rag-tools to be serving when it starts
+const ragToolsStartupTimeout = 30 * time.Second
+
 func run(ctx context.Context, args []string, getenv func(string) string) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
@@ -61,21 +67,86 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 		return err
 	}
 
-	if getenv("RAG_TOOLS_HOST") == "" {
-		return fmt.Errorf("RAG_TOOLS_HOST must be set")
+	// `server cutover-embeddings -model <model>` switches the default embedding model and exits
+	if len(args) > 1 && args[1] == cutoverCommand {
+		defer pgsqlConnection.Close()
+		return runEmbeddingCutover(ctx, os.Stdout, pgsqlConnection, args[2:])
 	}
 
-	ragToolsService, err := NewRAGToolsService(getenv("RAG_TOOLS_HOST"))
-	if err != nil {
-		l.Printf("error creating RAGToolsService: %v\n", err)
-		return err
+	// CHUNKER selects where markdown is chunked. It defaults to the rag-tools service when RAG_TOOLS_HOST is set.
+	chunkerKind := getenv("CHUNKER")
+	if chunkerKind == "" {
+		chunkerKind = chunker.KindLocal
+		if getenv("RAG_TOOLS_HOST") != "" {
+			chunkerKind = chunker.KindGRPC
+		}
+	}
+
+	readinessChecks := []handlers.ReadinessCheck{{Name: "postgres", Check: pgsqlConnection.Ping}}
+
+	var markdownChunker chunker.Chunker
+	var ragToolsService *RAGToolsService
+	switch chunkerKind {
+	case chunker.KindLocal:
+		markdownChunker = chunker.NewLocal()
+	case chunker.KindStructured:
+		markdownChunker = chunker.NewStructured()
+	case chunker.KindGRPC:
+		if getenv("RAG_TOOLS_HOST") == "" {
+			return fmt.Errorf("RAG_TOOLS_HOST must be set")
+		}
+
+		ragToolsConfig, err := RAGToolsConfigFromEnv(getenv)
+		if err != nil {
+			return err
+		}
+		ragToolsService, err = NewRAGToolsService(ragToolsConfig)
+		if err != nil {
+			l.Printf("error creating RAGToolsService: %v\n", err)
+			return err
+		}
+		// Chunking cannot work without rag-tools, so the server only starts once it is serving
+		if err := ragToolsService.WaitUntilServing(ctx, ragToolsStartupTimeout); err != nil {
+			ragToolsService.Close()
+			return err
+		}
+		markdownChunker = chunker.NewGRPC(ragToolsService.Client)
+		readinessChecks = append(readinessChecks, handlers.ReadinessCheck{Name: "rag-tools", Check: ragToolsService.CheckHealth})
+	default:
+		return fmt.Errorf("CHUNKER must be %s, %s or %s", chunker.KindLocal, chunker.KindStructured, chunker.KindGRPC)
 	}
+	l.Printf("Chunking markdown with the %s chunker", chunkerKind)
 
+	// Answers are generated with Gemini whichever embedder is used
 	geminiApiKey := getenv("GEMINI_API_KEY")
 	if geminiApiKey == "" {
 		return fmt.Errorf("GEMINI_API_KEY must be set")
 	}
 
+	// EMBEDDER selects where chunks and queries are embedded. It defaults to the Gemini API, and
+	// EMBEDDER_2 and so on add models that chunks are embedded with next to it.
+	embedderConfigs, err := embedder.ConfigsFromEnv(getenv)
+	if err != nil {
+		return err
+	}
+	var textEmbedders []embedder.Embedder
+	for _, config := range embedderConfigs {
+		textEmbedder, err := embedder.New(config)
+		if err != nil {
+			return err
+		}
+		if textEmbedder.Dimensions() == 0 {
+			l.Printf("Embedding with the %s embedder and model %s at the model's own size", config.Kind, textEmbedder.Model())
+		} else {
+			l.Printf("Embedding with the %s embedder, model %s and %d dimensions", config.Kind, textEmbedder.Model(), textEmbedder.Dimensions())
+		}
+		textEmbedders = append(textEmbedders, textEmbedder)
+	}
+	embedders, err := embedder.NewRegistry(textEmbedders...)
+	if err != nil {
+		return err
+	}
+
 	supabaseURL := getenv("SUPABASE_URL")
 	if supabaseURL == "" {
 		return fmt.Errorf("SUPABASE_URL must be set")
@@ -104,7 +175,7 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 		return fmt.Errorf("BACKEND_URL must be set")
 	}
 
-	srv := Server(l, pgsqlConnection, ragToolsService.Client, geminiApiKey, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient, backendURL)
+	srv := Server(ctx, l, pgsqlConnection, markdownChunker, readinessChecks, embedders, geminiApiKey, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient, backendURL)
 
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort("0.0.0.0", "8080"),
@@ -125,9 +196,14 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
 		defer pgsqlConnection.Close()
+		if ragToolsService != nil {
+			defer ragToolsService.Close()
+		}
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
 			fmt.Fprintf(os.Stderr, "error shutting down http server: %s\n", err)
 		}
+		// Background re-chunks stop with ctx and clean up before the connection is closed
+		handlers.WaitForBackgroundJobs()
 	}()
 	wg.Wait()
 	return nil
@@ -21,11 +21,69 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ContentType describes what a chunk is made of
+type ContentType int32
+
+const (
+	ContentType_CONTENT_TYPE_UNSPECIFIED ContentType = 0
+	ContentType_CONTENT_TYPE_TEXT        ContentType = 1
+	ContentType_CONTENT_TYPE_CODE        ContentType = 2
+	ContentType_CONTENT_TYPE_TABLE       ContentType = 3
+	ContentType_CONTENT_TYPE_MIXED       ContentType = 4
+)
+
+// Enum value maps for ContentType.
+var (
+	ContentType_name = map[int32]string{
+		0: "CONTENT_TYPE_UNSPECIFIED",
+		1: "CONTENT_TYPE_TEXT",
+		2: "CONTENT_TYPE_CODE",
+		3: "CONTENT_TYPE_TABLE",
+		4: "CONTENT_TYPE_MIXED",
+	}
+	ContentType_value = map[string]int32{
+		"CONTENT_TYPE_UNSPECIFIED": 0,
+		"CONTENT_TYPE_TEXT":        1,
+		"CONTENT_TYPE_CODE":        2,
+		"CONTENT_TYPE_TABLE":       3,
+		"CONTENT_TYPE_MIXED":       4,
+	}
+)
+
+func (x ContentType) Enum() *ContentType {
+	p := new(ContentType)
+	*p = x
+	return p
+}
+
+func (x ContentType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ContentType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_rag_tools_proto_enumTypes[0].Descriptor()
+}
+
+func (ContentType) Type() protoreflect.EnumType {
+	return &file_proto_rag_tools_proto_enumTypes[0]
+}
+
+func (x ContentType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ContentType.Descriptor instead.
+func (ContentType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_rag_tools_proto_rawDescGZIP(), []int{0}
+}
+
 type ChunkMarkdownRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
-	ChunkSize     int32                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
-	Overlap       int32                  `protobuf:"varint,3,opt,name=overlap,proto3" json:"overlap,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Content   string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	ChunkSize int32                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	Overlap   int32                  `protobuf:"varint,3,opt,name=overlap,proto3" json:"overlap,omitempty"`
+	// tokenizer counts chunk_size and overlap: "characters" (the default) or "approximate"
+	Tokenizer     string `protobuf:"bytes,4,opt,name=tokenizer,proto3" json:"tokenizer,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,9 +139,16 @@ func (x *ChunkMarkdownRequest) GetOverlap() int32 {
 	return 0
 }
 
+func (x *ChunkMarkdownRequest) GetTokenizer() string {
+	if x != nil {
+		return x.Tokenizer
+	}
+	return ""
+}
+
 type ChunkMarkdownResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Chunks        []string               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Chunks        []*Chunk               `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -118,27 +183,326 @@ func (*ChunkMarkdownResponse) Descriptor() ([]byte, []int) {
 	return file_proto_rag_tools_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *ChunkMarkdownResponse) GetChunks() []string {
+func (x *ChunkMarkdownResponse) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+type Chunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Text  string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// start_offset and end_offset are UTF-8 byte offsets of the chunk in the request content
+	StartOffset int32 `protobuf:"varint,2,opt,name=start_offset,json=startOffset,proto3" json:"start_offset,omitempty"`
+	EndOffset   int32 `protobuf:"varint,3,opt,name=end_offset,json=endOffset,proto3" json:"end_offset,omitempty"`
+	// heading_path lists the headings the chunk is under, outermost first, such as "## Install"
+	HeadingPath   []string    `protobuf:"bytes,4,rep,name=heading_path,json=headingPath,proto3" json:"heading_path,omitempty"`
+	TokenCount    int32       `protobuf:"varint,5,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
+	ContentType   ContentType `protobuf:"varint,6,opt,name=content_type,json=contentType,proto3,enum=rag_tools.ContentType" json:"content_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_proto_rag_tools_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rag_tools_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_proto_rag_tools_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Chunk) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Chunk) GetStartOffset() int32 {
+	if x != nil {
+		return x.StartOffset
+	}
+	return 0
+}
+
+func (x *Chunk) GetEndOffset() int32 {
+	if x != nil {
+		return x.EndOffset
+	}
+	return 0
+}
+
+func (x *Chunk) GetHeadingPath() []string {
+	if x != nil {
+		return x.HeadingPath
+	}
+	return nil
+}
+
+func (x *Chunk) GetTokenCount() int32 {
+	if x != nil {
+		return x.TokenCount
+	}
+	return 0
+}
+
+func (x *Chunk) GetContentType() ContentType {
+	if x != nil {
+		return x.ContentType
+	}
+	return ContentType_CONTENT_TYPE_UNSPECIFIED
+}
+
+type Page struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Page) Reset() {
+	*x = Page{}
+	mi := &file_proto_rag_tools_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Page) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Page) ProtoMessage() {}
+
+func (x *Page) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rag_tools_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Page.ProtoReflect.Descriptor instead.
+func (*Page) Descriptor() ([]byte, []int) {
+	return file_proto_rag_tools_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Page) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Page) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type ChunkMarkdownBatchRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Pages     []*Page                `protobuf:"bytes,1,rep,name=pages,proto3" json:"pages,omitempty"`
+	ChunkSize int32                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	Overlap   int32                  `protobuf:"varint,3,opt,name=overlap,proto3" json:"overlap,omitempty"`
+	// tokenizer counts chunk_size and overlap: "characters" (the default) or "approximate"
+	Tokenizer     string `protobuf:"bytes,4,opt,name=tokenizer,proto3" json:"tokenizer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChunkMarkdownBatchRequest) Reset() {
+	*x = ChunkMarkdownBatchRequest{}
+	mi := &file_proto_rag_tools_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkMarkdownBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkMarkdownBatchRequest) ProtoMessage() {}
+
+func (x *ChunkMarkdownBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rag_tools_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkMarkdownBatchRequest.ProtoReflect.Descriptor instead.
+func (*ChunkMarkdownBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rag_tools_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChunkMarkdownBatchRequest) GetPages() []*Page {
+	if x != nil {
+		return x.Pages
+	}
+	return nil
+}
+
+func (x *ChunkMarkdownBatchRequest) GetChunkSize() int32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *ChunkMarkdownBatchRequest) GetOverlap() int32 {
+	if x != nil {
+		return x.Overlap
+	}
+	return 0
+}
+
+func (x *ChunkMarkdownBatchRequest) GetTokenizer() string {
+	if x != nil {
+		return x.Tokenizer
+	}
+	return ""
+}
+
+// PageChunks holds the chunks of one page, or the error chunking it
+type PageChunks struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageId        int64                  `protobuf:"varint,1,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
+	Chunks        []*Chunk               `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageChunks) Reset() {
+	*x = PageChunks{}
+	mi := &file_proto_rag_tools_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageChunks) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageChunks) ProtoMessage() {}
+
+func (x *PageChunks) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rag_tools_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageChunks.ProtoReflect.Descriptor instead.
+func (*PageChunks) Descriptor() ([]byte, []int) {
+	return file_proto_rag_tools_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PageChunks) GetPageId() int64 {
+	if x != nil {
+		return x.PageId
+	}
+	return 0
+}
+
+func (x *PageChunks) GetChunks() []*Chunk {
 	if x != nil {
 		return x.Chunks
 	}
 	return nil
 }
 
+func (x *PageChunks) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 var File_proto_rag_tools_proto protoreflect.FileDescriptor
 
 const file_proto_rag_tools_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/rag-tools.proto\x12\trag_tools\"i\n" +
+	"\x15proto/rag-tools.proto\x12\trag_tools\"\x87\x01\n" +
 	"\x14ChunkMarkdownRequest\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\tR\acontent\x12\x1d\n" +
 	"\n" +
 	"chunk_size\x18\x02 \x01(\x05R\tchunkSize\x12\x18\n" +
-	"\aoverlap\x18\x03 \x01(\x05R\aoverlap\"/\n" +
-	"\x15ChunkMarkdownResponse\x12\x16\n" +
-	"\x06chunks\x18\x01 \x03(\tR\x06chunks2n\n" +
+	"\aoverlap\x18\x03 \x01(\x05R\aoverlap\x12\x1c\n" +
+	"\ttokenizer\x18\x04 \x01(\tR\ttokenizer\"G\n" +
+	"\x15ChunkMarkdownResponse\x12(\n" +
+	"\x06chunks\x18\x02 \x03(\v2\x10.rag_tools.ChunkR\x06chunksJ\x04\b\x01\x10\x02\"\xdc\x01\n" +
+	"\x05Chunk\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12!\n" +
+	"\fstart_offset\x18\x02 \x01(\x05R\vstartOffset\x12\x1d\n" +
+	"\n" +
+	"end_offset\x18\x03 \x01(\x05R\tendOffset\x12!\n" +
+	"\fheading_path\x18\x04 \x03(\tR\vheadingPath\x12\x1f\n" +
+	"\vtoken_count\x18\x05 \x01(\x05R\n" +
+	"tokenCount\x129\n" +
+	"\fcontent_type\x18\x06 \x01(\x0e2\x16.rag_tools.ContentTypeR\vcontentType\"0\n" +
+	"\x04Page\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\x99\x01\n" +
+	"\x19ChunkMarkdownBatchRequest\x12%\n" +
+	"\x05pages\x18\x01 \x03(\v2\x0f.rag_tools.PageR\x05pages\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x02 \x01(\x05R\tchunkSize\x12\x18\n" +
+	"\aoverlap\x18\x03 \x01(\x05R\aoverlap\x12\x1c\n" +
+	"\ttokenizer\x18\x04 \x01(\tR\ttokenizer\"e\n" +
+	"\n" +
+	"PageChunks\x12\x17\n" +
+	"\apage_id\x18\x01 \x01(\x03R\x06pageId\x12(\n" +
+	"\x06chunks\x18\x02 \x03(\v2\x10.rag_tools.ChunkR\x06chunks\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error*\x89\x01\n" +
+	"\vContentType\x12\x1c\n" +
+	"\x18CONTENT_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11CONTENT_TYPE_TEXT\x10\x01\x12\x15\n" +
+	"\x11CONTENT_TYPE_CODE\x10\x02\x12\x16\n" +
+	"\x12CONTENT_TYPE_TABLE\x10\x03\x12\x16\n" +
+	"\x12CONTENT_TYPE_MIXED\x10\x042\xc5\x01\n" +
 	"\x16MarkdownChunkerService\x12T\n" +
-	"\rChunkMarkdown\x12\x1f.rag_tools.ChunkMarkdownRequest\x1a .rag_tools.ChunkMarkdownResponse\"\x00B\x11Z\x0fproto/rag-toolsb\x06proto3"
+	"\rChunkMarkdown\x12\x1f.rag_tools.ChunkMarkdownRequest\x1a .rag_tools.ChunkMarkdownResponse\"\x00\x12U\n" +
+	"\x12ChunkMarkdownBatch\x12$.rag_tools.ChunkMarkdownBatchRequest\x1a\x15.rag_tools.PageChunks\"\x000\x01B\x11Z\x0fproto/rag-toolsb\x06proto3"
 
 var (
 	file_proto_rag_tools_proto_rawDescOnce sync.Once
@@ -152,19 +516,31 @@ func file_proto_rag_tools_proto_rawDescGZIP() []byte {
 	return file_proto_rag_tools_proto_rawDescData
 }
 
-var file_proto_rag_tools_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_rag_tools_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_rag_tools_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_rag_tools_proto_goTypes = []any{
-	(*ChunkMarkdownRequest)(nil),  // 0: rag_tools.ChunkMarkdownRequest
-	(*ChunkMarkdownResponse)(nil), // 1: rag_tools.ChunkMarkdownResponse
+	(ContentType)(0),                  // 0: rag_tools.ContentType
+	(*ChunkMarkdownRequest)(nil),      // 1: rag_tools.ChunkMarkdownRequest
+	(*ChunkMarkdownResponse)(nil),     // 2: rag_tools.ChunkMarkdownResponse
+	(*Chunk)(nil),                     // 3: rag_tools.Chunk
+	(*Page)(nil),                      // 4: rag_tools.Page
+	(*ChunkMarkdownBatchRequest)(nil), // 5: rag_tools.ChunkMarkdownBatchRequest
+	(*PageChunks)(nil),                // 6: rag_tools.PageChunks
 }
 var file_proto_rag_tools_proto_depIdxs = []int32{
-	0, // 0: rag_tools.MarkdownChunkerService.ChunkMarkdown:input_type -> rag_tools.ChunkMarkdownRequest
-	1, // 1: rag_tools.MarkdownChunkerService.ChunkMarkdown:output_type -> rag_tools.ChunkMarkdownResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	3, // 0: rag_tools.ChunkMarkdownResponse.chunks:type_name -> rag_tools.Chunk
+	0, // 1: rag_tools.Chunk.content_type:type_name -> rag_tools.ContentType
+	4, // 2: rag_tools.ChunkMarkdownBatchRequest.pages:type_name -> rag_tools.Page
+	3, // 3: rag_tools.PageChunks.chunks:type_name -> rag_tools.Chunk
+	1, // 4: rag_tools.MarkdownChunkerService.ChunkMarkdown:input_type -> rag_tools.ChunkMarkdownRequest
+	5, // 5: rag_tools.MarkdownChunkerService.ChunkMarkdownBatch:input_type -> rag_tools.ChunkMarkdownBatchRequest
+	2, // 6: rag_tools.MarkdownChunkerService.ChunkMarkdown:output_type -> rag_tools.ChunkMarkdownResponse
+	6, // 7: rag_tools.MarkdownChunkerService.ChunkMarkdownBatch:output_type -> rag_tools.PageChunks
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_proto_rag_tools_proto_init() }
@@ -177,13 +553,14 @@ func file_proto_rag_tools_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_rag_tools_proto_rawDesc), len(file_proto_rag_tools_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_rag_tools_proto_goTypes,
 		DependencyIndexes: file_proto_rag_tools_proto_depIdxs,
+		EnumInfos:         file_proto_rag_tools_proto_enumTypes,
 		MessageInfos:      file_proto_rag_tools_proto_msgTypes,
 	}.Build()
 	File_proto_rag_tools_proto = out.File
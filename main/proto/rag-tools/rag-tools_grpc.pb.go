@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MarkdownChunkerService_ChunkMarkdown_FullMethodName = "/rag_tools.MarkdownChunkerService/ChunkMarkdown"
+	MarkdownChunkerService_ChunkMarkdown_FullMethodName      = "/rag_tools.MarkdownChunkerService/ChunkMarkdown"
+	MarkdownChunkerService_ChunkMarkdownBatch_FullMethodName = "/rag_tools.MarkdownChunkerService/ChunkMarkdownBatch"
 )
 
 // MarkdownChunkerServiceClient is the client API for MarkdownChunkerService service.
@@ -27,6 +28,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MarkdownChunkerServiceClient interface {
 	ChunkMarkdown(ctx context.Context, in *ChunkMarkdownRequest, opts ...grpc.CallOption) (*ChunkMarkdownResponse, error)
+	// ChunkMarkdownBatch chunks many pages and streams back the chunks of each page as it is done
+	ChunkMarkdownBatch(ctx context.Context, in *ChunkMarkdownBatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PageChunks], error)
 }
 
 type markdownChunkerServiceClient struct {
@@ -47,11 +50,32 @@ func (c *markdownChunkerServiceClient) ChunkMarkdown(ctx context.Context, in *Ch
 	return out, nil
 }
 
+func (c *markdownChunkerServiceClient) ChunkMarkdownBatch(ctx context.Context, in *ChunkMarkdownBatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PageChunks], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MarkdownChunkerService_ServiceDesc.Streams[0], MarkdownChunkerService_ChunkMarkdownBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChunkMarkdownBatchRequest, PageChunks]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarkdownChunkerService_ChunkMarkdownBatchClient = grpc.ServerStreamingClient[PageChunks]
+
 // MarkdownChunkerServiceServer is the server API for MarkdownChunkerService service.
 // All implementations must embed UnimplementedMarkdownChunkerServiceServer
 // for forward compatibility.
 type MarkdownChunkerServiceServer interface {
 	ChunkMarkdown(context.Context, *ChunkMarkdownRequest) (*ChunkMarkdownResponse, error)
+	// ChunkMarkdownBatch chunks many pages and streams back the chunks of each page as it is done
+	ChunkMarkdownBatch(*ChunkMarkdownBatchRequest, grpc.ServerStreamingServer[PageChunks]) error
 	mustEmbedUnimplementedMarkdownChunkerServiceServer()
 }
 
@@ -65,6 +89,9 @@ type UnimplementedMarkdownChunkerServiceServer struct{}
 func (UnimplementedMarkdownChunkerServiceServer) ChunkMarkdown(context.Context, *ChunkMarkdownRequest) (*ChunkMarkdownResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChunkMarkdown not implemented")
 }
+func (UnimplementedMarkdownChunkerServiceServer) ChunkMarkdownBatch(*ChunkMarkdownBatchRequest, grpc.ServerStreamingServer[PageChunks]) error {
+	return status.Errorf(codes.Unimplemented, "method ChunkMarkdownBatch not implemented")
+}
 func (UnimplementedMarkdownChunkerServiceServer) mustEmbedUnimplementedMarkdownChunkerServiceServer() {
 }
 func (UnimplementedMarkdownChunkerServiceServer) testEmbeddedByValue() {}
@@ -105,6 +132,17 @@ func _MarkdownChunkerService_ChunkMarkdown_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MarkdownChunkerService_ChunkMarkdownBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChunkMarkdownBatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarkdownChunkerServiceServer).ChunkMarkdownBatch(m, &grpc.GenericServerStream[ChunkMarkdownBatchRequest, PageChunks]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarkdownChunkerService_ChunkMarkdownBatchServer = grpc.ServerStreamingServer[PageChunks]
+
 // MarkdownChunkerService_ServiceDesc is the grpc.ServiceDesc for MarkdownChunkerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -117,6 +155,12 @@ var MarkdownChunkerService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _MarkdownChunkerService_ChunkMarkdown_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ChunkMarkdownBatch",
+			Handler:       _MarkdownChunkerService_ChunkMarkdownBatch_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "proto/rag-tools.proto",
 }
@@ -2,22 +2,138 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
 
 	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// ragToolsServiceName is the name the rag-tools service reports its health under
+var ragToolsServiceName = pb.MarkdownChunkerService_ServiceDesc.ServiceName
+
+// ragToolsServiceConfig retries calls that fail with UNAVAILABLE, such as while rag-tools restarts,
+// and checks the health of the service so calls are only sent to it while it is serving
+var ragToolsServiceConfig = fmt.Sprintf(`{
+	"methodConfig": [{
+		"name": [{"service": %[1]q}],
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "0.2s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}],
+	"healthCheckConfig": {"serviceName": %[1]q}
+}`, ragToolsServiceName)
+
+// ragToolsMaxMessageSize is the largest message sent to or received from rag-tools. rag-tools
+// accepts messages of the same size, so batches of large pages fit in one call.
+const ragToolsMaxMessageSize = 64 << 20
+
+// DefaultRAGToolsCallTimeout is the deadline of calls to rag-tools that have none
+const DefaultRAGToolsCallTimeout = time.Minute
+
+// RAGToolsConfig is how to connect to the rag-tools service
+type RAGToolsConfig struct {
+	Address string
+	// CallTimeout is the deadline given to calls whose context has none
+	CallTimeout time.Duration
+	// TLS enables TLS. CAFile verifies the server with a private CA instead of the system roots,
+	// and CertFile and KeyFile are the client certificate for mTLS.
+	TLS        bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// RAGToolsConfigFromEnv reads the RAG_TOOLS_* settings
+func RAGToolsConfigFromEnv(getenv func(string) string) (RAGToolsConfig, error) {
+	config := RAGToolsConfig{
+		Address:     getenv("RAG_TOOLS_HOST"),
+		CallTimeout: DefaultRAGToolsCallTimeout,
+		CAFile:      getenv("RAG_TOOLS_CA_FILE"),
+		CertFile:    getenv("RAG_TOOLS_CERT_FILE"),
+		KeyFile:     getenv("RAG_TOOLS_KEY_FILE"),
+		ServerName:  getenv("RAG_TOOLS_SERVER_NAME"),
+	}
+	// TLS is turned on by RAG_TOOLS_TLS or by giving any of the files it uses
+	config.TLS = getenv("RAG_TOOLS_TLS") == "true" || config.CAFile != "" || config.CertFile != ""
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return config, fmt.Errorf("RAG_TOOLS_CERT_FILE and RAG_TOOLS_KEY_FILE must be set together")
+	}
+	if timeout := getenv("RAG_TOOLS_TIMEOUT"); timeout != "" {
+		callTimeout, err := time.ParseDuration(timeout)
+		if err != nil || callTimeout <= 0 {
+			return config, fmt.Errorf("RAG_TOOLS_TIMEOUT must be a duration such as 30s")
+		}
+		config.CallTimeout = callTimeout
+	}
+	return config, nil
+}
+
+// transportCredentials returns the credentials of the connection to rag-tools
+func (c RAGToolsConfig) transportCredentials() (credentials.TransportCredentials, error) {
+	if !c.TLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName}
+	if c.CAFile != "" {
+		ca, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RAG_TOOLS_CA_FILE: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("RAG_TOOLS_CA_FILE has no PEM certificates")
+		}
+	}
+	if c.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the rag-tools client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
 // TFIDFService wraps the TFIDF client and provides methods for text analysis
 type RAGToolsService struct {
 	Client pb.MarkdownChunkerServiceClient
+	Health healthpb.HealthClient
 	Conn   *grpc.ClientConn
 }
 
 // NewRAGToolsService creates a new RAGTools service instance
-func NewRAGToolsService(address string) (*RAGToolsService, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewRAGToolsService(config RAGToolsConfig) (*RAGToolsService, error) {
+	creds, err := config.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	callTimeout := config.CallTimeout
+	if callTimeout <= 0 {
+		callTimeout = DefaultRAGToolsCallTimeout
+	}
+
+	conn, err := grpc.NewClient(config.Address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(ragToolsServiceConfig),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(ragToolsMaxMessageSize), grpc.MaxCallRecvMsgSize(ragToolsMaxMessageSize)),
+		grpc.WithChainUnaryInterceptor(unaryCallTimeout(callTimeout)),
+		grpc.WithChainStreamInterceptor(streamCallTimeout(callTimeout)),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +141,7 @@ func NewRAGToolsService(address string) (*RAGToolsService, error) {
 	client := pb.NewMarkdownChunkerServiceClient(conn)
 	return &RAGToolsService{
 		Client: client,
+		Health: healthpb.NewHealthClient(conn),
 		Conn:   conn,
 	}, nil
 }
@@ -34,7 +151,41 @@ func (s *RAGToolsService) Close() error {
 	return s.Conn.Close()
 }
 
-func (s *RAGToolsService) ChunkMarkdown(ctx context.Context, content string, chunkSize, overlap int32) ([]string, error) {
+// CheckHealth asks rag-tools whether the chunker service is serving, with the standard gRPC health
+// checking protocol
+func (s *RAGToolsService) CheckHealth(ctx context.Context) error {
+	response, err := s.Health.Check(ctx, &healthpb.HealthCheckRequest{Service: ragToolsServiceName})
+	if err != nil {
+		return fmt.Errorf("rag-tools health check failed: %w", err)
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("rag-tools is %s", response.Status)
+	}
+	return nil
+}
+
+// WaitUntilServing checks the health of rag-tools until it is serving or timeout has passed
+func (s *RAGToolsService) WaitUntilServing(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		checkCtx, cancelCheck := context.WithTimeout(ctx, 5*time.Second)
+		err := s.CheckHealth(checkCtx)
+		cancelCheck()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("rag-tools did not become ready within %s: %w", timeout, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (s *RAGToolsService) ChunkMarkdown(ctx context.Context, content string, chunkSize, overlap int32) ([]*pb.Chunk, error) {
 	request := &pb.ChunkMarkdownRequest{
 		Content:   content,
 		ChunkSize: chunkSize,
@@ -48,3 +199,34 @@ func (s *RAGToolsService) ChunkMarkdown(ctx context.Context, content string, chu
 
 	return response.Chunks, nil
 }
+
+// unaryCallTimeout gives calls without a deadline one, so a hung rag-tools cannot block a request
+// forever
+func unaryCallTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// streamCallTimeout gives streams without a deadline one. The deadline covers the whole stream, and
+// its timer is released when the stream ends.
+func streamCallTimeout(timeout time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if _, ok := ctx.Deadline(); ok {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		context.AfterFunc(stream.Context(), cancel)
+		return stream, nil
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// deadlineChunker records the deadline of the calls it receives
+type deadlineChunker struct {
+	pb.UnimplementedMarkdownChunkerServiceServer
+	deadlines chan time.Time
+}
+
+func (c *deadlineChunker) ChunkMarkdown(ctx context.Context, request *pb.ChunkMarkdownRequest) (*pb.ChunkMarkdownResponse, error) {
+	deadline, _ := ctx.Deadline()
+	c.deadlines <- deadline
+	return &pb.ChunkMarkdownResponse{}, nil
+}
+
+func startRAGTools(t *testing.T) (string, *health.Server, *deadlineChunker) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	chunker := &deadlineChunker{deadlines: make(chan time.Time, 1)}
+	healthpb.RegisterHealthServer(server, healthServer)
+	pb.RegisterMarkdownChunkerServiceServer(server, chunker)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String(), healthServer, chunker
+}
+
+func TestRAGToolsHealth(t *testing.T) {
+	address, healthServer, _ := startRAGTools(t)
+	healthServer.SetServingStatus(ragToolsServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	service, err := NewRAGToolsService(RAGToolsConfig{Address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	if err := service.CheckHealth(context.Background()); err == nil {
+		t.Errorf("CheckHealth succeeded while rag-tools is not serving")
+	}
+
+	time.AfterFunc(500*time.Millisecond, func() {
+		healthServer.SetServingStatus(ragToolsServiceName, healthpb.HealthCheckResponse_SERVING)
+	})
+	if err := service.WaitUntilServing(context.Background(), 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CheckHealth(context.Background()); err != nil {
+		t.Errorf("CheckHealth = %v once rag-tools is serving", err)
+	}
+}
+
+func TestRAGToolsCallTimeout(t *testing.T) {
+	address, healthServer, chunker := startRAGTools(t)
+	healthServer.SetServingStatus(ragToolsServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	service, err := NewRAGToolsService(RAGToolsConfig{Address: address, CallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	start := time.Now()
+	if _, err := service.ChunkMarkdown(context.Background(), "# Title", 100, 10); err != nil {
+		t.Fatal(err)
+	}
+	deadline := <-chunker.deadlines
+	if deadline.IsZero() || deadline.Sub(start) > 6*time.Second {
+		t.Errorf("call without a deadline got deadline %v after its start, want about 5s", deadline.Sub(start))
+	}
+}
+
+func TestRAGToolsConfigFromEnv(t *testing.T) {
+	env := map[string]string{"RAG_TOOLS_HOST": "rag-tools:50051", "RAG_TOOLS_CA_FILE": "ca.pem", "RAG_TOOLS_TIMEOUT": "30s"}
+	config, err := RAGToolsConfigFromEnv(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.TLS || config.CallTimeout != 30*time.Second {
+		t.Errorf("got TLS %v and call timeout %v, want TLS and 30s", config.TLS, config.CallTimeout)
+	}
+
+	env["RAG_TOOLS_CERT_FILE"] = "client.pem"
+	if _, err := RAGToolsConfigFromEnv(func(key string) string { return env[key] }); err == nil {
+		t.Errorf("a client certificate without a key was accepted")
+	}
+}
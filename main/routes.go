@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
 	"github.com/itsmaleen/tech-doc-processor/handlers"
-	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mendableai/firecrawl-go"
 )
@@ -24,10 +26,13 @@ func loggingMiddleware(logger *log.Logger, next http.HandlerFunc) http.HandlerFu
 }
 
 func addRoutes(
+	ctx context.Context,
 	mux *http.ServeMux,
 	logger *log.Logger,
 	pgxConn *pgxpool.Pool,
-	ragToolsServiceClient pb.MarkdownChunkerServiceClient,
+	markdownChunker chunker.Chunker,
+	readinessChecks []handlers.ReadinessCheck,
+	embedders *embedder.Registry,
 	geminiApiKey string,
 	supabaseURL string,
 	supabaseAnonKey string,
@@ -35,6 +40,10 @@ func addRoutes(
 	firecrawlClient *firecrawl.FirecrawlApp,
 	backendURL string,
 ) {
+	// Health Routes
+	mux.HandleFunc("/healthz", handlers.HandleLiveness())
+	mux.HandleFunc("/readyz", handlers.HandleReadiness(logger, readinessChecks))
+
 	// Documentation Routes
 	mux.HandleFunc("/api/docs/list", loggingMiddleware(logger, handlers.HandleLoadDocPaths(logger, pgxConn)))
 	mux.HandleFunc("/api/docs/content", loggingMiddleware(logger, handlers.HandleLoadDocsContent(logger, pgxConn, supabaseURL, supabaseStorageBucket)))
@@ -45,14 +54,24 @@ func addRoutes(
 	mux.HandleFunc("/api/scraper/jina", loggingMiddleware(logger, handlers.HandleScrapeURLsUsingJina(logger, pgxConn, supabaseURL, supabaseAnonKey, supabaseStorageBucket)))
 	mux.HandleFunc("/api/scraper/raw", loggingMiddleware(logger, handlers.HandleScrapeDocsRaw(logger, pgxConn, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient)))
 	mux.HandleFunc("/api/scraper/markdown", loggingMiddleware(logger, handlers.HandlePagesWithoutMarkdownContent(logger, pgxConn, supabaseURL, supabaseAnonKey, supabaseStorageBucket)))
-	mux.HandleFunc("/api/scraper/chunk", loggingMiddleware(logger, handlers.HandleChunkingUnProcessedPages(logger, pgxConn, ragToolsServiceClient, supabaseURL, supabaseStorageBucket)))
+	mux.HandleFunc("/api/scraper/chunk", loggingMiddleware(logger, handlers.HandleChunkingUnProcessedPages(logger, pgxConn, markdownChunker, supabaseURL, supabaseStorageBucket)))
 	mux.HandleFunc("/api/scraper/firecrawl", loggingMiddleware(logger, handlers.HandleStartFirecrawlAsyncCrawl(logger, pgxConn, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient, backendURL)))
 	mux.HandleFunc("/api/scraper/firecrawl/webhook", loggingMiddleware(logger, handlers.HandleFirecrawlWebhook(logger, pgxConn, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient)))
 
+	// Source Routes
+	mux.HandleFunc("/api/sources/{id}/settings", loggingMiddleware(logger, handlers.HandleSourceSettings(logger, pgxConn)))
+	mux.HandleFunc("/api/sources/{id}/preview", loggingMiddleware(logger, handlers.HandlePreviewSourceContent(logger, pgxConn)))
+	mux.HandleFunc("/api/sources/{id}/tree", loggingMiddleware(logger, handlers.HandleSourceNavTree(logger, pgxConn)))
+	mux.HandleFunc("/api/sources/{id}/rechunk", loggingMiddleware(logger, handlers.HandleRechunkSource(ctx, logger, pgxConn, markdownChunker, embedders, supabaseURL, supabaseStorageBucket)))
+	mux.HandleFunc("/api/sources/{id}/boilerplate", loggingMiddleware(logger, handlers.HandleSourceBoilerplate(logger, pgxConn, supabaseURL, supabaseStorageBucket)))
+	mux.HandleFunc("/api/sources/{id}/links/check", loggingMiddleware(logger, handlers.HandleCheckSourceLinks(logger, pgxConn)))
+	mux.HandleFunc("/api/sources/{id}/links/report", loggingMiddleware(logger, handlers.HandleSourceLinkReport(logger, pgxConn)))
+
 	// RAG Routes
-	mux.HandleFunc("/api/rag/embeddings", loggingMiddleware(logger, handlers.HandleSaveEmbeddings(logger, pgxConn, geminiApiKey)))
-	mux.HandleFunc("/api/rag/retrieve", loggingMiddleware(logger, handlers.HandleRetrievalQuery(logger, pgxConn, geminiApiKey)))
-	mux.HandleFunc("/api/rag/query", loggingMiddleware(logger, handlers.HandleRAGQuery(logger, pgxConn, geminiApiKey)))
+	mux.HandleFunc("/api/rag/embeddings", loggingMiddleware(logger, handlers.HandleSaveEmbeddings(logger, pgxConn, embedders)))
+	mux.HandleFunc("/api/rag/retrieve", loggingMiddleware(logger, handlers.HandleRetrievalQuery(logger, pgxConn, embedders)))
+	mux.HandleFunc("/api/rag/models", loggingMiddleware(logger, handlers.HandleEmbeddingModels(logger, pgxConn)))
+	mux.HandleFunc("/api/rag/query", loggingMiddleware(logger, handlers.HandleRAGQuery(logger, pgxConn, embedders, geminiApiKey)))
 
 	// Maintenance Routes
 	mux.HandleFunc("/api/maintenance/cleanup-titles", loggingMiddleware(logger, handlers.HandleUpdatePageTitle(logger, pgxConn, supabaseURL, supabaseStorageBucket)))
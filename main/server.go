@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
-	pb "github.com/itsmaleen/tech-doc-processor/proto/rag-tools"
+	"github.com/itsmaleen/tech-doc-processor/chunker"
+	"github.com/itsmaleen/tech-doc-processor/embedder"
+	"github.com/itsmaleen/tech-doc-processor/handlers"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mendableai/firecrawl-go"
 )
@@ -28,9 +31,12 @@ func corsMiddleware(next http.Handler) http.Handler {
 }
 
 func Server(
+	ctx context.Context,
 	logger *log.Logger,
 	pgxConn *pgxpool.Pool,
-	ragToolsServiceClient pb.MarkdownChunkerServiceClient,
+	markdownChunker chunker.Chunker,
+	readinessChecks []handlers.ReadinessCheck,
+	embedders *embedder.Registry,
 	geminiApiKey string,
 	supabaseURL string,
 	supabaseAnonKey string,
@@ -39,7 +45,7 @@ func Server(
 	backendURL string,
 ) http.Handler {
 	mux := http.NewServeMux()
-	addRoutes(mux, logger, pgxConn, ragToolsServiceClient, geminiApiKey, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient, backendURL)
+	addRoutes(ctx, mux, logger, pgxConn, markdownChunker, readinessChecks, embedders, geminiApiKey, supabaseURL, supabaseAnonKey, supabaseStorageBucket, firecrawlClient, backendURL)
 
 	var handler http.Handler = mux
 	// Add CORS middleware
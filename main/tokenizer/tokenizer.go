@@ -0,0 +1,142 @@
+// Package tokenizer counts tokens to size chunks and fit retrieved chunks in a prompt.
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// NameCharacters counts characters
+	NameCharacters = "characters"
+	// NameApproximate approximates the tokens of BPE tokenizers offline
+	NameApproximate = "approximate"
+)
+
+// Tokenizer counts the tokens in text
+type Tokenizer interface {
+	// Name identifies the tokenizer to the rag-tools service
+	Name() string
+	Count(text string) int
+}
+
+// ByName returns the tokenizer with a name, or false when there is none
+func ByName(name string) (Tokenizer, bool) {
+	switch name {
+	case NameCharacters:
+		return Characters{}, true
+	case NameApproximate:
+		return Approximate{}, true
+	}
+	return nil, false
+}
+
+// Characters counts characters, which is how chunks were sized before tokens
+type Characters struct{}
+
+func (Characters) Name() string { return NameCharacters }
+
+func (Characters) Count(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Approximate estimates the tokens a BPE tokenizer such as cl100k would produce without a
+// vocabulary, so it works offline and is the same in the rag-tools service. Words of Latin letters
+// count a token for every six letters, words in other alphabets one for every three, digits one
+// for every three, and CJK characters, punctuation and symbols one each. A single space is part of
+// the word that follows it, while other runs of whitespace count as one token.
+type Approximate struct{}
+
+func (Approximate) Name() string { return NameApproximate }
+
+type runeClass int
+
+const (
+	classSpace runeClass = iota
+	classLatin
+	classLetter
+	classDigit
+	classWide
+	classOther
+)
+
+func (Approximate) Count(text string) int {
+	tokens := 0
+	run := 0
+	runClass := classOther
+	space := false
+	flush := func() {
+		switch runClass {
+		case classLatin:
+			tokens += (run + 5) / 6
+		case classLetter, classDigit:
+			tokens += (run + 2) / 3
+		case classSpace:
+			if !space {
+				tokens++
+			}
+		}
+		run = 0
+	}
+
+	for _, r := range text {
+		class := classify(r)
+		if run > 0 && (class != runClass || class == classWide || class == classOther) {
+			flush()
+		}
+		if class == classWide || class == classOther {
+			tokens++
+			runClass = class
+			continue
+		}
+		if run == 0 {
+			space = class == classSpace && r == ' '
+		} else if class == classSpace {
+			space = false
+		}
+		runClass = class
+		run++
+	}
+	if run > 0 {
+		flush()
+	}
+	return tokens
+}
+
+// classify groups runes into the classes Approximate counts. Whitespace and CJK characters are
+// listed explicitly so that the rag-tools service classifies runes the same way.
+func classify(r rune) runeClass {
+	switch {
+	case isSpace(r):
+		return classSpace
+	case isWide(r):
+		return classWide
+	case r < 0x250 && unicode.IsLetter(r):
+		return classLatin
+	case unicode.IsLetter(r):
+		return classLetter
+	case r >= '0' && r <= '9':
+		return classDigit
+	}
+	return classOther
+}
+
+// isSpace reports whether r has the Unicode White_Space property
+func isSpace(r rune) bool {
+	switch {
+	case r >= '\t' && r <= '\r', r == ' ', r == 0x85, r == 0xA0, r == 0x1680:
+		return true
+	case r >= 0x2000 && r <= 0x200A, r == 0x2028, r == 0x2029, r == 0x202F, r == 0x205F, r == 0x3000:
+		return true
+	}
+	return false
+}
+
+// isWide reports whether r is a CJK, kana or Hangul character
+func isWide(r rune) bool {
+	return (r >= 0x1100 && r <= 0x11FF) ||
+		(r >= 0x2E80 && r <= 0x9FFF) ||
+		(r >= 0xAC00 && r <= 0xD7AF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x20000 && r <= 0x3FFFF)
+}
@@ -0,0 +1,41 @@
+package tokenizer
+
+import "testing"
+
+// TestApproximate pins the counts of the approximate tokenizer. rag-tools/tests/test_tokenizer.py
+// checks the same counts for the rag-tools service.
+func TestApproximate(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"This is a test.", 5},
+		{"  indented\n\tcode()", 7},
+		{"東京タワー", 5},
+		{"1234567", 3},
+		{"Привет мир", 3},
+		{"x  y", 3},
+		{"internationalization", 4},
+	}
+	for _, tt := range tests {
+		if got := (Approximate{}).Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range []string{NameCharacters, NameApproximate} {
+		tok, ok := ByName(name)
+		if !ok || tok.Name() != name {
+			t.Errorf("ByName(%q) = %v, %v", name, tok, ok)
+		}
+	}
+	if _, ok := ByName("cl100k"); ok {
+		t.Error("expected no tokenizer for an unknown name")
+	}
+	if got := (Characters{}).Count("héllo"); got != 5 {
+		t.Errorf("Characters.Count = %d, want 5", got)
+	}
+}
@@ -0,0 +1,17 @@
+package types
+
+// PageImage represents an image referenced in the main content of a page
+type PageImage struct {
+	SourceURL   string `json:"source_url"`
+	StoragePath string `json:"storage_path,omitempty"`
+	AltText     string `json:"alt_text"`
+	Caption     string `json:"caption"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// PageDiagram represents a diagram source block found on a page
+type PageDiagram struct {
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	Position int    `json:"position"`
+}
@@ -0,0 +1,16 @@
+package types
+
+// BoilerplateBlock represents a markdown block repeated across many pages of a source
+type BoilerplateBlock struct {
+	Fingerprint string `json:"fingerprint"`
+	Sample      string `json:"sample"`
+	PageCount   int    `json:"page_count"`
+}
+
+// BoilerplateReport represents the boilerplate blocks found in a source
+type BoilerplateReport struct {
+	SourceID     int                `json:"source_id"`
+	Threshold    int                `json:"threshold"`
+	PagesScanned int                `json:"pages_scanned"`
+	Blocks       []BoilerplateBlock `json:"blocks"`
+}
@@ -2,29 +2,52 @@ package types
 
 // ChunkData represents a chunk of text with metadata
 type ChunkData struct {
-	Text       string   `json:"text"`
-	SourceURL  string   `json:"source_url"`
-	ChunkPath  []string `json:"chunk_path"`
-	ChunkIndex int      `json:"chunk_index"`
+	Text        string   `json:"text"`
+	SourceURL   string   `json:"source_url"`
+	ChunkPath   []string `json:"chunk_path"`
+	ChunkIndex  int      `json:"chunk_index"`
+	TokenCount  int      `json:"token_count,omitempty"`
+	DocsVersion string   `json:"docs_version,omitempty"`
+	// DeepLink is the source URL with the anchor of the chunk's section
+	DeepLink string `json:"deep_link,omitempty"`
 }
 
 // ChunkMetadata represents metadata for a chunk
 type ChunkMetadata struct {
-	SourceURL string   `json:"source_url"`
+	SourceURL string `json:"source_url"`
+	Title     string `json:"title,omitempty"`
+	// ChunkPath lists the headings the chunk is under, outermost first
 	ChunkPath []string `json:"chunk_path"`
 	Index     int      `json:"index"`
+	// Anchor is the id of the section the chunk is in, and DeepLink the source URL with that anchor
+	Anchor   string `json:"anchor,omitempty"`
+	DeepLink string `json:"deep_link,omitempty"`
+	// StartOffset and EndOffset are the byte offsets of the chunk in the page markdown
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	TokenCount  int    `json:"token_count,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	HasCode     bool   `json:"has_code"`
+	// CodeLanguages lists the languages of the code blocks in the chunk
+	CodeLanguages []string `json:"code_languages,omitempty"`
 }
 
 // Chunk represents a chunk of text with metadata
 type Chunk struct {
-	ID       int           `json:"id"`
-	Text     string        `json:"text"`
-	Metadata ChunkMetadata `json:"metadata"`
+	ID          int           `json:"id"`
+	Text        string        `json:"text"`
+	TokenCount  int           `json:"token_count,omitempty"`
+	DocsVersion string        `json:"docs_version,omitempty"`
+	Metadata    ChunkMetadata `json:"metadata"`
 }
 
 type Source struct {
-	Text string `json:"text"`
-	URL  string `json:"url"`
+	Text        string `json:"text"`
+	URL         string `json:"url"`
+	DocsVersion string `json:"docs_version,omitempty"`
+	// DeepLink links to the section of the page the source is from, or to the page when the
+	// section is not known
+	DeepLink string `json:"deep_link"`
 }
 
 type RAGResponse struct {
@@ -0,0 +1,11 @@
+package types
+
+// EmbeddingModel is a model chunks are embedded with, and how many of the chunks that answer
+// queries have its embedding
+type EmbeddingModel struct {
+	Model          string `json:"model"`
+	Dimensions     int    `json:"dimensions"`
+	IsDefault      bool   `json:"is_default"`
+	EmbeddedChunks int    `json:"embedded_chunks"`
+	TotalChunks    int    `json:"total_chunks"`
+}
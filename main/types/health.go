@@ -0,0 +1,7 @@
+package types
+
+// Readiness is the result of the readiness checks, with "ok" or the error of each check
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
@@ -0,0 +1,40 @@
+package types
+
+// NavLink represents a link in a page's breadcrumbs or sidebar. Depth is the nesting level in the sidebar.
+type NavLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+	Depth int    `json:"depth,omitempty"`
+}
+
+// PageNavigation represents the navigation markup captured from a page
+type PageNavigation struct {
+	Breadcrumbs []NavLink `json:"breadcrumbs"`
+	Sidebar     []NavLink `json:"sidebar"`
+}
+
+// NavPage represents a page and its captured navigation, used to build a source's navigation tree
+type NavPage struct {
+	ID         int
+	URL        string
+	Title      string
+	Navigation PageNavigation
+}
+
+// NavNode represents a section or page in a source's navigation tree. Sections without their own
+// page have no page ID. Order is the position among the node's siblings.
+type NavNode struct {
+	Title    string     `json:"title"`
+	Path     string     `json:"path"`
+	PageID   int        `json:"page_id,omitempty"`
+	URL      string     `json:"url,omitempty"`
+	Order    int        `json:"order"`
+	Children []*NavNode `json:"children,omitempty"`
+}
+
+// NavTree represents the navigation tree of a source
+type NavTree struct {
+	SourceID    int      `json:"source_id"`
+	GeneratedAt string   `json:"generated_at"`
+	Root        *NavNode `json:"root"`
+}
@@ -1,9 +1,42 @@
 package types
 
+import "time"
+
 type Page struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	URL      string `json:"url"`
-	Markdown string `json:"markdown"`
-	Path     string `json:"path"`
+	ID           string           `json:"id"`
+	Title        string           `json:"title"`
+	URL          string           `json:"url"`
+	Markdown     string           `json:"markdown"`
+	Path         string           `json:"path"`
+	Description  string           `json:"description,omitempty"`
+	CanonicalURL string           `json:"canonical_url,omitempty"`
+	LastUpdated  *time.Time       `json:"last_updated,omitempty"`
+	Headings     []Heading        `json:"headings,omitempty"`
+	FrontMatter  *PageFrontMatter `json:"front_matter,omitempty"`
+}
+
+// PageFrontMatter represents the YAML front matter written at the top of stored page markdown
+type PageFrontMatter struct {
+	SourceURL   string    `yaml:"source_url" json:"source_url"`
+	Title       string    `yaml:"title,omitempty" json:"title,omitempty"`
+	CrawledAt   time.Time `yaml:"crawled_at" json:"crawled_at"`
+	ContentHash string    `yaml:"content_hash" json:"content_hash"`
+	Language    string    `yaml:"language,omitempty" json:"language,omitempty"`
+	DocsVersion string    `yaml:"docs_version,omitempty" json:"docs_version,omitempty"`
+}
+
+// Heading represents one entry of a page's heading outline
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// PageMetadata represents the metadata extracted from a page's HTML
+type PageMetadata struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description,omitempty"`
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	LastUpdated  *time.Time `json:"last_updated,omitempty"`
+	Headings     []Heading  `json:"headings"`
 }
@@ -0,0 +1,39 @@
+package types
+
+// BrokenLink represents an internal link that no longer resolves
+type BrokenLink struct {
+	PageURL    string `json:"page_url"`
+	TargetURL  string `json:"target_url"`
+	StatusCode int    `json:"status_code"`
+	Problem    string `json:"problem"`
+	Error      string `json:"error,omitempty"`
+	CheckedAt  string `json:"checked_at"`
+}
+
+// RemovedPage represents a previously indexed page that has disappeared
+type RemovedPage struct {
+	PageID     int    `json:"page_id"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	StatusCode int    `json:"status_code"`
+	RemovedAt  string `json:"removed_at"`
+}
+
+// LinkReport represents the broken link and dead page report for a source
+type LinkReport struct {
+	SourceID     int           `json:"source_id"`
+	GeneratedAt  string        `json:"generated_at"`
+	BrokenLinks  []BrokenLink  `json:"broken_links"`
+	RemovedPages []RemovedPage `json:"removed_pages"`
+}
+
+// LinkCheckSummary represents the outcome of re-checking the links of a source
+type LinkCheckSummary struct {
+	SourceID     int `json:"source_id"`
+	Checked      int `json:"checked"`
+	Broken       int `json:"broken"`
+	Removed      int `json:"removed"`
+	Restored     int `json:"restored"`
+	Unreachable  int `json:"unreachable"`
+	RedirectLoop int `json:"redirect_loops"`
+}
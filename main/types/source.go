@@ -0,0 +1,35 @@
+package types
+
+// DocumentationSource represents a documentation source and its ingestion settings
+type DocumentationSource struct {
+	ID                   int      `json:"id"`
+	URL                  string   `json:"source_url"`
+	Name                 string   `json:"source_name"`
+	AllowedLanguages     []string `json:"allowed_languages"`
+	VersionPattern       string   `json:"version_pattern"`
+	DefaultVersion       string   `json:"default_version"`
+	ContentSelector      string   `json:"content_selector"`
+	RemoveSelectors      []string `json:"remove_selectors"`
+	BoilerplateThreshold int      `json:"boilerplate_threshold"`
+	// ChunkStrategy is "recursive" or "structured", or empty for the server's chunker
+	ChunkStrategy string `json:"chunk_strategy"`
+	// ChunkSize and ChunkOverlap are in tokens
+	ChunkSize    int `json:"chunk_size"`
+	ChunkOverlap int `json:"chunk_overlap"`
+}
+
+// RechunkJob describes the re-chunking of a source started in the background
+type RechunkJob struct {
+	SourceID        int    `json:"source_id"`
+	StrategyVersion string `json:"strategy_version"`
+	PageCount       int    `json:"page_count"`
+}
+
+// ContentPreview represents the markdown a page would be converted to with a source's extraction rules
+type ContentPreview struct {
+	URL             string   `json:"url"`
+	ContentSelector string   `json:"content_selector"`
+	RemoveSelectors []string `json:"remove_selectors"`
+	Extraction      string   `json:"extraction"`
+	Markdown        string   `json:"markdown"`
+}
@@ -1,11 +1,42 @@
+import re
+from dataclasses import dataclass, field
 from typing import List
 from langchain_text_splitters import MarkdownTextSplitter
 
+from tokenizer import CHARACTERS, TOKENIZERS, approximate_tokens
+
+CONTENT_TEXT = "text"
+CONTENT_CODE = "code"
+CONTENT_TABLE = "table"
+CONTENT_MIXED = "mixed"
+
+FENCE_RE = re.compile(r"^ {0,3}(`{3,}|~{3,})\s*([^`\s]*)")
+HEADING_RE = re.compile(r"^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$")
+# The {#id} attribute the markdown converter adds to headings with an id
+HEADING_ID_RE = re.compile(r"[ \t]*\{#[A-Za-z][\w\-.:]*\}$")
+
+
+@dataclass
+class Chunk:
+    text: str
+    # UTF-8 byte offsets of the chunk in the markdown
+    start_offset: int
+    end_offset: int
+    heading_path: List[str] = field(default_factory=list)
+    token_count: int = 0
+    content_type: str = CONTENT_TEXT
+
 
 class MarkdownChunker:
-    def __init__(self, chunk_size: int = 1000, overlap: int = 200):
+    def __init__(
+        self, chunk_size: int = 1000, overlap: int = 200, tokenizer: str = CHARACTERS
+    ):
+        """chunk_size and overlap are counted with the named tokenizer."""
+        length_function = TOKENIZERS[tokenizer]
+        # Chunks sized in characters are still counted in tokens
+        self.count_tokens = approximate_tokens if tokenizer == CHARACTERS else length_function
         self.splitter = MarkdownTextSplitter(
-            chunk_size=chunk_size, chunk_overlap=overlap
+            chunk_size=chunk_size, chunk_overlap=overlap, length_function=length_function
         )
 
     def chunk(self, markdown_content: str) -> List[str]:
@@ -15,3 +46,116 @@ class MarkdownChunker:
         # Use Langchain's MarkdownTextSplitter to split the content
         chunks = self.splitter.split_text(markdown_content)
         return chunks
+
+    def chunk_with_metadata(self, markdown_content: str) -> List[Chunk]:
+        """Chunk markdown and describe where each chunk is and what it contains."""
+        texts = self.chunk(markdown_content)
+        headings, fences = scan_markdown(markdown_content)
+
+        chunks = []
+        start = end = 0
+        for i, text in enumerate(texts):
+            # Every chunk starts after the one before it, so searching after the previous start
+            # matches repeated text in order
+            index = markdown_content.find(text, start + 1 if i > 0 else 0)
+            if index < 0:
+                index = markdown_content.find(text)
+            if index >= 0:
+                start, end = index, index + len(text)
+
+            start_offset = len(markdown_content[:start].encode("utf-8"))
+            chunks.append(
+                Chunk(
+                    text=text,
+                    start_offset=start_offset,
+                    end_offset=start_offset + len(markdown_content[start:end].encode("utf-8")),
+                    heading_path=heading_path(headings, start),
+                    token_count=self.count_tokens(text),
+                    content_type=content_type(text, open_fence(fences, start)),
+                )
+            )
+        return chunks
+
+
+def scan_markdown(markdown_content: str):
+    """Return (offset, level, text) for the ATX headings outside fenced code blocks, and
+    (start, end, marker) for the fenced code blocks."""
+    headings = []
+    fences = []
+    fence = None
+    offset = 0
+    for line in markdown_content.split("\n"):
+        line_offset = offset
+        offset += len(line) + 1
+        line = line.rstrip("\r")
+
+        match = FENCE_RE.match(line)
+        if match:
+            if fence is None:
+                fence = (line_offset, match.group(1))
+            elif line.strip().startswith(fence[1]) and not match.group(2):
+                fences.append((fence[0], min(offset, len(markdown_content)), fence[1]))
+                fence = None
+            continue
+        if fence is not None:
+            continue
+        match = HEADING_RE.match(line)
+        if match and match.group(2):
+            headings.append((line_offset, len(match.group(1)), match.group(2)))
+    if fence is not None:
+        fences.append((fence[0], len(markdown_content), fence[1]))
+    return headings, fences
+
+
+def open_fence(fences, offset: int) -> str:
+    """Return the marker of the code block an offset is inside, or "" when it is not in one."""
+    for start, end, marker in fences:
+        if start < offset < end:
+            return marker
+    return ""
+
+
+def heading_path(headings, offset: int) -> List[str]:
+    """Return the headings an offset is under, outermost first, including one on its line. {#id}
+    attributes are left out."""
+    stack = []
+    for heading_offset, level, text in headings:
+        if heading_offset > offset:
+            break
+        while stack and stack[-1][0] >= level:
+            stack.pop()
+        stack.append((level, text))
+    return ["#" * level + " " + HEADING_ID_RE.sub("", text) for level, text in stack]
+
+
+def content_type(text: str, fence: str = "") -> str:
+    """Describe a chunk as code or a table when everything but its headings is code or table
+    rows, as text when it has neither, and as mixed otherwise. fence is the marker of the code
+    block the chunk starts in, if any."""
+    has_code = has_table = has_text = False
+    for line in text.split("\n"):
+        stripped = line.strip()
+        match = FENCE_RE.match(line)
+        if match:
+            has_code = True
+            if not fence:
+                fence = match.group(1)
+            elif stripped.startswith(fence) and not match.group(2):
+                fence = ""
+            continue
+        if fence:
+            has_code = True
+        elif not stripped or HEADING_RE.match(line):
+            continue
+        elif stripped.startswith("|"):
+            has_table = True
+        else:
+            has_text = True
+
+    if has_code and not has_table and not has_text:
+        return CONTENT_CODE
+    if has_table and not has_code and not has_text:
+        return CONTENT_TABLE
+    if not has_code and not has_table:
+        return CONTENT_TEXT
+    return CONTENT_MIXED
@@ -13,7 +13,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x16markdown_chunker.proto\x12\trag_tools\"L\n\x14\x43hunkMarkdownRequest\x12\x0f\n\x07\x63ontent\x18\x01 \x01(\t\x12\x12\n\nchunk_size\x18\x02 \x01(\x05\x12\x0f\n\x07overlap\x18\x03 \x01(\x05\"\'\n\x15\x43hunkMarkdownResponse\x12\x0e\n\x06\x63hunks\x18\x01 \x03(\t2n\n\x16MarkdownChunkerService\x12T\n\rChunkMarkdown\x12\x1f.rag_tools.ChunkMarkdownRequest\x1a .rag_tools.ChunkMarkdownResponse\"\x00\x62\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x16markdown_chunker.proto\x12\trag_tools\"_\n\x14\x43hunkMarkdownRequest\x12\x0f\n\x07\x63ontent\x18\x01 \x01(\t\x12\x12\n\nchunk_size\x18\x02 \x01(\x05\x12\x0f\n\x07overlap\x18\x03 \x01(\x05\x12\x11\n\ttokenizer\x18\x04 \x01(\t\"?\n\x15\x43hunkMarkdownResponse\x12 \n\x06\x63hunks\x18\x02 \x03(\x0b\x32\x10.rag_tools.ChunkJ\x04\x08\x01\x10\x02\"\x98\x01\n\x05\x43hunk\x12\x0c\n\x04text\x18\x01 \x01(\t\x12\x14\n\x0cstart_offset\x18\x02 \x01(\x05\x12\x12\n\nend_offset\x18\x03 \x01(\x05\x12\x14\n\x0cheading_path\x18\x04 \x03(\t\x12\x13\n\x0btoken_count\x18\x05 \x01(\x05\x12,\n\x0c\x63ontent_type\x18\x06 \x01(\x0e\x32\x16.rag_tools.ContentType\"#\n\x04Page\x12\n\n\x02id\x18\x01 \x01(\x03\x12\x0f\n\x07\x63ontent\x18\x02 \x01(\t\"s\n\x19\x43hunkMarkdownBatchRequest\x12\x1e\n\x05pages\x18\x01 \x03(\x0b\x32\x0f.rag_tools.Page\x12\x12\n\nchunk_size\x18\x02 \x01(\x05\x12\x0f\n\x07overlap\x18\x03 \x01(\x05\x12\x11\n\ttokenizer\x18\x04 \x01(\t\"N\n\nPageChunks\x12\x0f\n\x07page_id\x18\x01 \x01(\x03\x12 \n\x06\x63hunks\x18\x02 \x03(\x0b\x32\x10.rag_tools.Chunk\x12\r\n\x05\x65rror\x18\x03 \x01(\t*\x89\x01\n\x0b\x43ontentType\x12\x1c\n\x18\x43ONTENT_TYPE_UNSPECIFIED\x10\x00\x12\x15\n\x11\x43ONTENT_TYPE_TEXT\x10\x01\x12\x15\n\x11\x43ONTENT_TYPE_CODE\x10\x02\x12\x16\n\x12\x43ONTENT_TYPE_TABLE\x10\x03\x12\x16\n\x12\x43ONTENT_TYPE_MIXED\x10\x04\x32\xc5\x01\n\x16MarkdownChunkerService\x12T\n\rChunkMarkdown\x12\x1f.rag_tools.ChunkMarkdownRequest\x1a .rag_tools.ChunkMarkdownResponse\"\x00\x12U\n\x12\x43hunkMarkdownBatch\x12$.rag_tools.ChunkMarkdownBatchRequest\x1a\x15.rag_tools.PageChunks\"\x00\x30\x01\x62\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -21,9 +21,19 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'markdown_chunker_pb2', _glo
 if _descriptor._USE_C_DESCRIPTORS == False:
   DESCRIPTOR._options = None
   _globals['_CHUNKMARKDOWNREQUEST']._serialized_start=37
-  _globals['_CHUNKMARKDOWNREQUEST']._serialized_end=113
-  _globals['_CHUNKMARKDOWNRESPONSE']._serialized_start=115
-  _globals['_CHUNKMARKDOWNRESPONSE']._serialized_end=154
-  _globals['_MARKDOWNCHUNKERSERVICE']._serialized_start=156
-  _globals['_MARKDOWNCHUNKERSERVICE']._serialized_end=266
+  _globals['_CHUNKMARKDOWNREQUEST']._serialized_end=132
+  _globals['_CHUNKMARKDOWNRESPONSE']._serialized_start=134
+  _globals['_CHUNKMARKDOWNRESPONSE']._serialized_end=197
+  _globals['_CHUNK']._serialized_start=200
+  _globals['_CHUNK']._serialized_end=352
+  _globals['_PAGE']._serialized_start=354
+  _globals['_PAGE']._serialized_end=389
+  _globals['_CHUNKMARKDOWNBATCHREQUEST']._serialized_start=391
+  _globals['_CHUNKMARKDOWNBATCHREQUEST']._serialized_end=506
+  _globals['_PAGECHUNKS']._serialized_start=508
+  _globals['_PAGECHUNKS']._serialized_end=586
+  _globals['_CONTENTTYPE']._serialized_start=589
+  _globals['_CONTENTTYPE']._serialized_end=726
+  _globals['_MARKDOWNCHUNKERSERVICE']._serialized_start=729
+  _globals['_MARKDOWNCHUNKERSERVICE']._serialized_end=926
 # @@protoc_insertion_point(module_scope)
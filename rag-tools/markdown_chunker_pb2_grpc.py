@@ -19,6 +19,11 @@ class MarkdownChunkerServiceStub(object):
                 request_serializer=markdown__chunker__pb2.ChunkMarkdownRequest.SerializeToString,
                 response_deserializer=markdown__chunker__pb2.ChunkMarkdownResponse.FromString,
                 )
+        self.ChunkMarkdownBatch = channel.unary_stream(
+                '/rag_tools.MarkdownChunkerService/ChunkMarkdownBatch',
+                request_serializer=markdown__chunker__pb2.ChunkMarkdownBatchRequest.SerializeToString,
+                response_deserializer=markdown__chunker__pb2.PageChunks.FromString,
+                )
 
 
 class MarkdownChunkerServiceServicer(object):
@@ -30,6 +35,13 @@ class MarkdownChunkerServiceServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def ChunkMarkdownBatch(self, request, context):
+        """ChunkMarkdownBatch chunks many pages and streams back the chunks of each page as it is done
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_MarkdownChunkerServiceServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -38,6 +50,11 @@ def add_MarkdownChunkerServiceServicer_to_server(servicer, server):
                     request_deserializer=markdown__chunker__pb2.ChunkMarkdownRequest.FromString,
                     response_serializer=markdown__chunker__pb2.ChunkMarkdownResponse.SerializeToString,
             ),
+            'ChunkMarkdownBatch': grpc.unary_stream_rpc_method_handler(
+                    servicer.ChunkMarkdownBatch,
+                    request_deserializer=markdown__chunker__pb2.ChunkMarkdownBatchRequest.FromString,
+                    response_serializer=markdown__chunker__pb2.PageChunks.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'rag_tools.MarkdownChunkerService', rpc_method_handlers)
@@ -64,3 +81,20 @@ class MarkdownChunkerService(object):
             markdown__chunker__pb2.ChunkMarkdownResponse.FromString,
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def ChunkMarkdownBatch(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(request, target, '/rag_tools.MarkdownChunkerService/ChunkMarkdownBatch',
+            markdown__chunker__pb2.ChunkMarkdownBatchRequest.SerializeToString,
+            markdown__chunker__pb2.PageChunks.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
@@ -0,0 +1,48 @@
+"""Regenerate the chunker parity fixtures used by the Go tests.
+
+For every markdown file in main/chunker/testdata/parity this writes a JSON file with the
+chunks MarkdownChunker returns for each chunk size, overlap and tokenizer in CASES. Run it from the
+rag-tools directory after changing the chunker or upgrading langchain-text-splitters:
+
+    python scripts/generate_parity_fixtures.py
+"""
+
+import json
+import sys
+from pathlib import Path
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent))
+
+from markdown_chunker import MarkdownChunker  # noqa: E402
+from tokenizer import APPROXIMATE, CHARACTERS  # noqa: E402
+
+FIXTURES = Path(__file__).resolve().parents[2] / "main" / "chunker" / "testdata" / "parity"
+
+CASES = [(1000, 200, CHARACTERS), (250, 50, CHARACTERS), (256, 50, APPROXIMATE), (64, 16, APPROXIMATE)]
+
+
+def main():
+    for markdown_path in sorted(FIXTURES.glob("*.md")):
+        content = markdown_path.read_text(encoding="utf-8")
+        cases = []
+        for chunk_size, overlap, tokenizer in CASES:
+            chunker = MarkdownChunker(
+                chunk_size=chunk_size, overlap=overlap, tokenizer=tokenizer
+            )
+            cases.append(
+                {
+                    "chunk_size": chunk_size,
+                    "overlap": overlap,
+                    "tokenizer": tokenizer,
+                    "chunks": chunker.chunk(content),
+                }
+            )
+        fixture_path = markdown_path.with_suffix(".json")
+        fixture_path.write_text(
+            json.dumps(cases, indent=2, ensure_ascii=False) + "\n", encoding="utf-8"
+        )
+        print(f"wrote {fixture_path.name}")
+
+
+if __name__ == "__main__":
+    main()
@@ -1,27 +1,150 @@
+import os
+import signal
+import threading
 import grpc
 from concurrent import futures
+from grpc_health.v1 import health, health_pb2, health_pb2_grpc
+import markdown_chunker
+import tokenizer
 from markdown_chunker import MarkdownChunker
 import markdown_chunker_pb2
 import markdown_chunker_pb2_grpc
 
+SERVICE_NAME = markdown_chunker_pb2.DESCRIPTOR.services_by_name[
+    "MarkdownChunkerService"
+].full_name
+
+# Seconds in-flight calls get to finish when the server is stopped
+SHUTDOWN_GRACE = 10
+
+# Largest message received or sent, matching the Go client so batches of large pages fit in one call
+MAX_MESSAGE_SIZE = 64 * 1024 * 1024
+
+CONTENT_TYPES = {
+    markdown_chunker.CONTENT_TEXT: markdown_chunker_pb2.CONTENT_TYPE_TEXT,
+    markdown_chunker.CONTENT_CODE: markdown_chunker_pb2.CONTENT_TYPE_CODE,
+    markdown_chunker.CONTENT_TABLE: markdown_chunker_pb2.CONTENT_TYPE_TABLE,
+    markdown_chunker.CONTENT_MIXED: markdown_chunker_pb2.CONTENT_TYPE_MIXED,
+}
+
 
 class MarkdownChunkerServicer(markdown_chunker_pb2_grpc.MarkdownChunkerServiceServicer):
     def ChunkMarkdown(self, request, context):
-        chunker = MarkdownChunker(
-            chunk_size=request.chunk_size if request.chunk_size > 0 else 1000,
-            overlap=request.overlap if request.overlap > 0 else 200,
-        )
-        chunks = chunker.chunk(request.content)
+        chunker = new_chunker(request, context)
+        chunks = to_pb_chunks(chunker.chunk_with_metadata(request.content))
         return markdown_chunker_pb2.ChunkMarkdownResponse(chunks=chunks)
 
+    def ChunkMarkdownBatch(self, request, context):
+        chunker = new_chunker(request, context)
+        for page in request.pages:
+            if not context.is_active():
+                return
+            try:
+                chunks = to_pb_chunks(chunker.chunk_with_metadata(page.content))
+            except Exception as e:
+                # One page that fails to chunk does not fail the others
+                yield markdown_chunker_pb2.PageChunks(page_id=page.id, error=str(e))
+                continue
+            yield markdown_chunker_pb2.PageChunks(page_id=page.id, chunks=chunks)
+
+
+def new_chunker(request, context) -> MarkdownChunker:
+    """Create a chunker with the sizes and tokenizer of a request, using the defaults for sizes
+    that are not set. An overlap of 0 is kept when the chunk size is set, so chunks can be made
+    without overlap."""
+    tokenizer_name = request.tokenizer or tokenizer.CHARACTERS
+    if tokenizer_name not in tokenizer.TOKENIZERS:
+        context.abort(
+            grpc.StatusCode.INVALID_ARGUMENT, f"unknown tokenizer {tokenizer_name!r}"
+        )
+    if request.chunk_size <= 0:
+        return MarkdownChunker(chunk_size=1000, overlap=200, tokenizer=tokenizer_name)
+    return MarkdownChunker(
+        chunk_size=request.chunk_size,
+        overlap=max(request.overlap, 0),
+        tokenizer=tokenizer_name,
+    )
+
+
+def to_pb_chunks(chunks):
+    return [
+        markdown_chunker_pb2.Chunk(
+            text=chunk.text,
+            start_offset=chunk.start_offset,
+            end_offset=chunk.end_offset,
+            heading_path=chunk.heading_path,
+            token_count=chunk.token_count,
+            content_type=CONTENT_TYPES[chunk.content_type],
+        )
+        for chunk in chunks
+    ]
+
+
+def server_credentials():
+    """Return TLS credentials when RAG_TOOLS_TLS_CERT_FILE and RAG_TOOLS_TLS_KEY_FILE are set, or None
+    to serve without TLS. RAG_TOOLS_TLS_CLIENT_CA_FILE additionally requires client certificates
+    signed by that CA (mTLS)."""
+    cert_file = os.environ.get("RAG_TOOLS_TLS_CERT_FILE")
+    key_file = os.environ.get("RAG_TOOLS_TLS_KEY_FILE")
+    client_ca_file = os.environ.get("RAG_TOOLS_TLS_CLIENT_CA_FILE")
+    if not cert_file and not key_file:
+        if client_ca_file:
+            raise ValueError("RAG_TOOLS_TLS_CLIENT_CA_FILE requires a server certificate")
+        return None
+    if not cert_file or not key_file:
+        raise ValueError(
+            "RAG_TOOLS_TLS_CERT_FILE and RAG_TOOLS_TLS_KEY_FILE must be set together"
+        )
+
+    with open(key_file, "rb") as f:
+        key = f.read()
+    with open(cert_file, "rb") as f:
+        cert = f.read()
+    client_ca = None
+    if client_ca_file:
+        with open(client_ca_file, "rb") as f:
+            client_ca = f.read()
+    return grpc.ssl_server_credentials(
+        [(key, cert)],
+        root_certificates=client_ca,
+        require_client_auth=client_ca is not None,
+    )
+
 
 def serve():
-    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10))
+    server = grpc.server(
+        futures.ThreadPoolExecutor(max_workers=10),
+        options=[
+            ("grpc.max_receive_message_length", MAX_MESSAGE_SIZE),
+            ("grpc.max_send_message_length", MAX_MESSAGE_SIZE),
+        ],
+    )
     markdown_chunker_pb2_grpc.add_MarkdownChunkerServiceServicer_to_server(
         MarkdownChunkerServicer(), server
     )
-    server.add_insecure_port("[::]:50051")
+
+    # Clients check the standard gRPC health service before sending calls
+    health_servicer = health.HealthServicer()
+    health_pb2_grpc.add_HealthServicer_to_server(health_servicer, server)
+
+    address = f"[::]:{os.environ.get('RAG_TOOLS_PORT', '50051')}"
+    credentials = server_credentials()
+    if credentials is None:
+        server.add_insecure_port(address)
+    else:
+        server.add_secure_port(address, credentials)
     server.start()
+    for service in (SERVICE_NAME, ""):
+        health_servicer.set(service, health_pb2.HealthCheckResponse.SERVING)
+
+    def stop(signum, frame):
+        # Report NOT_SERVING first so clients stop sending new calls, then let in-flight ones finish
+        health_servicer.enter_graceful_shutdown()
+        server.stop(SHUTDOWN_GRACE)
+
+    # Signal handlers can only be installed from the main thread, not when tests run the server
+    if threading.current_thread() is threading.main_thread():
+        signal.signal(signal.SIGTERM, stop)
     server.wait_for_termination()
 
 
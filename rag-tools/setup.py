@@ -7,6 +7,7 @@ setup(
     install_requires=[
         "grpcio>=1.59.0",
         "grpcio-tools>=1.59.0",
+        "grpcio-health-checking>=1.59.0",
         "langchain>=0.1.12",
         "langchain-text-splitters>=0.0.1",
     ],
@@ -1,5 +1,6 @@
 import grpc
-from server import serve
+from grpc_health.v1 import health_pb2, health_pb2_grpc
+from server import SERVICE_NAME, serve
 import markdown_chunker_pb2
 import markdown_chunker_pb2_grpc
 import threading
@@ -30,9 +31,63 @@ def test_grpc_service():
 
         # Verify the response
         assert len(response.chunks) > 0
-        assert all(isinstance(chunk, str) for chunk in response.chunks)
-        assert all(len(chunk) > 0 for chunk in response.chunks)
+        assert all(len(chunk.text) > 0 for chunk in response.chunks)
+        assert response.chunks[0].heading_path == ["# Test"]
+        assert response.chunks[0].start_offset == 0
+        assert response.chunks[-1].end_offset == len(request.content.encode("utf-8"))
+        assert all(
+            chunk.content_type == markdown_chunker_pb2.CONTENT_TYPE_TEXT
+            for chunk in response.chunks
+        )
+
+        # The chunker service reports that it is serving through the gRPC health service
+        health_stub = health_pb2_grpc.HealthStub(channel)
+        health_response = health_stub.Check(
+            health_pb2.HealthCheckRequest(service=SERVICE_NAME)
+        )
+        assert health_response.status == health_pb2.HealthCheckResponse.SERVING
 
     finally:
         # Clean up
         channel.close()
+
+
+class _Context:
+    def is_active(self):
+        return True
+
+    def abort(self, code, details):
+        raise Exception(details)
+
+
+def test_chunk_markdown_batch():
+    from server import MarkdownChunkerServicer
+
+    request = markdown_chunker_pb2.ChunkMarkdownBatchRequest(
+        pages=[
+            markdown_chunker_pb2.Page(id=1, content="# One\nFirst page."),
+            markdown_chunker_pb2.Page(id=2, content=""),
+            markdown_chunker_pb2.Page(id=3, content="# Three\n" + "Third page. " * 40),
+        ],
+        chunk_size=64,
+        overlap=8,
+        tokenizer="approximate",
+    )
+    results = list(MarkdownChunkerServicer().ChunkMarkdownBatch(request, _Context()))
+
+    assert [result.page_id for result in results] == [1, 2, 3]
+    assert all(not result.error for result in results)
+    assert results[0].chunks[0].heading_path == ["# One"]
+    assert len(results[1].chunks) == 0
+    assert len(results[2].chunks) > 1
+    assert all(chunk.token_count <= 64 for chunk in results[2].chunks)
+
+
+def test_new_chunker_keeps_zero_overlap():
+    from server import new_chunker
+
+    request = markdown_chunker_pb2.ChunkMarkdownRequest(chunk_size=64, overlap=0)
+    assert new_chunker(request, _Context()).splitter._chunk_overlap == 0
+
+    request = markdown_chunker_pb2.ChunkMarkdownRequest()
+    assert new_chunker(request, _Context()).splitter._chunk_overlap == 200
@@ -1,5 +1,11 @@
 import pytest
-from markdown_chunker import MarkdownChunker
+from markdown_chunker import (
+    CONTENT_CODE,
+    CONTENT_MIXED,
+    MarkdownChunker,
+    heading_path,
+    scan_markdown,
+)
 
 
 def test_chunk_markdown_basic():
@@ -51,3 +57,50 @@ def test_chunk_markdown_with_custom_overlap():
     markdown_content = "This is a test. " * 10
     chunks = chunker.chunk(markdown_content)
     assert len(chunks) > 1
+
+
+def test_chunk_with_metadata_offsets():
+    chunker = MarkdownChunker(chunk_size=100, overlap=20)
+    markdown_content = "# Café\n\n" + "Repeated text. " * 20
+    encoded = markdown_content.encode("utf-8")
+    chunks = chunker.chunk_with_metadata(markdown_content)
+    assert len(chunks) > 1
+    for chunk in chunks:
+        assert encoded[chunk.start_offset : chunk.end_offset].decode("utf-8") == chunk.text
+    starts = [chunk.start_offset for chunk in chunks]
+    assert starts == sorted(starts)
+
+
+def test_chunk_with_metadata_heading_path():
+    chunker = MarkdownChunker(chunk_size=60, overlap=0)
+    markdown_content = """# Guide
+
+Intro text for the guide.
+
+## Install
+
+```sh
+# not a heading
+pip install rag-tools
+```
+
+## Run
+
+Run the server."""
+    chunks = chunker.chunk_with_metadata(markdown_content)
+    assert chunks[0].heading_path == ["# Guide"]
+    # The last chunk starts with the closing fence of the Install code block
+    assert chunks[-1].text.startswith("```")
+    assert chunks[-1].heading_path == ["# Guide", "## Install"]
+    assert chunks[-1].content_type == CONTENT_MIXED
+    code = [chunk for chunk in chunks if "pip install" in chunk.text]
+    assert code and code[0].heading_path == ["# Guide", "## Install"]
+    assert code[0].content_type == CONTENT_CODE
+    assert all(chunk.token_count > 0 for chunk in chunks)
+
+
+def test_heading_path_drops_heading_ids():
+    markdown_content = "# Intro {#intro}\n\n## Install {#setup}\n\nRun it."
+    headings, _ = scan_markdown(markdown_content)
+    offset = markdown_content.index("Run it.")
+    assert heading_path(headings, offset) == ["# Intro", "## Install"]
@@ -0,0 +1,19 @@
+import pytest
+from tokenizer import approximate_tokens
+
+# The same counts are pinned for the Go server in main/tokenizer/tokenizer_test.go
+CASES = [
+    ("", 0),
+    ("This is a test.", 5),
+    ("  indented\n\tcode()", 7),
+    ("東京タワー", 5),
+    ("1234567", 3),
+    ("Привет мир", 3),
+    ("x  y", 3),
+    ("internationalization", 4),
+]
+
+
+@pytest.mark.parametrize("text,expected", CASES)
+def test_approximate_tokens(text, expected):
+    assert approximate_tokens(text) == expected
@@ -0,0 +1,94 @@
+"""Token counting shared with the Go server's tokenizer package.
+
+approximate_tokens must give the same counts as tokenizer.Approximate in Go so that chunks sized
+in tokens are identical whichever side chunks them.
+"""
+
+import unicodedata
+
+CHARACTERS = "characters"
+APPROXIMATE = "approximate"
+
+_SPACE, _LATIN, _LETTER, _DIGIT, _WIDE, _OTHER = range(6)
+
+
+def _is_space(c: str) -> bool:
+    """Whether c has the Unicode White_Space property."""
+    r = ord(c)
+    return (
+        0x09 <= r <= 0x0D
+        or r in (0x20, 0x85, 0xA0, 0x1680, 0x2028, 0x2029, 0x202F, 0x205F, 0x3000)
+        or 0x2000 <= r <= 0x200A
+    )
+
+
+def _is_wide(c: str) -> bool:
+    """Whether c is a CJK, kana or Hangul character."""
+    r = ord(c)
+    return (
+        0x1100 <= r <= 0x11FF
+        or 0x2E80 <= r <= 0x9FFF
+        or 0xAC00 <= r <= 0xD7AF
+        or 0xF900 <= r <= 0xFAFF
+        or 0x20000 <= r <= 0x3FFFF
+    )
+
+
+def _classify(c: str) -> int:
+    if _is_space(c):
+        return _SPACE
+    if _is_wide(c):
+        return _WIDE
+    if unicodedata.category(c).startswith("L"):
+        return _LATIN if ord(c) < 0x250 else _LETTER
+    if "0" <= c <= "9":
+        return _DIGIT
+    return _OTHER
+
+
+def approximate_tokens(text: str) -> int:
+    """Estimate the tokens a BPE tokenizer such as cl100k would produce, without a vocabulary.
+
+    Words of Latin letters count a token for every six letters, words in other alphabets one for
+    every three, digits one for every three, and CJK characters, punctuation and symbols one each.
+    A single space is part of the word that follows it, while other runs of whitespace count as
+    one token.
+    """
+    tokens = 0
+    run = 0
+    run_class = _OTHER
+    single_space = False
+
+    def flush():
+        if run_class == _LATIN:
+            return (run + 5) // 6
+        if run_class in (_LETTER, _DIGIT):
+            return (run + 2) // 3
+        if run_class == _SPACE and not single_space:
+            return 1
+        return 0
+
+    for c in text:
+        char_class = _classify(c)
+        if run > 0 and (char_class != run_class or char_class in (_WIDE, _OTHER)):
+            tokens += flush()
+            run = 0
+        if char_class in (_WIDE, _OTHER):
+            tokens += 1
+            run_class = char_class
+            continue
+        if run == 0:
+            single_space = char_class == _SPACE and c == " "
+        elif char_class == _SPACE:
+            single_space = False
+        run_class = char_class
+        run += 1
+    if run > 0:
+        tokens += flush()
+    return tokens
+
+
+TOKENIZERS = {
+    CHARACTERS: len,
+    APPROXIMATE: approximate_tokens,
+}
@@ -0,0 +1,71 @@
+alter table "public"."urls" add column "status_code" integer;
+
+alter table "public"."urls" add column "fetch_error" text;
+
+alter table "public"."urls" add column "checked_at" timestamp with time zone;
+
+alter table "public"."pages" add column "removed_at" timestamp with time zone;
+
+create table "public"."page_links" (
+    "id" bigint generated by default as identity not null,
+    "page_id" integer not null,
+    "target_url" text not null,
+    "created_at" timestamp with time zone not null default now()
+);
+
+
+CREATE UNIQUE INDEX page_links_pkey ON public.page_links USING btree (id);
+
+CREATE UNIQUE INDEX page_links_page_id_target_url_key ON public.page_links USING btree (page_id, target_url);
+
+CREATE INDEX idx_page_links_target_url ON public.page_links USING btree (target_url);
+
+CREATE INDEX idx_pages_removed_at ON public.pages USING btree (removed_at);
+
+alter table "public"."page_links" add constraint "page_links_pkey" PRIMARY KEY using index "page_links_pkey";
+
+alter table "public"."page_links" add constraint "page_links_page_id_fkey" FOREIGN KEY (page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."page_links" validate constraint "page_links_page_id_fkey";
+
+grant delete on table "public"."page_links" to "anon";
+
+grant insert on table "public"."page_links" to "anon";
+
+grant references on table "public"."page_links" to "anon";
+
+grant select on table "public"."page_links" to "anon";
+
+grant trigger on table "public"."page_links" to "anon";
+
+grant truncate on table "public"."page_links" to "anon";
+
+grant update on table "public"."page_links" to "anon";
+
+grant delete on table "public"."page_links" to "authenticated";
+
+grant insert on table "public"."page_links" to "authenticated";
+
+grant references on table "public"."page_links" to "authenticated";
+
+grant select on table "public"."page_links" to "authenticated";
+
+grant trigger on table "public"."page_links" to "authenticated";
+
+grant truncate on table "public"."page_links" to "authenticated";
+
+grant update on table "public"."page_links" to "authenticated";
+
+grant delete on table "public"."page_links" to "service_role";
+
+grant insert on table "public"."page_links" to "service_role";
+
+grant references on table "public"."page_links" to "service_role";
+
+grant select on table "public"."page_links" to "service_role";
+
+grant trigger on table "public"."page_links" to "service_role";
+
+grant truncate on table "public"."page_links" to "service_role";
+
+grant update on table "public"."page_links" to "service_role";
@@ -0,0 +1,7 @@
+alter table "public"."pages" add column "language" text;
+
+alter table "public"."chunks" add column "language" text;
+
+alter table "public"."documentation_sources" add column "allowed_languages" text[];
+
+CREATE INDEX idx_chunks_language ON public.chunks USING btree (language);
@@ -0,0 +1,9 @@
+alter table "public"."pages" add column "docs_version" text;
+
+alter table "public"."chunks" add column "docs_version" text;
+
+alter table "public"."documentation_sources" add column "version_pattern" text;
+
+alter table "public"."documentation_sources" add column "default_version" text;
+
+CREATE INDEX idx_chunks_docs_version ON public.chunks USING btree (docs_version);
@@ -0,0 +1,3 @@
+alter table "public"."documentation_sources" add column "content_selector" text;
+
+alter table "public"."documentation_sources" add column "remove_selectors" text[];
@@ -0,0 +1,125 @@
+create table "public"."page_images" (
+    "id" bigint generated by default as identity not null,
+    "page_id" integer not null,
+    "source_url" text not null,
+    "storage_path" text,
+    "alt_text" text,
+    "caption" text,
+    "content_type" text,
+    "created_at" timestamp with time zone not null default now()
+);
+
+create table "public"."page_diagrams" (
+    "id" bigint generated by default as identity not null,
+    "page_id" integer not null,
+    "diagram_type" text not null,
+    "source" text not null,
+    "position" integer not null default 0,
+    "created_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX page_images_pkey ON public.page_images USING btree (id);
+
+CREATE UNIQUE INDEX page_images_page_id_source_url_key ON public.page_images USING btree (page_id, source_url);
+
+CREATE UNIQUE INDEX page_diagrams_pkey ON public.page_diagrams USING btree (id);
+
+CREATE INDEX idx_page_diagrams_page_id ON public.page_diagrams USING btree (page_id);
+
+alter table "public"."page_images" add constraint "page_images_pkey" PRIMARY KEY using index "page_images_pkey";
+
+alter table "public"."page_images" add constraint "page_images_page_id_source_url_key" UNIQUE using index "page_images_page_id_source_url_key";
+
+alter table "public"."page_images" add constraint "page_images_page_id_fkey" FOREIGN KEY (page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."page_images" validate constraint "page_images_page_id_fkey";
+
+alter table "public"."page_diagrams" add constraint "page_diagrams_pkey" PRIMARY KEY using index "page_diagrams_pkey";
+
+alter table "public"."page_diagrams" add constraint "page_diagrams_page_id_fkey" FOREIGN KEY (page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."page_diagrams" validate constraint "page_diagrams_page_id_fkey";
+
+grant delete on table "public"."page_images" to "anon";
+
+grant insert on table "public"."page_images" to "anon";
+
+grant references on table "public"."page_images" to "anon";
+
+grant select on table "public"."page_images" to "anon";
+
+grant trigger on table "public"."page_images" to "anon";
+
+grant truncate on table "public"."page_images" to "anon";
+
+grant update on table "public"."page_images" to "anon";
+
+grant delete on table "public"."page_images" to "authenticated";
+
+grant insert on table "public"."page_images" to "authenticated";
+
+grant references on table "public"."page_images" to "authenticated";
+
+grant select on table "public"."page_images" to "authenticated";
+
+grant trigger on table "public"."page_images" to "authenticated";
+
+grant truncate on table "public"."page_images" to "authenticated";
+
+grant update on table "public"."page_images" to "authenticated";
+
+grant delete on table "public"."page_images" to "service_role";
+
+grant insert on table "public"."page_images" to "service_role";
+
+grant references on table "public"."page_images" to "service_role";
+
+grant select on table "public"."page_images" to "service_role";
+
+grant trigger on table "public"."page_images" to "service_role";
+
+grant truncate on table "public"."page_images" to "service_role";
+
+grant update on table "public"."page_images" to "service_role";
+
+grant delete on table "public"."page_diagrams" to "anon";
+
+grant insert on table "public"."page_diagrams" to "anon";
+
+grant references on table "public"."page_diagrams" to "anon";
+
+grant select on table "public"."page_diagrams" to "anon";
+
+grant trigger on table "public"."page_diagrams" to "anon";
+
+grant truncate on table "public"."page_diagrams" to "anon";
+
+grant update on table "public"."page_diagrams" to "anon";
+
+grant delete on table "public"."page_diagrams" to "authenticated";
+
+grant insert on table "public"."page_diagrams" to "authenticated";
+
+grant references on table "public"."page_diagrams" to "authenticated";
+
+grant select on table "public"."page_diagrams" to "authenticated";
+
+grant trigger on table "public"."page_diagrams" to "authenticated";
+
+grant truncate on table "public"."page_diagrams" to "authenticated";
+
+grant update on table "public"."page_diagrams" to "authenticated";
+
+grant delete on table "public"."page_diagrams" to "service_role";
+
+grant insert on table "public"."page_diagrams" to "service_role";
+
+grant references on table "public"."page_diagrams" to "service_role";
+
+grant select on table "public"."page_diagrams" to "service_role";
+
+grant trigger on table "public"."page_diagrams" to "service_role";
+
+grant truncate on table "public"."page_diagrams" to "service_role";
+
+grant update on table "public"."page_diagrams" to "service_role";
@@ -0,0 +1,7 @@
+alter table "public"."pages" add column "description" text;
+
+alter table "public"."pages" add column "canonical_url" text;
+
+alter table "public"."pages" add column "last_updated" timestamp with time zone;
+
+alter table "public"."pages" add column "headings" jsonb;
@@ -0,0 +1,3 @@
+alter table "public"."pages" add column "breadcrumbs" jsonb;
+
+alter table "public"."pages" add column "sidebar" jsonb;
@@ -0,0 +1,64 @@
+alter table "public"."documentation_sources" add column "boilerplate_threshold" integer;
+
+create table "public"."boilerplate_blocks" (
+    "id" bigint generated by default as identity not null,
+    "source_id" integer not null,
+    "fingerprint" text not null,
+    "sample" text not null,
+    "page_count" integer not null,
+    "created_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX boilerplate_blocks_pkey ON public.boilerplate_blocks USING btree (id);
+
+CREATE UNIQUE INDEX boilerplate_blocks_source_id_fingerprint_key ON public.boilerplate_blocks USING btree (source_id, fingerprint);
+
+alter table "public"."boilerplate_blocks" add constraint "boilerplate_blocks_pkey" PRIMARY KEY using index "boilerplate_blocks_pkey";
+
+alter table "public"."boilerplate_blocks" add constraint "boilerplate_blocks_source_id_fingerprint_key" UNIQUE using index "boilerplate_blocks_source_id_fingerprint_key";
+
+alter table "public"."boilerplate_blocks" add constraint "boilerplate_blocks_source_id_fkey" FOREIGN KEY (source_id) REFERENCES documentation_sources(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."boilerplate_blocks" validate constraint "boilerplate_blocks_source_id_fkey";
+
+grant delete on table "public"."boilerplate_blocks" to "anon";
+
+grant insert on table "public"."boilerplate_blocks" to "anon";
+
+grant references on table "public"."boilerplate_blocks" to "anon";
+
+grant select on table "public"."boilerplate_blocks" to "anon";
+
+grant trigger on table "public"."boilerplate_blocks" to "anon";
+
+grant truncate on table "public"."boilerplate_blocks" to "anon";
+
+grant update on table "public"."boilerplate_blocks" to "anon";
+
+grant delete on table "public"."boilerplate_blocks" to "authenticated";
+
+grant insert on table "public"."boilerplate_blocks" to "authenticated";
+
+grant references on table "public"."boilerplate_blocks" to "authenticated";
+
+grant select on table "public"."boilerplate_blocks" to "authenticated";
+
+grant trigger on table "public"."boilerplate_blocks" to "authenticated";
+
+grant truncate on table "public"."boilerplate_blocks" to "authenticated";
+
+grant update on table "public"."boilerplate_blocks" to "authenticated";
+
+grant delete on table "public"."boilerplate_blocks" to "service_role";
+
+grant insert on table "public"."boilerplate_blocks" to "service_role";
+
+grant references on table "public"."boilerplate_blocks" to "service_role";
+
+grant select on table "public"."boilerplate_blocks" to "service_role";
+
+grant trigger on table "public"."boilerplate_blocks" to "service_role";
+
+grant truncate on table "public"."boilerplate_blocks" to "service_role";
+
+grant update on table "public"."boilerplate_blocks" to "service_role";
@@ -0,0 +1 @@
+alter table "public"."chunks" add column "token_count" integer;
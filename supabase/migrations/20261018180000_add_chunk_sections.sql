@@ -0,0 +1,70 @@
+create table "public"."chunk_sections" (
+    "id" bigint generated by default as identity not null,
+    "page_id" integer not null,
+    "text" text not null,
+    "token_count" integer,
+    "metadata" json not null,
+    "language" text,
+    "docs_version" text,
+    "created_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX chunk_sections_pkey ON public.chunk_sections USING btree (id);
+
+CREATE INDEX idx_chunk_sections_page_id ON public.chunk_sections USING btree (page_id);
+
+alter table "public"."chunk_sections" add constraint "chunk_sections_pkey" PRIMARY KEY using index "chunk_sections_pkey";
+
+alter table "public"."chunk_sections" add constraint "chunk_sections_page_id_fkey" FOREIGN KEY (page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."chunk_sections" validate constraint "chunk_sections_page_id_fkey";
+
+alter table "public"."chunks" add column "section_id" bigint;
+
+CREATE INDEX idx_chunks_section_id ON public.chunks USING btree (section_id);
+
+alter table "public"."chunks" add constraint "chunks_section_id_fkey" FOREIGN KEY (section_id) REFERENCES chunk_sections(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."chunks" validate constraint "chunks_section_id_fkey";
+
+grant delete on table "public"."chunk_sections" to "anon";
+
+grant insert on table "public"."chunk_sections" to "anon";
+
+grant references on table "public"."chunk_sections" to "anon";
+
+grant select on table "public"."chunk_sections" to "anon";
+
+grant trigger on table "public"."chunk_sections" to "anon";
+
+grant truncate on table "public"."chunk_sections" to "anon";
+
+grant update on table "public"."chunk_sections" to "anon";
+
+grant delete on table "public"."chunk_sections" to "authenticated";
+
+grant insert on table "public"."chunk_sections" to "authenticated";
+
+grant references on table "public"."chunk_sections" to "authenticated";
+
+grant select on table "public"."chunk_sections" to "authenticated";
+
+grant trigger on table "public"."chunk_sections" to "authenticated";
+
+grant truncate on table "public"."chunk_sections" to "authenticated";
+
+grant update on table "public"."chunk_sections" to "authenticated";
+
+grant delete on table "public"."chunk_sections" to "service_role";
+
+grant insert on table "public"."chunk_sections" to "service_role";
+
+grant references on table "public"."chunk_sections" to "service_role";
+
+grant select on table "public"."chunk_sections" to "service_role";
+
+grant trigger on table "public"."chunk_sections" to "service_role";
+
+grant truncate on table "public"."chunk_sections" to "service_role";
+
+grant update on table "public"."chunk_sections" to "service_role";
@@ -0,0 +1,15 @@
+alter table "public"."documentation_sources" add column "chunk_strategy" text;
+
+alter table "public"."documentation_sources" add column "chunk_size" integer;
+
+alter table "public"."documentation_sources" add column "chunk_overlap" integer;
+
+alter table "public"."chunks" add column "strategy_version" text;
+
+alter table "public"."chunks" add column "pending" boolean not null default false;
+
+alter table "public"."chunk_sections" add column "strategy_version" text;
+
+alter table "public"."chunk_sections" add column "pending" boolean not null default false;
+
+CREATE INDEX idx_chunks_strategy_version ON public.chunks USING btree (strategy_version);
@@ -0,0 +1,73 @@
+alter table "public"."chunks" add column "simhash" bigint;
+
+create table "public"."chunk_skips" (
+    "id" bigint generated by default as identity not null,
+    "page_id" integer not null,
+    "text" text not null,
+    "reason" text not null,
+    "words" integer not null,
+    "link_share" real not null,
+    "simhash" bigint,
+    "duplicate_of_page_id" integer,
+    "duplicate_distance" integer,
+    "strategy_version" text,
+    "created_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX chunk_skips_pkey ON public.chunk_skips USING btree (id);
+
+CREATE INDEX idx_chunk_skips_page_id ON public.chunk_skips USING btree (page_id);
+
+CREATE INDEX idx_chunk_skips_reason ON public.chunk_skips USING btree (reason);
+
+alter table "public"."chunk_skips" add constraint "chunk_skips_pkey" PRIMARY KEY using index "chunk_skips_pkey";
+
+alter table "public"."chunk_skips" add constraint "chunk_skips_page_id_fkey" FOREIGN KEY (page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."chunk_skips" validate constraint "chunk_skips_page_id_fkey";
+
+alter table "public"."chunk_skips" add constraint "chunk_skips_duplicate_of_page_id_fkey" FOREIGN KEY (duplicate_of_page_id) REFERENCES pages(id) ON UPDATE CASCADE ON DELETE SET NULL not valid;
+
+alter table "public"."chunk_skips" validate constraint "chunk_skips_duplicate_of_page_id_fkey";
+
+grant delete on table "public"."chunk_skips" to "anon";
+
+grant insert on table "public"."chunk_skips" to "anon";
+
+grant references on table "public"."chunk_skips" to "anon";
+
+grant select on table "public"."chunk_skips" to "anon";
+
+grant trigger on table "public"."chunk_skips" to "anon";
+
+grant truncate on table "public"."chunk_skips" to "anon";
+
+grant update on table "public"."chunk_skips" to "anon";
+
+grant delete on table "public"."chunk_skips" to "authenticated";
+
+grant insert on table "public"."chunk_skips" to "authenticated";
+
+grant references on table "public"."chunk_skips" to "authenticated";
+
+grant select on table "public"."chunk_skips" to "authenticated";
+
+grant trigger on table "public"."chunk_skips" to "authenticated";
+
+grant truncate on table "public"."chunk_skips" to "authenticated";
+
+grant update on table "public"."chunk_skips" to "authenticated";
+
+grant delete on table "public"."chunk_skips" to "service_role";
+
+grant insert on table "public"."chunk_skips" to "service_role";
+
+grant references on table "public"."chunk_skips" to "service_role";
+
+grant select on table "public"."chunk_skips" to "service_role";
+
+grant trigger on table "public"."chunk_skips" to "service_role";
+
+grant truncate on table "public"."chunk_skips" to "service_role";
+
+grant update on table "public"."chunk_skips" to "service_role";
@@ -0,0 +1,71 @@
+-- Keep the newest embedding of each chunk and model before making them unique
+delete from "public"."embeddings" a using "public"."embeddings" b where a."chunk_id" = b."chunk_id" and a."model" = b."model" and a."id" < b."id";
+
+CREATE UNIQUE INDEX embeddings_chunk_id_model_key ON public.embeddings USING btree (chunk_id, model);
+
+CREATE INDEX idx_embeddings_model ON public.embeddings USING btree (model);
+
+alter table "public"."embeddings" add constraint "embeddings_chunk_id_model_key" UNIQUE using index "embeddings_chunk_id_model_key";
+
+alter table "public"."embeddings" alter column "model" drop default;
+
+create table "public"."embedding_models" (
+    "model" text not null,
+    "dimensions" integer not null,
+    "is_default" boolean not null default false,
+    "created_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX embedding_models_pkey ON public.embedding_models USING btree (model);
+
+CREATE UNIQUE INDEX embedding_models_is_default_key ON public.embedding_models USING btree (is_default) WHERE is_default;
+
+alter table "public"."embedding_models" add constraint "embedding_models_pkey" PRIMARY KEY using index "embedding_models_pkey";
+
+insert into "public"."embedding_models" ("model", "dimensions", "is_default") values ('gemini-embedding-exp-03-07', 3072, true);
+
+insert into "public"."embeddings" ("chunk_id", "model", "embedding")
+select "id", 'gemini-embedding-exp-03-07', "vector_embedding" from "public"."chunks" where "vector_embedding" is not null
+on conflict ("chunk_id", "model") do nothing;
+
+grant delete on table "public"."embedding_models" to "anon";
+
+grant insert on table "public"."embedding_models" to "anon";
+
+grant references on table "public"."embedding_models" to "anon";
+
+grant select on table "public"."embedding_models" to "anon";
+
+grant trigger on table "public"."embedding_models" to "anon";
+
+grant truncate on table "public"."embedding_models" to "anon";
+
+grant update on table "public"."embedding_models" to "anon";
+
+grant delete on table "public"."embedding_models" to "authenticated";
+
+grant insert on table "public"."embedding_models" to "authenticated";
+
+grant references on table "public"."embedding_models" to "authenticated";
+
+grant select on table "public"."embedding_models" to "authenticated";
+
+grant trigger on table "public"."embedding_models" to "authenticated";
+
+grant truncate on table "public"."embedding_models" to "authenticated";
+
+grant update on table "public"."embedding_models" to "authenticated";
+
+grant delete on table "public"."embedding_models" to "service_role";
+
+grant insert on table "public"."embedding_models" to "service_role";
+
+grant references on table "public"."embedding_models" to "service_role";
+
+grant select on table "public"."embedding_models" to "service_role";
+
+grant trigger on table "public"."embedding_models" to "service_role";
+
+grant truncate on table "public"."embedding_models" to "service_role";
+
+grant update on table "public"."embedding_models" to "service_role";
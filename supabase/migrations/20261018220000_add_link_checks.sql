@@ -0,0 +1,62 @@
+create table "public"."link_checks" (
+    "id" bigint generated by default as identity not null,
+    "source_id" integer not null,
+    "url" text not null,
+    "status_code" integer,
+    "fetch_error" text,
+    "checked_at" timestamp with time zone not null default now()
+);
+
+CREATE UNIQUE INDEX link_checks_pkey ON public.link_checks USING btree (id);
+
+CREATE UNIQUE INDEX link_checks_source_id_url_key ON public.link_checks USING btree (source_id, url);
+
+alter table "public"."link_checks" add constraint "link_checks_pkey" PRIMARY KEY using index "link_checks_pkey";
+
+alter table "public"."link_checks" add constraint "link_checks_source_id_url_key" UNIQUE using index "link_checks_source_id_url_key";
+
+alter table "public"."link_checks" add constraint "link_checks_source_id_fkey" FOREIGN KEY (source_id) REFERENCES documentation_sources(id) ON UPDATE CASCADE ON DELETE CASCADE not valid;
+
+alter table "public"."link_checks" validate constraint "link_checks_source_id_fkey";
+
+grant delete on table "public"."link_checks" to "anon";
+
+grant insert on table "public"."link_checks" to "anon";
+
+grant references on table "public"."link_checks" to "anon";
+
+grant select on table "public"."link_checks" to "anon";
+
+grant trigger on table "public"."link_checks" to "anon";
+
+grant truncate on table "public"."link_checks" to "anon";
+
+grant update on table "public"."link_checks" to "anon";
+
+grant delete on table "public"."link_checks" to "authenticated";
+
+grant insert on table "public"."link_checks" to "authenticated";
+
+grant references on table "public"."link_checks" to "authenticated";
+
+grant select on table "public"."link_checks" to "authenticated";
+
+grant trigger on table "public"."link_checks" to "authenticated";
+
+grant truncate on table "public"."link_checks" to "authenticated";
+
+grant update on table "public"."link_checks" to "authenticated";
+
+grant delete on table "public"."link_checks" to "service_role";
+
+grant insert on table "public"."link_checks" to "service_role";
+
+grant references on table "public"."link_checks" to "service_role";
+
+grant select on table "public"."link_checks" to "service_role";
+
+grant trigger on table "public"."link_checks" to "service_role";
+
+grant truncate on table "public"."link_checks" to "service_role";
+
+grant update on table "public"."link_checks" to "service_role";
@@ -0,0 +1 @@
+alter table "public"."chunk_skips" add column "pending" boolean not null default false;